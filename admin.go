@@ -0,0 +1,240 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrUnknownLevel is returned by LevelAdmin.SetLevel for names that were never registered.
+var ErrUnknownLevel = errors.New("unknown level name")
+
+// LevelAdmin is an http.Handler that lists registered level controls and
+// changes them at runtime, optionally for a limited time.
+//
+// GET returns a JSON array of all controls, or a single object when the
+// "name" query parameter is given:
+//
+//	[{"name":"db","level":"DEBUG","revert_to":"INFO","expires":"2026-10-16T12:01:00Z"}]
+//
+// PUT and POST change a level. Parameters are read from a JSON body
+// ({"name":"db","level":"debug","ttl":"1m"}) or from query/form values of the
+// same names. With a positive ttl the level reverts on its own once the ttl
+// has elapsed; without one the change is permanent.
+//
+// Mount it on an internal listener, for example:
+//
+//	admin := NewLevelAdmin()
+//	admin.RegisterLogger("app", logger)
+//	mux.Handle("/debug/levels", admin)
+type LevelAdmin struct {
+	mu      sync.Mutex
+	entries map[string]*levelEntry
+}
+
+// levelEntry is a registered level control and its pending revert, if any.
+type levelEntry struct {
+	level   *slog.LevelVar
+	base    slog.Level  // level restored when a temporary change expires, valid while timer is set
+	timer   *time.Timer // pending revert, nil if the current level is permanent
+	expires time.Time
+}
+
+// levelStatus is the JSON form of a level control.
+type levelStatus struct {
+	Name     string     `json:"name"`
+	Level    string     `json:"level"`
+	RevertTo string     `json:"revert_to,omitempty"`
+	Expires  *time.Time `json:"expires,omitempty"`
+}
+
+// levelRequest is the JSON body of a level change.
+type levelRequest struct {
+	Name  string `json:"name"`
+	Level string `json:"level"`
+	TTL   string `json:"ttl"`
+}
+
+// NewLevelAdmin returns an empty LevelAdmin.
+func NewLevelAdmin() *LevelAdmin {
+	return &LevelAdmin{entries: make(map[string]*levelEntry)}
+}
+
+// Register adds a level control under name, replacing any control already
+// registered under that name. A nil lv is ignored.
+func (a *LevelAdmin) Register(name string, lv *slog.LevelVar) {
+	if lv == nil {
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if old, ok := a.entries[name]; ok && old.timer != nil {
+		old.timer.Stop()
+	}
+	a.entries[name] = &levelEntry{level: lv}
+}
+
+// RegisterLogger registers the level control of l (see LevelVarOf) under name.
+// It reports false if the logger's level cannot be changed at runtime.
+func (a *LevelAdmin) RegisterLogger(name string, l *slog.Logger) bool {
+	lv := LevelVarOf(l)
+	a.Register(name, lv)
+	return lv != nil
+}
+
+// SetLevel changes the level registered under name. If ttl is positive, the
+// level reverts once ttl has elapsed to the level it had when the change was
+// made, or, if a revert was already pending, to the level that one would
+// have restored. A later permanent change cancels the pending revert.
+func (a *LevelAdmin) SetLevel(name string, level slog.Level, ttl time.Duration) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	e, ok := a.entries[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownLevel, name)
+	}
+	if e.timer != nil {
+		e.timer.Stop()
+		e.timer = nil
+		e.expires = time.Time{}
+	} else {
+		e.base = e.level.Level()
+	}
+	e.level.Set(level)
+	if ttl <= 0 {
+		return nil
+	}
+	e.expires = time.Now().Add(ttl)
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		a.mu.Lock()
+		defer a.mu.Unlock()
+		if e.timer != timer {
+			return // superseded by a later change
+		}
+		e.level.Set(e.base)
+		e.timer = nil
+		e.expires = time.Time{}
+	})
+	e.timer = timer
+	return nil
+}
+
+// status returns the JSON form of the entry registered under name.
+func (a *LevelAdmin) status(name string) (levelStatus, bool) {
+	e, ok := a.entries[name]
+	if !ok {
+		return levelStatus{}, false
+	}
+	st := levelStatus{Name: name, Level: e.level.Level().String()}
+	if e.timer != nil {
+		expires := e.expires
+		st.RevertTo = e.base.String()
+		st.Expires = &expires
+	}
+	return st, true
+}
+
+// ServeHTTP implements http.Handler.
+func (a *LevelAdmin) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+		a.serveGet(w, r)
+	case http.MethodPut, http.MethodPost:
+		a.serveSet(w, r)
+	default:
+		w.Header().Set("Allow", "GET, HEAD, PUT, POST")
+		writeAdminError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+	}
+}
+
+func (a *LevelAdmin) serveGet(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if name := r.URL.Query().Get("name"); name != "" {
+		st, ok := a.status(name)
+		if !ok {
+			writeAdminError(w, http.StatusNotFound, fmt.Errorf("%w: %q", ErrUnknownLevel, name))
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, st)
+		return
+	}
+	names := make([]string, 0, len(a.entries))
+	for name := range a.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]levelStatus, 0, len(names))
+	for _, name := range names {
+		st, _ := a.status(name)
+		list = append(list, st)
+	}
+	writeAdminJSON(w, http.StatusOK, list)
+}
+
+func (a *LevelAdmin) serveSet(w http.ResponseWriter, r *http.Request) {
+	req, err := readLevelRequest(r)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+	level, err := parseLevel(req.Level)
+	if err != nil {
+		writeAdminError(w, http.StatusBadRequest, err)
+		return
+	}
+	var ttl time.Duration
+	if req.TTL != "" {
+		ttl, err = time.ParseDuration(req.TTL)
+		if err != nil || ttl < 0 {
+			writeAdminError(w, http.StatusBadRequest, fmt.Errorf("invalid ttl %q", req.TTL))
+			return
+		}
+	}
+	if err := a.SetLevel(req.Name, level, ttl); err != nil {
+		writeAdminError(w, http.StatusNotFound, err)
+		return
+	}
+	a.mu.Lock()
+	st, _ := a.status(req.Name)
+	a.mu.Unlock()
+	writeAdminJSON(w, http.StatusOK, st)
+}
+
+// readLevelRequest reads a level change from a JSON body or from form values.
+func readLevelRequest(r *http.Request) (levelRequest, error) {
+	var req levelRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			return req, fmt.Errorf("invalid JSON body: %w", err)
+		}
+	} else {
+		req.Name = r.FormValue("name")
+		req.Level = r.FormValue("level")
+		req.TTL = r.FormValue("ttl")
+	}
+	if req.Name == "" {
+		return req, errors.New("missing name")
+	}
+	if req.Level == "" {
+		return req, errors.New("missing level")
+	}
+	return req, nil
+}
+
+func writeAdminJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeAdminError(w http.ResponseWriter, code int, err error) {
+	writeAdminJSON(w, code, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLevelAdminList(t *testing.T) {
+	admin := NewLevelAdmin()
+	db, web := new(slog.LevelVar), new(slog.LevelVar)
+	web.Set(slog.LevelWarn)
+	admin.Register("http", web)
+	admin.Register("db", db)
+	if admin.RegisterLogger("custom", NewSlogConfig(WithCustomHandler(newTestHandler())).NewLogger()) {
+		t.Error("RegisterLogger accepted a logger without a LevelVar")
+	}
+
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var list []levelStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].Name != "db" || list[0].Level != "INFO" || list[1].Name != "http" || list[1].Level != "WARN" {
+		t.Errorf("list = %+v", list)
+	}
+}
+
+func TestLevelAdminGetByName(t *testing.T) {
+	admin := NewLevelAdmin()
+	admin.Register("db", new(slog.LevelVar))
+
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?name=db", nil))
+	var st levelStatus
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil || st.Name != "db" {
+		t.Errorf("GET ?name=db = %s (%v)", rec.Body, err)
+	}
+
+	rec = httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?name=nope", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", rec.Code)
+	}
+}
+
+func TestLevelAdminSetPermanent(t *testing.T) {
+	admin := NewLevelAdmin()
+	logger := NewSlogConfig().NewLogger()
+	admin.RegisterLogger("app", logger)
+
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`{"name":"app","level":"debug"}`))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if !logger.Enabled(req.Context(), slog.LevelDebug) {
+		t.Error("logger not switched to DEBUG")
+	}
+	var st levelStatus
+	json.Unmarshal(rec.Body.Bytes(), &st)
+	if st.Level != "DEBUG" || st.Expires != nil {
+		t.Errorf("status = %+v, want permanent DEBUG", st)
+	}
+}
+
+func TestLevelAdminSetWithTTL(t *testing.T) {
+	admin := NewLevelAdmin()
+	lv := new(slog.LevelVar)
+	admin.Register("app", lv)
+
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/?name=app&level=debug&ttl=20ms", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var st levelStatus
+	json.Unmarshal(rec.Body.Bytes(), &st)
+	if st.Level != "DEBUG" || st.RevertTo != "INFO" || st.Expires == nil {
+		t.Errorf("status = %+v, want temporary DEBUG reverting to INFO", st)
+	}
+	if lv.Level() != slog.LevelDebug {
+		t.Fatalf("level = %v, want DEBUG", lv.Level())
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for lv.Level() != slog.LevelInfo && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if lv.Level() != slog.LevelInfo {
+		t.Errorf("level = %v after ttl, want INFO", lv.Level())
+	}
+}
+
+func TestLevelAdminRevertsToCurrentBase(t *testing.T) {
+	admin := NewLevelAdmin()
+	lv := new(slog.LevelVar)
+	admin.Register("app", lv)
+	lv.Set(slog.LevelWarn) // changed outside the admin after registration
+	if err := admin.SetLevel("app", slog.LevelDebug, time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.SetLevel("app", slog.LevelError, 200*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?name=app", nil))
+	var st levelStatus
+	json.Unmarshal(rec.Body.Bytes(), &st)
+	if st.RevertTo != "WARN" {
+		t.Errorf("revert_to = %q, want WARN", st.RevertTo)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for lv.Level() != slog.LevelWarn && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if lv.Level() != slog.LevelWarn {
+		t.Errorf("level = %v after ttl, want WARN", lv.Level())
+	}
+}
+
+func TestLevelAdminPermanentCancelsRevert(t *testing.T) {
+	admin := NewLevelAdmin()
+	lv := new(slog.LevelVar)
+	admin.Register("app", lv)
+	if err := admin.SetLevel("app", slog.LevelDebug, 10*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := admin.SetLevel("app", slog.LevelWarn, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if lv.Level() != slog.LevelWarn {
+		t.Errorf("level = %v, want WARN to survive the cancelled revert", lv.Level())
+	}
+}
+
+func TestLevelAdminErrors(t *testing.T) {
+	admin := NewLevelAdmin()
+	admin.Register("app", new(slog.LevelVar))
+	tests := []struct {
+		method string
+		target string
+		code   int
+	}{
+		{http.MethodPut, "/?level=debug", http.StatusBadRequest},
+		{http.MethodPut, "/?name=app", http.StatusBadRequest},
+		{http.MethodPut, "/?name=app&level=loud", http.StatusBadRequest},
+		{http.MethodPut, "/?name=app&level=debug&ttl=soon", http.StatusBadRequest},
+		{http.MethodPut, "/?name=nope&level=debug", http.StatusNotFound},
+		{http.MethodDelete, "/", http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+		if rec.Code != tt.code {
+			t.Errorf("%s %s: status = %d, want %d", tt.method, tt.target, rec.Code, tt.code)
+		}
+	}
+	if err := admin.SetLevel("nope", slog.LevelDebug, 0); !errors.Is(err, ErrUnknownLevel) {
+		t.Errorf("SetLevel error = %v, want ErrUnknownLevel", err)
+	}
+}
@@ -0,0 +1,354 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+)
+
+// ErrAsyncClosed is returned by AsyncHandler.Handle once the handler has been closed.
+var ErrAsyncClosed = errors.New("async handler closed")
+
+// DefaultAsyncQueueSize is the queue capacity used when AsyncOptions.QueueSize is not positive.
+const DefaultAsyncQueueSize = 1024
+
+// AsyncPolicy selects what AsyncHandler does with a record when its queue is full.
+type AsyncPolicy int
+
+const (
+	AsyncBlock      AsyncPolicy = iota // wait until the writer makes room
+	AsyncDropNewest                    // discard the incoming record
+	AsyncDropOldest                    // discard the oldest queued record
+)
+
+// String returns "block", "drop_newest" or "drop_oldest".
+func (p AsyncPolicy) String() string {
+	switch p {
+	case AsyncBlock:
+		return "block"
+	case AsyncDropNewest:
+		return "drop_newest"
+	case AsyncDropOldest:
+		return "drop_oldest"
+	}
+	return "unknown"
+}
+
+// AsyncOptions configure an AsyncHandler.
+type AsyncOptions struct {
+	// QueueSize is the number of records that may wait for the writer.
+	// If not positive, DefaultAsyncQueueSize is used.
+	QueueSize int
+
+	// Policy applies when the queue is full. The zero value blocks.
+	Policy AsyncPolicy
+
+	// OnError, if non-nil, is called on the writer goroutine with every error
+	// returned by the wrapped handler. Errors are counted either way.
+	OnError func(error)
+}
+
+// AsyncStats are the counters of an AsyncHandler.
+type AsyncStats struct {
+	Queued        int    // records currently waiting for the writer
+	Written       uint64 // records handled by the wrapped handler without error
+	Errors        uint64 // records the wrapped handler failed to handle
+	DroppedNewest uint64 // incoming records discarded on a full queue
+	DroppedOldest uint64 // queued records discarded to make room
+}
+
+// Dropped returns the total number of discarded records.
+func (s AsyncStats) Dropped() uint64 {
+	return s.DroppedNewest + s.DroppedOldest
+}
+
+// AsyncHandler hands records to a background goroutine that passes them to
+// the wrapped handler, so that slow outputs do not stall the logging call.
+// Records are snapshotted before they are queued: their attributes are cloned
+// (Record.Clone) and slog.LogValuer values are resolved on the calling goroutine.
+//
+// Handlers derived with WithAttrs and WithGroup share the queue and the writer
+// goroutine of their parent. Call Flush to wait for queued records and Close
+// to stop the writer once logging is done.
+type AsyncHandler struct {
+	next  slog.Handler
+	queue *asyncQueue
+}
+
+// NewAsyncHandler starts the writer goroutine and returns a handler that
+// queues records for next.
+func NewAsyncHandler(next slog.Handler, opts AsyncOptions) *AsyncHandler {
+	size := opts.QueueSize
+	if size <= 0 {
+		size = DefaultAsyncQueueSize
+	}
+	q := &asyncQueue{
+		buf:     make([]asyncRecord, size),
+		policy:  opts.Policy,
+		onError: opts.OnError,
+		done:    make(chan struct{}),
+	}
+	q.notEmpty.L = &q.mu
+	q.notFull.L = &q.mu
+	go q.run()
+	return &AsyncHandler{next: next, queue: q}
+}
+
+// WithAsync returns a ConfigOption that makes handlers built from the config
+// write asynchronously through an AsyncHandler (see AsyncHandlerOf).
+// It has no effect on a CustomHandler.
+func WithAsync(opts AsyncOptions) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.Async = &opts
+		cfg.touch()
+	}
+}
+
+// AsyncHandlerOf returns the AsyncHandler of a logger built from a config
+// with WithAsync, or nil if the logger writes synchronously.
+func AsyncHandlerOf(l *slog.Logger) *AsyncHandler {
+	if l == nil {
+		return nil
+	}
+	h, _ := findHandler[*AsyncHandler](l.Handler())
+	return h
+}
+
+func (h *AsyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle queues a snapshot of r. It returns ErrAsyncClosed after Close;
+// errors of the wrapped handler are reported through AsyncOptions.OnError.
+// The wrapped handler receives the values of ctx but not its cancellation,
+// since the record is usually written after the logging call has returned.
+func (h *AsyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.queue.push(asyncRecord{ctx: context.WithoutCancel(ctx), handler: h.next, record: snapshotRecord(r)})
+}
+
+func (h *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &AsyncHandler{next: h.next.WithAttrs(attrs), queue: h.queue}
+}
+
+func (h *AsyncHandler) WithGroup(name string) slog.Handler {
+	return &AsyncHandler{next: h.next.WithGroup(name), queue: h.queue}
+}
+
+func (h *AsyncHandler) Unwrap() slog.Handler {
+	return h.next
+}
+
+// Flush waits until every record queued before the call has been written or
+// dropped, or until ctx is done.
+func (h *AsyncHandler) Flush(ctx context.Context) error {
+	return h.queue.flush(ctx)
+}
+
+// Close stops accepting records, waits for the queued ones to be written and
+// stops the writer goroutine. If the wrapped handler implements io.Closer, it
+// is closed as well and its error returned. It is safe to call more than once.
+func (h *AsyncHandler) Close() error {
+	h.queue.close()
+	if c, ok := h.next.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
+
+// Stats returns a snapshot of the handler's counters.
+func (h *AsyncHandler) Stats() AsyncStats {
+	h.queue.mu.Lock()
+	defer h.queue.mu.Unlock()
+	st := h.queue.stats
+	st.Queued = h.queue.n
+	return st
+}
+
+// asyncRecord is a queued record and the handler it is destined for.
+type asyncRecord struct {
+	ctx     context.Context
+	handler slog.Handler
+	record  slog.Record
+}
+
+// asyncQueue is the bounded ring buffer shared by an AsyncHandler and the
+// handlers derived from it.
+type asyncQueue struct {
+	mu       sync.Mutex
+	notEmpty sync.Cond
+	notFull  sync.Cond
+	buf      []asyncRecord
+	head, n  int
+	policy   AsyncPolicy
+	onError  func(error)
+	closed   bool
+	done     chan struct{} // closed when the writer goroutine exits
+
+	// accepted and finished count records that entered and left the queue;
+	// Flush waits for finished to catch up with accepted.
+	accepted, finished uint64
+	progress           chan struct{} // closed when finished advances, if non-nil
+	stats              AsyncStats
+}
+
+func (q *asyncQueue) push(item asyncRecord) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrAsyncClosed
+	}
+	if q.n == len(q.buf) {
+		switch q.policy {
+		case AsyncDropNewest:
+			q.stats.DroppedNewest++
+			return nil
+		case AsyncDropOldest:
+			q.pop()
+			q.stats.DroppedOldest++
+			q.advance()
+		default:
+			for q.n == len(q.buf) && !q.closed {
+				q.notFull.Wait()
+			}
+			if q.closed {
+				return ErrAsyncClosed
+			}
+		}
+	}
+	q.buf[(q.head+q.n)%len(q.buf)] = item
+	q.n++
+	q.accepted++
+	q.notEmpty.Signal()
+	return nil
+}
+
+// pop removes and returns the oldest queued record. q.mu must be held.
+func (q *asyncQueue) pop() asyncRecord {
+	item := q.buf[q.head]
+	q.buf[q.head] = asyncRecord{}
+	q.head = (q.head + 1) % len(q.buf)
+	q.n--
+	q.notFull.Signal()
+	return item
+}
+
+// advance counts a record that left the queue and wakes flushers. q.mu must be held.
+func (q *asyncQueue) advance() {
+	q.finished++
+	if q.progress != nil {
+		close(q.progress)
+		q.progress = nil
+	}
+}
+
+// run is the writer goroutine. It drains the queue after close before exiting.
+func (q *asyncQueue) run() {
+	defer close(q.done)
+	for {
+		q.mu.Lock()
+		for q.n == 0 && !q.closed {
+			q.notEmpty.Wait()
+		}
+		if q.n == 0 {
+			q.mu.Unlock()
+			return
+		}
+		item := q.pop()
+		q.mu.Unlock()
+
+		err := item.handler.Handle(item.ctx, item.record)
+
+		q.mu.Lock()
+		if err != nil {
+			q.stats.Errors++
+		} else {
+			q.stats.Written++
+		}
+		q.advance()
+		q.mu.Unlock()
+		if err != nil && q.onError != nil {
+			q.onError(err)
+		}
+	}
+}
+
+func (q *asyncQueue) flush(ctx context.Context) error {
+	q.mu.Lock()
+	target := q.accepted
+	for q.finished < target {
+		if q.progress == nil {
+			q.progress = make(chan struct{})
+		}
+		progress := q.progress
+		q.mu.Unlock()
+		select {
+		case <-progress:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		q.mu.Lock()
+	}
+	q.mu.Unlock()
+	return nil
+}
+
+func (q *asyncQueue) close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		q.notEmpty.Broadcast()
+		q.notFull.Broadcast()
+	}
+	q.mu.Unlock()
+	<-q.done
+}
+
+// snapshotRecord returns a copy of r that shares no mutable state with the
+// caller: the attribute storage is cloned and LogValuer values are resolved.
+func snapshotRecord(r slog.Record) slog.Record {
+	resolve := false
+	r.Attrs(func(a slog.Attr) bool {
+		resolve = needsResolve(a.Value)
+		return !resolve
+	})
+	if !resolve {
+		return r.Clone()
+	}
+	c := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		c.AddAttrs(resolveAttr(a))
+		return true
+	})
+	return c
+}
+
+// needsResolve reports whether v is, or contains, a slog.LogValuer.
+func needsResolve(v slog.Value) bool {
+	switch v.Kind() {
+	case slog.KindLogValuer:
+		return true
+	case slog.KindGroup:
+		for _, a := range v.Group() {
+			if needsResolve(a.Value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveAttr resolves LogValuer values in a, including inside groups.
+func resolveAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() == slog.KindGroup {
+		group := a.Value.Group()
+		resolved := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			resolved[i] = resolveAttr(ga)
+		}
+		a.Value = slog.GroupValue(resolved...)
+	}
+	return a
+}
@@ -0,0 +1,276 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// gateHandler blocks in Handle until its gate is opened.
+type gateHandler struct {
+	*testHandler
+	gate chan struct{}
+}
+
+func newGateHandler() *gateHandler {
+	return &gateHandler{testHandler: newTestHandler(), gate: make(chan struct{})}
+}
+
+func (h *gateHandler) Handle(ctx context.Context, r slog.Record) error {
+	<-h.gate
+	return h.testHandler.Handle(ctx, r)
+}
+
+// messages returns the messages of all records stored by th, in order.
+func messages(th *testHandler) []string {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	var msgs []string
+	for _, r := range *th.records {
+		msgs = append(msgs, r.Message)
+	}
+	return msgs
+}
+
+func asyncRecordMsg(msg string) slog.Record {
+	return slog.NewRecord(testTime, slog.LevelInfo, msg, 0)
+}
+
+func TestAsyncHandlerFlush(t *testing.T) {
+	th := newTestHandler()
+	h := NewAsyncHandler(th, AsyncOptions{QueueSize: 4})
+	defer h.Close()
+	logger := slog.New(h).With("k", "v")
+	for _, msg := range []string{"a", "b", "c", "d", "e", "f"} {
+		logger.Info(msg)
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if got := strings.Join(messages(th), ""); got != "abcdef" {
+		t.Errorf("messages = %q, want %q", got, "abcdef")
+	}
+	if got := flattenRecord(th.lastRecord())["k"]; got != "v" {
+		t.Errorf("attr k = %v, want v", got)
+	}
+	if st := h.Stats(); st.Written != 6 || st.Dropped() != 0 || st.Queued != 0 {
+		t.Errorf("Stats = %+v", st)
+	}
+}
+
+func TestAsyncHandlerDropPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy AsyncPolicy
+		want   string
+	}{
+		{AsyncDropNewest, "abc"},
+		{AsyncDropOldest, "ade"},
+	} {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			gh := newGateHandler()
+			h := NewAsyncHandler(gh, AsyncOptions{QueueSize: 2, Policy: tc.policy})
+			ctx := context.Background()
+			h.Handle(ctx, asyncRecordMsg("a"))
+			// Wait for the writer to take "a" so that the queue holds exactly two records.
+			for h.Stats().Queued != 0 {
+				time.Sleep(time.Millisecond)
+			}
+			for _, msg := range []string{"b", "c", "d", "e"} {
+				if err := h.Handle(ctx, asyncRecordMsg(msg)); err != nil {
+					t.Fatalf("Handle(%s): %v", msg, err)
+				}
+			}
+			close(gh.gate)
+			h.Close()
+			if got := strings.Join(messages(gh.testHandler), ""); got != tc.want {
+				t.Errorf("messages = %q, want %q", got, tc.want)
+			}
+			if st := h.Stats(); st.Dropped() != 2 {
+				t.Errorf("Stats = %+v, want 2 dropped", st)
+			}
+		})
+	}
+}
+
+func TestAsyncHandlerBlock(t *testing.T) {
+	gh := newGateHandler()
+	h := NewAsyncHandler(gh, AsyncOptions{QueueSize: 1})
+	ctx := context.Background()
+	h.Handle(ctx, asyncRecordMsg("a"))
+	for h.Stats().Queued != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	h.Handle(ctx, asyncRecordMsg("b"))
+
+	done := make(chan struct{})
+	go func() {
+		h.Handle(ctx, asyncRecordMsg("c"))
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatal("Handle returned while the queue was full")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(gh.gate)
+	<-done
+	h.Close()
+	if got := strings.Join(messages(gh.testHandler), ""); got != "abc" {
+		t.Errorf("messages = %q, want %q", got, "abc")
+	}
+}
+
+func TestAsyncHandlerFlushContext(t *testing.T) {
+	gh := newGateHandler()
+	h := NewAsyncHandler(gh, AsyncOptions{})
+	h.Handle(context.Background(), asyncRecordMsg("a"))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := h.Flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Flush error = %v, want deadline exceeded", err)
+	}
+	close(gh.gate)
+	if err := h.Flush(context.Background()); err != nil {
+		t.Errorf("Flush: %v", err)
+	}
+	h.Close()
+}
+
+func TestAsyncHandlerClose(t *testing.T) {
+	th := newTestHandler()
+	h := NewAsyncHandler(th, AsyncOptions{})
+	h.Handle(context.Background(), asyncRecordMsg("a"))
+	h.Close()
+	h.Close()
+	if th.lastRecord() == nil {
+		t.Error("queued record not written on Close")
+	}
+	if err := h.Handle(context.Background(), asyncRecordMsg("b")); !errors.Is(err, ErrAsyncClosed) {
+		t.Errorf("Handle after Close = %v, want ErrAsyncClosed", err)
+	}
+}
+
+// ctxHandler records the context error and the value of ctxKey{} seen by Handle.
+type ctxHandler struct {
+	*testHandler
+	errs   []error
+	values []any
+}
+
+type ctxKey struct{}
+
+func (h *ctxHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	h.errs = append(h.errs, ctx.Err())
+	h.values = append(h.values, ctx.Value(ctxKey{}))
+	h.mu.Unlock()
+	return h.testHandler.Handle(ctx, r)
+}
+
+// closingHandler fails to close with err.
+type closingHandler struct {
+	*testHandler
+	err error
+}
+
+func (h closingHandler) Close() error { return h.err }
+
+func TestAsyncHandlerDetachesContext(t *testing.T) {
+	ch := &ctxHandler{testHandler: newTestHandler()}
+	h := NewAsyncHandler(ch, AsyncOptions{})
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), ctxKey{}, "request"))
+	h.Handle(ctx, asyncRecordMsg("a"))
+	cancel()
+	h.Close()
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+	if len(ch.errs) != 1 || ch.errs[0] != nil || ch.values[0] != "request" {
+		t.Errorf("wrapped handler saw errors %v and values %v", ch.errs, ch.values)
+	}
+}
+
+func TestAsyncHandlerCloseError(t *testing.T) {
+	errSink := errors.New("flush failed")
+	h := NewAsyncHandler(closingHandler{newTestHandler(), errSink}, AsyncOptions{})
+	if err := h.Close(); !errors.Is(err, errSink) {
+		t.Errorf("Close = %v, want %v", err, errSink)
+	}
+}
+
+func TestAsyncHandlerErrors(t *testing.T) {
+	var (
+		mu   sync.Mutex
+		errs []error
+	)
+	h := NewAsyncHandler(failingHandler{newTestHandler()}, AsyncOptions{OnError: func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+	}})
+	h.Handle(context.Background(), asyncRecordMsg("a"))
+	h.Close()
+	if st := h.Stats(); st.Errors != 1 || st.Written != 0 {
+		t.Errorf("Stats = %+v, want 1 error", st)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(errs) != 1 || !strings.Contains(errs[0].Error(), "sink down") {
+		t.Errorf("OnError got %v", errs)
+	}
+}
+
+// counter is a LogValuer over mutable state.
+type counter struct{ n int }
+
+func (c *counter) LogValue() slog.Value { return slog.IntValue(c.n) }
+
+func TestAsyncHandlerSnapshot(t *testing.T) {
+	gh := newGateHandler()
+	h := NewAsyncHandler(gh, AsyncOptions{})
+	c := &counter{n: 1}
+	r := asyncRecordMsg("a")
+	r.AddAttrs(slog.Any("count", c), slog.Group("g", slog.Any("count", c)))
+	h.Handle(context.Background(), r)
+	c.n = 2
+	r.AddAttrs(slog.String("late", "x"))
+	close(gh.gate)
+	h.Close()
+
+	attrs := flattenRecord(gh.lastRecord())
+	if attrs["count"] != int64(1) || attrs["g.count"] != int64(1) {
+		t.Errorf("LogValuer resolved late: %v", attrs)
+	}
+	if _, ok := attrs["late"]; ok {
+		t.Errorf("attribute added after Handle leaked into queued record: %v", attrs)
+	}
+}
+
+func TestWithAsync(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithOutput(&buf), WithAsync(AsyncOptions{QueueSize: 8}))
+	logger, err := cfg.BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	h := AsyncHandlerOf(logger)
+	if h == nil {
+		t.Fatal("AsyncHandlerOf = nil")
+	}
+	if LevelVarOf(logger) == nil {
+		t.Error("LevelVarOf = nil for async logger")
+	}
+	logger.Debug("hidden")
+	logger.Info("hello")
+	h.Close()
+	if out := buf.String(); !strings.Contains(out, "hello") || strings.Contains(out, "hidden") {
+		t.Errorf("output = %q", out)
+	}
+	if AsyncHandlerOf(NewSlogConfig().NewLogger()) != nil {
+		t.Error("AsyncHandlerOf of a synchronous logger is non-nil")
+	}
+}
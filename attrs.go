@@ -0,0 +1,180 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"time"
+)
+
+// attrScope holds the attributes and open groups a handler accumulated through
+// WithAttrs and WithGroup. Handlers that format records themselves use it to
+// visit every attribute of a record with its group path, following the
+// slog.Handler rules: LogValuer values are resolved, empty attributes and
+// empty groups are dropped, and groups with an empty key are inlined.
+type attrScope struct {
+	replace func([]string, slog.Attr) slog.Attr
+	groups  []string
+	attrs   []scopedAttr
+}
+
+// scopedAttr is an attribute added with WithAttrs under the groups open at the time.
+type scopedAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+// withAttrs returns a copy of the scope with attrs added under the open groups.
+func (s attrScope) withAttrs(attrs []slog.Attr) attrScope {
+	scoped := make([]scopedAttr, len(s.attrs), len(s.attrs)+len(attrs))
+	copy(scoped, s.attrs)
+	for _, a := range attrs {
+		scoped = append(scoped, scopedAttr{groups: s.groups, attr: a})
+	}
+	s.attrs = scoped
+	return s
+}
+
+// withGroup returns a copy of the scope with the group name opened.
+func (s attrScope) withGroup(name string) attrScope {
+	if name == "" {
+		return s
+	}
+	s.groups = append(slices.Clip(s.groups), name)
+	return s
+}
+
+// builtin applies ReplaceAttr to a built-in attribute (time, level, msg,
+// source) and reports whether it should be written.
+func (s attrScope) builtin(a slog.Attr) (slog.Attr, bool) {
+	if s.replace != nil {
+		a = s.replace(nil, a)
+		a.Value = a.Value.Resolve()
+	}
+	return a, a.Key != ""
+}
+
+// each calls fn for every non-group attribute of the scope and of r, in
+// order, with the path of groups it is nested in. fn must not retain groups.
+func (s attrScope) each(r slog.Record, fn func(groups []string, a slog.Attr)) {
+	for _, sa := range s.attrs {
+		s.walk(sa.groups, sa.attr, fn)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		s.walk(s.groups, a, fn)
+		return true
+	})
+}
+
+func (s attrScope) walk(groups []string, a slog.Attr, fn func([]string, slog.Attr)) {
+	a.Value = a.Value.Resolve()
+	if a.Value.Kind() != slog.KindGroup && s.replace != nil {
+		a = s.replace(groups, a)
+		a.Value = a.Value.Resolve()
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		members := a.Value.Group()
+		if len(members) == 0 {
+			return
+		}
+		if a.Key != "" {
+			groups = append(slices.Clip(groups), a.Key)
+		}
+		for _, m := range members {
+			s.walk(groups, m, fn)
+		}
+		return
+	}
+	if a.Key == "" {
+		return
+	}
+	fn(groups, a)
+}
+
+// attrNode is an attribute (value) or a group (children) in the nested form
+// of a record's attributes, for handlers that write groups as nested objects
+// or blocks.
+type attrNode struct {
+	key      string
+	value    slog.Value
+	children []*attrNode // non-nil for groups
+}
+
+// insert adds the attribute key=v under the nested groups, merging groups
+// with the same key.
+func (n *attrNode) insert(groups []string, key string, v slog.Value) {
+	for _, g := range groups {
+		n = n.group(g)
+	}
+	n.children = append(n.children, &attrNode{key: key, value: v})
+}
+
+// group returns the child group with the given key, adding it if needed.
+func (n *attrNode) group(key string) *attrNode {
+	for _, child := range n.children {
+		if child.key == key && child.children != nil {
+			return child
+		}
+	}
+	child := &attrNode{key: key, children: []*attrNode{}}
+	n.children = append(n.children, child)
+	return child
+}
+
+// appendJSON writes the children of a group node as a JSON object, in order,
+// with values encoded as slog.JSONHandler encodes them.
+func (n *attrNode) appendJSON(buf *bytes.Buffer) {
+	buf.WriteByte('{')
+	for i, child := range n.children {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		appendJSONValue(buf, slog.StringValue(child.key))
+		buf.WriteByte(':')
+		if child.children != nil {
+			child.appendJSON(buf)
+			continue
+		}
+		appendJSONValue(buf, child.value)
+	}
+	buf.WriteByte('}')
+}
+
+// appendJSONValue writes a resolved, non-group value as JSON: times in
+// RFC 3339 format, durations as nanoseconds, errors as their message and
+// non-finite floats as strings. Values that fail to marshal are written as
+// an "!ERROR:" string.
+func appendJSONValue(buf *bytes.Buffer, v slog.Value) {
+	var x any
+	switch v.Kind() {
+	case slog.KindTime:
+		x = v.Time().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		x = v.Duration().Nanoseconds()
+	case slog.KindFloat64:
+		x = v.Float64()
+		if f := v.Float64(); math.IsNaN(f) || math.IsInf(f, 0) {
+			x = fmt.Sprint(f)
+		}
+	case slog.KindAny:
+		x = v.Any()
+		if err, ok := x.(error); ok {
+			if _, ok := x.(json.Marshaler); !ok {
+				x = err.Error()
+			}
+		}
+	default:
+		x = v.Any()
+	}
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	mark := buf.Len()
+	if err := enc.Encode(x); err != nil {
+		buf.Truncate(mark)
+		enc.Encode(fmt.Sprintf("!ERROR:%v", err))
+	}
+	buf.Truncate(buf.Len() - 1) // Encode appends a newline
+}
@@ -0,0 +1,415 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// ErrBatchClosed is returned by the Handle method of batching handlers once
+// they have been closed.
+var ErrBatchClosed = errors.New("batching handler closed")
+
+// Defaults of BatchOptions and RetryPolicy.
+const (
+	DefaultBatchMaxRecords = 1000
+	DefaultBatchInterval   = time.Second
+	DefaultRetryAttempts   = 5
+	DefaultRetryMinBackoff = 100 * time.Millisecond
+	DefaultRetryMaxBackoff = 10 * time.Second
+)
+
+// DefaultHTTPTimeout bounds each request of the HTTP sinks when no client is
+// configured.
+const DefaultHTTPTimeout = 10 * time.Second
+
+// BatchOptions configure how a batching handler groups records into requests.
+// A batch is sent when it holds MaxRecords records or MaxBytes bytes, and
+// buffered records are sent at least every Interval.
+type BatchOptions struct {
+	// MaxRecords is the largest number of records in a batch.
+	// If not positive, DefaultBatchMaxRecords is used.
+	MaxRecords int
+
+	// MaxBytes, if positive, limits the encoded size of a batch. A record
+	// larger than MaxBytes is sent in a batch of its own.
+	MaxBytes int
+
+	// Interval is the longest time a record waits before being sent.
+	// If not positive, DefaultBatchInterval is used.
+	Interval time.Duration
+
+	// MaxPending is the number of records that may wait to be sent; further
+	// records are dropped and counted until the backlog shrinks. If not
+	// positive, ten times MaxRecords is used.
+	MaxPending int
+
+	// Retry controls how failed batches are retried.
+	Retry RetryPolicy
+
+	// OnError, if non-nil, is called on the sending goroutine with the error
+	// of every batch that is given up, in whole or in part. Failed records
+	// are counted either way.
+	OnError func(error)
+}
+
+// RetryPolicy controls how a failed batch is retried: after the n-th failed
+// attempt, the sender waits a random time between half and all of
+// MinBackoff*2^(n-1), capped at MaxBackoff. Batches rejected as malformed,
+// such as with HTTP status 400, are not retried.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts per batch, including the first.
+	// If not positive, DefaultRetryAttempts is used; 1 disables retries.
+	MaxAttempts int
+
+	// MinBackoff and MaxBackoff bound the wait between attempts.
+	// If not positive, DefaultRetryMinBackoff and DefaultRetryMaxBackoff are used.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+}
+
+// BatchStats are the counters of a batching handler.
+type BatchStats struct {
+	Pending int    // records waiting to be sent
+	Sent    uint64 // records delivered
+	Failed  uint64 // records given up after their last attempt or rejected
+	Dropped uint64 // records discarded because MaxPending records were waiting
+	Batches uint64 // batches delivered
+	Retries uint64 // failed attempts that were retried
+}
+
+// backoff returns the wait after the given number of failed attempts.
+func (p RetryPolicy) backoff(failures int) time.Duration {
+	d := p.MinBackoff
+	for i := 1; i < failures && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxBackoff)
+	return d/2 + rand.N(d/2+1)
+}
+
+// batcher buffers items and sends them in batches from a background
+// goroutine, retrying failed batches. It is shared by a batching handler and
+// the handlers derived from it.
+type batcher[T any] struct {
+	opts BatchOptions
+	send func(ctx context.Context, batch []T) error
+
+	// spill, if non-nil, is given the items of a batch that failed for good
+	// and returns how many of them it kept for a later attempt.
+	spill func(batch []T, err error) int
+
+	mu           sync.Mutex
+	pending      []batchItem[T]
+	pendingBytes int
+	closed       bool
+	wake         chan struct{} // signals the sender; buffered
+	done         chan struct{} // closed when the sender exits
+
+	// accepted and finished count items that entered and left the buffer;
+	// flush waits for finished to catch up with accepted.
+	accepted, finished uint64
+	progress           chan struct{} // closed when finished advances, if non-nil
+	stats              BatchStats
+}
+
+type batchItem[T any] struct {
+	item T
+	size int
+}
+
+// newBatcher applies the defaults of opts and starts the sender goroutine.
+func newBatcher[T any](opts BatchOptions, send func(context.Context, []T) error) *batcher[T] {
+	return newSpillingBatcher(opts, send, nil)
+}
+
+// newSpillingBatcher is like newBatcher, with a spill function for the items
+// of failed batches.
+func newSpillingBatcher[T any](opts BatchOptions, send func(context.Context, []T) error, spill func([]T, error) int) *batcher[T] {
+	if opts.MaxRecords <= 0 {
+		opts.MaxRecords = DefaultBatchMaxRecords
+	}
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultBatchInterval
+	}
+	if opts.MaxPending <= 0 {
+		opts.MaxPending = 10 * opts.MaxRecords
+	}
+	if opts.Retry.MaxAttempts <= 0 {
+		opts.Retry.MaxAttempts = DefaultRetryAttempts
+	}
+	if opts.Retry.MinBackoff <= 0 {
+		opts.Retry.MinBackoff = DefaultRetryMinBackoff
+	}
+	if opts.Retry.MaxBackoff <= 0 {
+		opts.Retry.MaxBackoff = DefaultRetryMaxBackoff
+	}
+	b := &batcher[T]{
+		opts:  opts,
+		send:  send,
+		spill: spill,
+		wake:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	go b.run()
+	return b
+}
+
+// add buffers an item of the given encoded size.
+func (b *batcher[T]) add(item T, size int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return ErrBatchClosed
+	}
+	if len(b.pending) >= b.opts.MaxPending {
+		b.stats.Dropped++
+		return nil
+	}
+	b.pending = append(b.pending, batchItem[T]{item: item, size: size})
+	b.pendingBytes += size
+	b.accepted++
+	if len(b.pending) >= b.opts.MaxRecords || (b.opts.MaxBytes > 0 && b.pendingBytes >= b.opts.MaxBytes) {
+		b.signal()
+	}
+	return nil
+}
+
+// signal wakes the sender without blocking.
+func (b *batcher[T]) signal() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run is the sender goroutine. Whenever it wakes up, it sends everything
+// buffered; after close it sends what remains and exits.
+func (b *batcher[T]) run() {
+	defer close(b.done)
+	ticker := time.NewTicker(b.opts.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.wake:
+		case <-ticker.C:
+		}
+		for {
+			batch, closed := b.take()
+			if len(batch) == 0 {
+				if closed {
+					return
+				}
+				break
+			}
+			b.deliver(batch)
+		}
+	}
+}
+
+// take removes the next batch from the buffer.
+func (b *batcher[T]) take() ([]T, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n, size := 0, 0
+	for n < len(b.pending) && n < b.opts.MaxRecords {
+		if n > 0 && b.opts.MaxBytes > 0 && size+b.pending[n].size > b.opts.MaxBytes {
+			break
+		}
+		size += b.pending[n].size
+		n++
+	}
+	batch := make([]T, n)
+	for i := range batch {
+		batch[i] = b.pending[i].item
+	}
+	b.pending = b.pending[n:]
+	b.pendingBytes -= size
+	if len(b.pending) == 0 {
+		b.pending = nil
+	}
+	return batch, b.closed
+}
+
+// deliver sends a batch, retrying according to the policy, and records the
+// outcome. A send that fails with a *partialError is retried with the items
+// it returns only. Items that fail for good are offered to spill; those it
+// keeps are neither sent nor failed.
+func (b *batcher[T]) deliver(batch []T) {
+	total, failed := len(batch), 0
+	var err, rejected error
+	for attempt := 1; ; attempt++ {
+		err = b.send(context.Background(), batch)
+		var partial *partialError[T]
+		if errors.As(err, &partial) {
+			failed += partial.failed
+			batch = partial.retry
+			if partial.failed > 0 {
+				rejected = err
+			}
+		}
+		if err == nil || len(batch) == 0 || attempt >= b.opts.Retry.MaxAttempts || !retryable(err) {
+			break
+		}
+		b.mu.Lock()
+		b.stats.Retries++
+		b.mu.Unlock()
+		time.Sleep(b.opts.Retry.backoff(attempt))
+	}
+	spilled := 0
+	if err != nil {
+		if b.spill != nil {
+			spilled = b.spill(batch, err)
+		}
+		failed += len(batch) - spilled
+	}
+	report := err
+	if report == nil || spilled == len(batch) {
+		report = rejected
+	}
+
+	b.mu.Lock()
+	b.stats.Failed += uint64(failed)
+	b.stats.Sent += uint64(total - failed - spilled)
+	if err == nil {
+		b.stats.Batches++
+	}
+	b.finished += uint64(total)
+	if b.progress != nil {
+		close(b.progress)
+		b.progress = nil
+	}
+	b.mu.Unlock()
+	if report != nil && b.opts.OnError != nil {
+		b.opts.OnError(report)
+	}
+}
+
+// flush sends everything buffered and waits until it has been delivered or
+// given up, or until ctx is done.
+func (b *batcher[T]) flush(ctx context.Context) error {
+	b.mu.Lock()
+	target := b.accepted
+	for b.finished < target {
+		if b.progress == nil {
+			b.progress = make(chan struct{})
+		}
+		progress := b.progress
+		b.signal()
+		b.mu.Unlock()
+		select {
+		case <-progress:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		b.mu.Lock()
+	}
+	b.mu.Unlock()
+	return nil
+}
+
+// close stops accepting items, sends the buffered ones and stops the sender.
+func (b *batcher[T]) close() {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	b.signal()
+	<-b.done
+}
+
+func (b *batcher[T]) snapshot() BatchStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := b.stats
+	st.Pending = len(b.pending)
+	return st
+}
+
+// permanentError marks a send error that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// partialError reports a batch that was delivered in part: retry holds the
+// items to send again and failed counts the items rejected for good.
+type partialError[T any] struct {
+	retry  []T
+	failed int
+	err    error
+}
+
+func (e *partialError[T]) Error() string { return e.err.Error() }
+func (e *partialError[T]) Unwrap() error { return e.err }
+
+// retryable reports whether a failed send may succeed when repeated.
+func retryable(err error) bool {
+	var perm *permanentError
+	return !errors.As(err, &perm)
+}
+
+// httpSender posts request bodies to an HTTP endpoint.
+type httpSender struct {
+	client *http.Client
+	url    string
+	header http.Header
+	gzip   bool
+}
+
+// newHTTPSender returns a sender for url. A nil client uses one with
+// DefaultHTTPTimeout.
+func newHTTPSender(client *http.Client, url string, header http.Header, gzip bool) *httpSender {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	return &httpSender{client: client, url: url, header: header.Clone(), gzip: gzip}
+}
+
+// post sends body and returns the response body. Responses other than 2xx
+// are errors; 4xx responses other than 408 and 429 are not retried.
+func (s *httpSender) post(ctx context.Context, contentType string, body []byte) ([]byte, error) {
+	if s.gzip {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		zw.Write(body)
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		body = buf.Bytes()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, &permanentError{err}
+	}
+	for k, v := range s.header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", contentType)
+	if s.gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("logger: POST %s: %s: %s", s.url, resp.Status, bytes.TrimSpace(respBody[:min(len(respBody), 512)]))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 &&
+			resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return nil, &permanentError{err}
+		}
+		return nil, err
+	}
+	return respBody, err
+}
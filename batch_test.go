@@ -0,0 +1,187 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// batchRecorder collects the batches sent by a batcher and fails the first
+// failures sends.
+type batchRecorder struct {
+	mu       sync.Mutex
+	batches  [][]int
+	failures int
+	err      error
+}
+
+func (r *batchRecorder) send(_ context.Context, batch []int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.failures > 0 {
+		r.failures--
+		return r.err
+	}
+	r.batches = append(r.batches, slices.Clone(batch))
+	return nil
+}
+
+func (r *batchRecorder) sent() [][]int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.batches)
+}
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+func TestBatcherLimits(t *testing.T) {
+	rec := &batchRecorder{}
+	gate := make(chan struct{})
+	b := newBatcher(BatchOptions{MaxRecords: 3, MaxBytes: 10, Interval: time.Hour}, func(ctx context.Context, batch []int) error {
+		<-gate
+		return rec.send(ctx, batch)
+	})
+	defer b.close()
+	// The first batch fills up and blocks the sender, so that the next
+	// records are cut into batches together.
+	for i, size := range []int{1, 1, 1, 1, 8, 20, 1} {
+		if err := b.add(i, size); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(gate)
+	if err := b.flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := [][]int{{0, 1, 2}, {3, 4}, {5}, {6}}
+	if got := rec.sent(); !slices.EqualFunc(got, want, slices.Equal) {
+		t.Errorf("batches = %v, want %v", got, want)
+	}
+	if st := b.snapshot(); st.Sent != 7 || st.Batches != 4 || st.Pending != 0 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestBatcherInterval(t *testing.T) {
+	rec := &batchRecorder{}
+	b := newBatcher(BatchOptions{Interval: 10 * time.Millisecond}, rec.send)
+	defer b.close()
+	b.add(1, 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(rec.sent()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("batch not sent after the interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBatcherRetry(t *testing.T) {
+	var reported []error
+	rec := &batchRecorder{failures: 2, err: errors.New("unavailable")}
+	b := newBatcher(BatchOptions{Interval: time.Hour, Retry: fastRetry, OnError: func(err error) {
+		reported = append(reported, err)
+	}}, rec.send)
+	b.add(1, 1)
+	b.flush(context.Background())
+	if st := b.snapshot(); st.Sent != 1 || st.Retries != 2 || st.Failed != 0 {
+		t.Errorf("after transient errors: stats = %+v", st)
+	}
+
+	rec.mu.Lock()
+	rec.failures = 5
+	rec.mu.Unlock()
+	b.add(2, 1)
+	b.flush(context.Background())
+	if st := b.snapshot(); st.Failed != 1 || st.Retries != 4 {
+		t.Errorf("after exhausting attempts: stats = %+v", st)
+	}
+
+	rec.mu.Lock()
+	rec.failures, rec.err = 1, &permanentError{errors.New("bad request")}
+	rec.mu.Unlock()
+	b.add(3, 1)
+	b.close()
+	if st := b.snapshot(); st.Failed != 2 || st.Retries != 4 {
+		t.Errorf("after a permanent error: stats = %+v", st)
+	}
+	if len(reported) != 2 {
+		t.Errorf("OnError called %d times, want 2", len(reported))
+	}
+	if err := b.add(4, 1); !errors.Is(err, ErrBatchClosed) {
+		t.Errorf("add after close = %v, want ErrBatchClosed", err)
+	}
+}
+
+func TestBatcherMaxPending(t *testing.T) {
+	started, gate := make(chan struct{}, 10), make(chan struct{})
+	b := newBatcher(BatchOptions{MaxRecords: 1, MaxPending: 2, Interval: time.Hour}, func(context.Context, []int) error {
+		started <- struct{}{}
+		<-gate
+		return nil
+	})
+	b.add(0, 1)
+	<-started // the sender took the record and blocks
+	for i := 1; i <= 4; i++ {
+		b.add(i, 1)
+	}
+	if st := b.snapshot(); st.Pending != 2 || st.Dropped != 2 {
+		t.Errorf("stats = %+v", st)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.flush(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("flush = %v, want DeadlineExceeded", err)
+	}
+	close(gate)
+	b.close()
+	if st := b.snapshot(); st.Sent != 3 {
+		t.Errorf("sent %d records, want 3", st.Sent)
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{MinBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for _, tt := range []struct {
+		failures int
+		max      time.Duration
+	}{
+		{1, 100 * time.Millisecond},
+		{2, 200 * time.Millisecond},
+		{4, 800 * time.Millisecond},
+		{5, time.Second},
+		{40, time.Second},
+	} {
+		for range 20 {
+			if d := p.backoff(tt.failures); d < tt.max/2 || d > tt.max {
+				t.Errorf("backoff(%d) = %v, want between %v and %v", tt.failures, d, tt.max/2, tt.max)
+			}
+		}
+	}
+}
+
+func TestHTTPSenderStatus(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte("details\n"))
+	}))
+	defer srv.Close()
+	s := newHTTPSender(nil, srv.URL, nil, false)
+	for code, retry := range map[int]bool{503: true, 429: true, 408: true, 400: false, 413: false} {
+		status = code
+		_, err := s.post(context.Background(), "text/plain", nil)
+		if err == nil || retryable(err) != retry {
+			t.Errorf("status %d: err = %v, retryable = %v", code, err, retryable(err))
+		}
+	}
+	status = http.StatusNoContent
+	if _, err := s.post(context.Background(), "text/plain", nil); err != nil {
+		t.Errorf("status 204: %v", err)
+	}
+}
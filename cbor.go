@@ -0,0 +1,320 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"runtime"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// CBOR major types (RFC 8949).
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// CBOR tags written by CBORHandler.
+const (
+	cborTagBignum       = 2     // unsigned bignum: uint64 attribute values
+	cborTagNegBignum    = 3     // negative bignum
+	cborTagTime         = 1001  // extended time (RFC 9581): {1: seconds, -9: nanoseconds}
+	cborTagDuration     = 1002  // duration (RFC 9581): {1: seconds, -9: nanoseconds}
+	cborTagSelfDescribe = 55799 // self-described CBOR, prefixed to every record
+)
+
+// cborMagic is the encoding of the self-describe tag that starts every record.
+var cborMagic = []byte{0xd9, 0xd9, 0xf7}
+
+// CBORHandler writes records as a sequence of CBOR maps (RFC 8949, RFC 8742),
+// a compact binary alternative to the JSON handler with the same layout:
+// time, level, msg, source and the attributes, with groups as nested maps.
+//
+// Every record starts with the self-describe tag 55799, so files can be
+// recognized and decoded without outside information. Value kinds survive the
+// round trip through CBORDecoder: times and durations use the RFC 9581 tags
+// 1001 and 1002, uint64 values the unsigned bignum tag 2, and other values
+// are written as the matching CBOR type. Values of kind Any other than
+// errors and []byte are encoded through their JSON form.
+//
+// It is registered as the "cbor" handler type.
+type CBORHandler struct {
+	mu        *sync.Mutex // serializes writes to w across derived handlers
+	w         io.Writer
+	level     slog.Leveler
+	addSource bool
+	scope     attrScope
+}
+
+// NewCBORHandler returns a CBORHandler that writes to w.
+// A nil opts uses the defaults.
+func NewCBORHandler(w io.Writer, opts *slog.HandlerOptions) *CBORHandler {
+	if opts == nil {
+		opts = &slog.HandlerOptions{}
+	}
+	h := &CBORHandler{
+		mu:        new(sync.Mutex),
+		w:         w,
+		level:     opts.Level,
+		addSource: opts.AddSource,
+		scope:     attrScope{replace: opts.ReplaceAttr},
+	}
+	if h.level == nil {
+		h.level = slog.LevelInfo
+	}
+	return h
+}
+
+func (h *CBORHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *CBORHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope.withAttrs(attrs)
+	return &h2
+}
+
+func (h *CBORHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.scope = h.scope.withGroup(name)
+	return &h2
+}
+
+func (h *CBORHandler) Handle(_ context.Context, r slog.Record) error {
+	root := &attrNode{}
+	addBuiltin := func(a slog.Attr) {
+		if a, ok := h.scope.builtin(a); ok {
+			root.insert(nil, a.Key, a.Value)
+		}
+	}
+	if !r.Time.IsZero() {
+		addBuiltin(slog.Time(slog.TimeKey, r.Time))
+	}
+	addBuiltin(slog.Any(slog.LevelKey, r.Level))
+	if h.addSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		addBuiltin(slog.Any(slog.SourceKey, &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}))
+	}
+	addBuiltin(slog.String(slog.MessageKey, r.Message))
+	h.scope.each(r, func(groups []string, a slog.Attr) {
+		root.insert(groups, a.Key, a.Value)
+	})
+
+	var buf bytes.Buffer
+	buf.Write(cborMagic)
+	encodeCBORGroup(&buf, root)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+// encodeCBORGroup writes the children of a group node as a map.
+func encodeCBORGroup(buf *bytes.Buffer, n *attrNode) {
+	writeCBORHead(buf, cborMap, uint64(len(n.children)))
+	for _, child := range n.children {
+		writeCBORText(buf, child.key)
+		if child.children != nil {
+			encodeCBORGroup(buf, child)
+			continue
+		}
+		encodeCBORValue(buf, child.value)
+	}
+}
+
+// encodeCBORValue writes a resolved, non-group slog value.
+func encodeCBORValue(buf *bytes.Buffer, v slog.Value) {
+	switch v.Kind() {
+	case slog.KindString:
+		writeCBORText(buf, v.String())
+	case slog.KindInt64:
+		writeCBORInt(buf, v.Int64())
+	case slog.KindUint64:
+		writeCBORHead(buf, cborTag, cborTagBignum)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], v.Uint64())
+		n := 0
+		for n < len(b) && b[n] == 0 {
+			n++
+		}
+		writeCBORHead(buf, cborBytes, uint64(len(b)-n))
+		buf.Write(b[n:])
+	case slog.KindFloat64:
+		writeCBORFloat(buf, v.Float64())
+	case slog.KindBool:
+		writeCBORBool(buf, v.Bool())
+	case slog.KindTime:
+		t := v.Time()
+		writeCBORHead(buf, cborTag, cborTagTime)
+		writeCBORSecondsNanos(buf, t.Unix(), int64(t.Nanosecond()))
+	case slog.KindDuration:
+		d := v.Duration()
+		writeCBORHead(buf, cborTag, cborTagDuration)
+		writeCBORSecondsNanos(buf, int64(d/time.Second), int64(d%time.Second))
+	case slog.KindGroup:
+		root := &attrNode{children: []*attrNode{}}
+		for _, a := range v.Group() {
+			root.insert(nil, a.Key, a.Value.Resolve())
+		}
+		encodeCBORGroup(buf, root)
+	default:
+		encodeCBORAny(buf, v.Any())
+	}
+}
+
+// encodeCBORAny writes a value of kind Any.
+func encodeCBORAny(buf *bytes.Buffer, x any) {
+	switch x := x.(type) {
+	case nil:
+		buf.WriteByte(0xf6)
+	case []byte:
+		writeCBORHead(buf, cborBytes, uint64(len(x)))
+		buf.Write(x)
+	case error:
+		writeCBORText(buf, x.Error())
+	case slog.Level:
+		writeCBORText(buf, x.String())
+	case *slog.Source:
+		writeCBORHead(buf, cborMap, 3)
+		writeCBORText(buf, "function")
+		writeCBORText(buf, x.Function)
+		writeCBORText(buf, "file")
+		writeCBORText(buf, x.File)
+		writeCBORText(buf, "line")
+		writeCBORInt(buf, int64(x.Line))
+	default:
+		data, err := json.Marshal(x)
+		if err != nil {
+			writeCBORText(buf, fmt.Sprintf("!ERROR:%v", err))
+			return
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var generic any
+		if err := dec.Decode(&generic); err != nil {
+			writeCBORText(buf, string(data))
+			return
+		}
+		encodeCBORJSON(buf, generic)
+	}
+}
+
+// encodeCBORJSON writes a value decoded from JSON with UseNumber.
+// Object keys are sorted, as encoding/json does.
+func encodeCBORJSON(buf *bytes.Buffer, x any) {
+	switch x := x.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		writeCBORHead(buf, cborMap, uint64(len(keys)))
+		for _, k := range keys {
+			writeCBORText(buf, k)
+			encodeCBORJSON(buf, x[k])
+		}
+	case []any:
+		writeCBORHead(buf, cborArray, uint64(len(x)))
+		for _, item := range x {
+			encodeCBORJSON(buf, item)
+		}
+	case string:
+		writeCBORText(buf, x)
+	case bool:
+		writeCBORBool(buf, x)
+	case json.Number:
+		if n, err := strconv.ParseInt(string(x), 10, 64); err == nil {
+			writeCBORInt(buf, n)
+		} else if u, err := strconv.ParseUint(string(x), 10, 64); err == nil {
+			writeCBORHead(buf, cborUint, u)
+		} else {
+			f, _ := x.Float64()
+			writeCBORFloat(buf, f)
+		}
+	default:
+		buf.WriteByte(0xf6)
+	}
+}
+
+// writeCBORHead writes the initial byte and argument of a data item.
+func writeCBORHead(buf *bytes.Buffer, major byte, n uint64) {
+	major <<= 5
+	switch {
+	case n < 24:
+		buf.WriteByte(major | byte(n))
+	case n <= math.MaxUint8:
+		buf.WriteByte(major | 24)
+		buf.WriteByte(byte(n))
+	case n <= math.MaxUint16:
+		buf.WriteByte(major | 25)
+		buf.Write(binary.BigEndian.AppendUint16(nil, uint16(n)))
+	case n <= math.MaxUint32:
+		buf.WriteByte(major | 26)
+		buf.Write(binary.BigEndian.AppendUint32(nil, uint32(n)))
+	default:
+		buf.WriteByte(major | 27)
+		buf.Write(binary.BigEndian.AppendUint64(nil, n))
+	}
+}
+
+func writeCBORInt(buf *bytes.Buffer, n int64) {
+	if n < 0 {
+		writeCBORHead(buf, cborNegInt, uint64(-1-n))
+		return
+	}
+	writeCBORHead(buf, cborUint, uint64(n))
+}
+
+func writeCBORText(buf *bytes.Buffer, s string) {
+	writeCBORHead(buf, cborText, uint64(len(s)))
+	buf.WriteString(s)
+}
+
+func writeCBORFloat(buf *bytes.Buffer, f float64) {
+	buf.WriteByte(cborSimple<<5 | 27)
+	buf.Write(binary.BigEndian.AppendUint64(nil, math.Float64bits(f)))
+}
+
+func writeCBORBool(buf *bytes.Buffer, b bool) {
+	if b {
+		buf.WriteByte(0xf5)
+	} else {
+		buf.WriteByte(0xf4)
+	}
+}
+
+// writeCBORSecondsNanos writes the RFC 9581 map {1: seconds, -9: nanoseconds},
+// leaving out zero nanoseconds.
+func writeCBORSecondsNanos(buf *bytes.Buffer, secs, nanos int64) {
+	if nanos == 0 {
+		writeCBORHead(buf, cborMap, 1)
+		writeCBORInt(buf, 1)
+		writeCBORInt(buf, secs)
+		return
+	}
+	writeCBORHead(buf, cborMap, 2)
+	writeCBORInt(buf, 1)
+	writeCBORInt(buf, secs)
+	writeCBORInt(buf, -9)
+	writeCBORInt(buf, nanos)
+}
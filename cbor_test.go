@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// cborTestAttrs covers every slog kind the CBOR encoding preserves.
+var cborTestAttrs = []slog.Attr{
+	slog.String("s", "text"),
+	slog.Int64("i", -42),
+	slog.Uint64("u", math.MaxUint64),
+	slog.Uint64("small", 7),
+	slog.Float64("f", 1.5),
+	slog.Bool("b", true),
+	slog.Time("t", time.Date(2026, 10, 16, 1, 2, 3, 4, time.UTC)),
+	slog.Duration("d", -1500*time.Millisecond),
+	slog.Group("g", slog.Int("n", 1), slog.Group("inner", slog.String("k", "v"))),
+	slog.Any("nil", nil),
+	slog.Any("err", errors.New("boom")),
+}
+
+func TestCBORRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewCBORHandler(&buf, nil)
+	r := slog.NewRecord(testTime, slog.LevelWarn+2, "hello", 0)
+	r.AddAttrs(cborTestAttrs...)
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+	if !IsCBOR(buf.Bytes()) {
+		t.Fatalf("record does not start with the self-describe tag: % x", buf.Bytes()[:3])
+	}
+
+	got, err := NewCBORDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !got.Time.Equal(testTime) || got.Level != slog.LevelWarn+2 || got.Message != "hello" {
+		t.Errorf("decoded record = %v %v %q", got.Time, got.Level, got.Message)
+	}
+	attrs := map[string]slog.Value{}
+	got.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value
+		return true
+	})
+	want := map[string]slog.Value{
+		"s":     slog.StringValue("text"),
+		"i":     slog.Int64Value(-42),
+		"u":     slog.Uint64Value(math.MaxUint64),
+		"small": slog.Uint64Value(7),
+		"f":     slog.Float64Value(1.5),
+		"b":     slog.BoolValue(true),
+		"t":     slog.TimeValue(time.Date(2026, 10, 16, 1, 2, 3, 4, time.UTC)),
+		"d":     slog.DurationValue(-1500 * time.Millisecond),
+		"g":     slog.GroupValue(slog.Int("n", 1), slog.Group("inner", slog.String("k", "v"))),
+		"nil":   slog.AnyValue(nil),
+		"err":   slog.StringValue("boom"),
+	}
+	for k, w := range want {
+		if v, ok := attrs[k]; !ok || !v.Equal(w) {
+			t.Errorf("attr %s = %v (%s), want %v (%s)", k, v, v.Kind(), w, w.Kind())
+		}
+	}
+	if _, err := NewCBORDecoder(&buf).Decode(); err != io.EOF {
+		t.Errorf("Decode at end = %v, want io.EOF", err)
+	}
+}
+
+func TestCBORToJSONMatchesJSONHandler(t *testing.T) {
+	var cbor, want bytes.Buffer
+	fixedTime := WithHandlerOptions(&slog.HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+		if a.Key == slog.TimeKey && len(groups) == 0 {
+			a.Value = slog.TimeValue(testTime)
+		}
+		return a
+	}})
+	cfgs := []SlogConfig{
+		NewSlogConfig(WithHandlerType("cbor"), WithOutput(&cbor), fixedTime),
+		NewSlogConfig(WithHandlerType("json"), WithOutput(&want), fixedTime),
+	}
+	for _, cfg := range cfgs {
+		logger := cfg.NewLogger().With("app", "api").WithGroup("req")
+		logger.LogAttrs(context.Background(), slog.LevelInfo, "one", cborTestAttrs...)
+		logger.Error("two", "id", 2)
+	}
+	var got bytes.Buffer
+	if err := CBORToJSON(&got, &cbor); err != nil {
+		t.Fatalf("CBORToJSON: %v", err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("got\n%s\nwant\n%s", got.String(), want.String())
+	}
+}
+
+func TestCBORAnyValues(t *testing.T) {
+	var buf bytes.Buffer
+	type point struct {
+		X, Y int
+	}
+	slog.New(NewCBORHandler(&buf, nil)).Info("m", "p", point{1, 2}, "raw", []byte{1, 2}, "list", []string{"a", "b"})
+	r, err := NewCBORDecoder(&buf).Decode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	attrs := flattenRecord(&r)
+	if attrs["p.X"] != int64(1) || attrs["p.Y"] != int64(2) {
+		t.Errorf("struct attrs = %v", attrs)
+	}
+	if raw, ok := attrs["raw"].([]byte); !ok || !bytes.Equal(raw, []byte{1, 2}) {
+		t.Errorf("raw = %#v", attrs["raw"])
+	}
+	if list, ok := attrs["list"].([]any); !ok || len(list) != 2 || list[1] != "b" {
+		t.Errorf("list = %#v", attrs["list"])
+	}
+}
+
+func TestCBORDecoderForeignEncodings(t *testing.T) {
+	data := []byte{
+		0xbf,                                                       // indefinite map
+		0x63, 'm', 's', 'g', 0x7f, 0x62, 'h', 'i', 0x61, '!', 0xff, // indefinite text "hi!"
+		0x61, 'h', 0xf9, 0x3e, 0x00, // half float 1.5
+		0x61, 'a', 0x9f, 0x01, 0x20, 0xff, // indefinite array [1, -1]
+		0xff,
+	}
+	r, err := NewCBORDecoder(bytes.NewReader(data)).Decode()
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	attrs := flattenRecord(&r)
+	if r.Message != "hi!" || attrs["h"] != 1.5 {
+		t.Errorf("record = %q %v", r.Message, attrs)
+	}
+	if list, ok := attrs["a"].([]any); !ok || len(list) != 2 || list[1] != int64(-1) {
+		t.Errorf("a = %#v", attrs["a"])
+	}
+}
+
+func TestCBORDecoderTruncated(t *testing.T) {
+	var buf bytes.Buffer
+	slog.New(NewCBORHandler(&buf, nil)).Info("hello", "k", "v")
+	data := buf.Bytes()[:buf.Len()-2]
+	if _, err := NewCBORDecoder(bytes.NewReader(data)).Decode(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("Decode error = %v, want unexpected EOF", err)
+	}
+}
+
+func TestOpenLogFileCBOR(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.cbor")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.New(NewCBORHandler(f, nil)).Info("stored", "n", 1)
+	f.Close()
+
+	rc, err := OpenLogFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte(`"msg":"stored","n":1}`)) {
+		t.Errorf("OpenLogFile output = %s", data)
+	}
+}
@@ -0,0 +1,360 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/big"
+	"time"
+)
+
+// cborMaxDepth bounds the nesting of decoded arrays, maps and tags.
+const cborMaxDepth = 64
+
+// errCBORBreak is returned by readValue for the "break" stop code of an
+// indefinite-length item.
+var errCBORBreak = errors.New("cbor: unexpected break")
+
+// CBORDecoder reads records written by CBORHandler.
+type CBORDecoder struct {
+	r *bufio.Reader
+}
+
+// NewCBORDecoder returns a decoder that reads a CBOR record sequence from r.
+func NewCBORDecoder(r io.Reader) *CBORDecoder {
+	br, ok := r.(*bufio.Reader)
+	if !ok {
+		br = bufio.NewReader(r)
+	}
+	return &CBORDecoder{r: br}
+}
+
+// Decode reads the next record. The "time", "level" and "msg" entries become
+// the record's time, level and message; all other entries become attributes,
+// with nested maps as groups. It returns io.EOF when no records remain.
+func (d *CBORDecoder) Decode() (slog.Record, error) {
+	if _, err := d.r.Peek(1); err != nil {
+		return slog.Record{}, err
+	}
+	v, err := d.readValue(0)
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return slog.Record{}, fmt.Errorf("cbor: %w", err)
+	}
+	if v.Kind() != slog.KindGroup {
+		return slog.Record{}, fmt.Errorf("cbor: record is a %s, not a map", v.Kind())
+	}
+	var (
+		r     slog.Record
+		attrs []slog.Attr
+	)
+	r.Level = slog.LevelInfo
+	for _, a := range v.Group() {
+		switch {
+		case a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime:
+			r.Time = a.Value.Time()
+		case a.Key == slog.LevelKey && a.Value.Kind() == slog.KindString:
+			level, err := parseLevel(a.Value.String())
+			if err != nil {
+				return slog.Record{}, fmt.Errorf("cbor: %w", err)
+			}
+			r.Level = level
+		case a.Key == slog.MessageKey && a.Value.Kind() == slog.KindString:
+			r.Message = a.Value.String()
+		default:
+			attrs = append(attrs, a)
+		}
+	}
+	rec := slog.NewRecord(r.Time, r.Level, r.Message, 0)
+	rec.AddAttrs(attrs...)
+	return rec, nil
+}
+
+// CBORToJSON converts the records read from r into JSON lines written to w,
+// in the format of slog.JSONHandler.
+func CBORToJSON(w io.Writer, r io.Reader) error {
+	dec := NewCBORDecoder(r)
+	h := slog.NewJSONHandler(w, &slog.HandlerOptions{Level: slog.Level(math.MinInt)})
+	for {
+		rec, err := dec.Decode()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := h.Handle(context.Background(), rec); err != nil {
+			return err
+		}
+	}
+}
+
+// IsCBOR reports whether data starts with the self-describe tag that
+// CBORHandler writes before every record.
+func IsCBOR(data []byte) bool {
+	return bytes.HasPrefix(data, cborMagic)
+}
+
+// cborJSONReader returns a reader of rc that converts CBOR records to JSON
+// lines on the fly, or passes the data through unchanged if it is not CBOR.
+// Closing it closes rc.
+func cborJSONReader(rc io.ReadCloser) io.ReadCloser {
+	br := bufio.NewReader(rc)
+	if magic, _ := br.Peek(len(cborMagic)); !IsCBOR(magic) {
+		return struct {
+			io.Reader
+			io.Closer
+		}{br, rc}
+	}
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(CBORToJSON(pw, br))
+	}()
+	return &pipeReadCloser{PipeReader: pr, src: rc}
+}
+
+// pipeReadCloser closes both ends of a conversion: the pipe and its source.
+type pipeReadCloser struct {
+	*io.PipeReader
+	src io.Closer
+}
+
+func (r *pipeReadCloser) Close() error {
+	r.PipeReader.Close()
+	return r.src.Close()
+}
+
+// readHead reads the initial byte of a data item and its argument.
+// For indefinite lengths, info is 31 and n is 0.
+func (d *CBORDecoder) readHead() (major, info byte, n uint64, err error) {
+	b, err := d.r.ReadByte()
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info = b>>5, b&0x1f
+	var size int
+	switch {
+	case info < 24:
+		return major, info, uint64(info), nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	case info == 31 && major != cborUint && major != cborNegInt && major != cborTag:
+		return major, info, 0, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("invalid additional information %d for major type %d", info, major)
+	}
+	var arg [8]byte
+	if _, err := io.ReadFull(d.r, arg[8-size:]); err != nil {
+		return 0, 0, 0, err
+	}
+	return major, info, binary.BigEndian.Uint64(arg[:]), nil
+}
+
+// readString reads the content of a byte or text string whose head was read.
+func (d *CBORDecoder) readString(major, info byte, n uint64) ([]byte, error) {
+	if info != 31 {
+		var buf bytes.Buffer
+		if _, err := io.CopyN(&buf, d.r, int64(min(n, math.MaxInt64))); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	var out []byte
+	for {
+		m, chunkInfo, cn, err := d.readHead()
+		if err != nil {
+			return nil, err
+		}
+		if m == cborSimple && chunkInfo == 31 {
+			return out, nil
+		}
+		if m != major || chunkInfo == 31 {
+			return nil, fmt.Errorf("invalid chunk of indefinite-length string")
+		}
+		chunk, err := d.readString(m, chunkInfo, cn)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, chunk...)
+	}
+}
+
+// readValue reads one data item as a slog value. Maps become groups;
+// arrays and other containers nested in them become Any values.
+func (d *CBORDecoder) readValue(depth int) (slog.Value, error) {
+	if depth > cborMaxDepth {
+		return slog.Value{}, errors.New("nesting too deep")
+	}
+	major, info, n, err := d.readHead()
+	if err != nil {
+		return slog.Value{}, err
+	}
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return slog.Uint64Value(n), nil
+		}
+		return slog.Int64Value(int64(n)), nil
+	case cborNegInt:
+		if n > math.MaxInt64 {
+			return slog.AnyValue(new(big.Int).Sub(big.NewInt(-1), new(big.Int).SetUint64(n))), nil
+		}
+		return slog.Int64Value(-1 - int64(n)), nil
+	case cborBytes:
+		b, err := d.readString(major, info, n)
+		return slog.AnyValue(b), err
+	case cborText:
+		b, err := d.readString(major, info, n)
+		return slog.StringValue(string(b)), err
+	case cborArray:
+		var items []any
+		for i := uint64(0); info == 31 || i < n; i++ {
+			v, err := d.readValue(depth + 1)
+			if err == errCBORBreak && info == 31 {
+				break
+			}
+			if err != nil {
+				return slog.Value{}, err
+			}
+			items = append(items, cborAny(v))
+		}
+		return slog.AnyValue(items), nil
+	case cborMap:
+		var attrs []slog.Attr
+		for i := uint64(0); info == 31 || i < n; i++ {
+			k, err := d.readValue(depth + 1)
+			if err == errCBORBreak && info == 31 {
+				break
+			}
+			if err != nil {
+				return slog.Value{}, err
+			}
+			v, err := d.readValue(depth + 1)
+			if err != nil {
+				return slog.Value{}, err
+			}
+			key := k.String()
+			if k.Kind() == slog.KindAny {
+				key = fmt.Sprint(k.Any())
+			}
+			attrs = append(attrs, slog.Attr{Key: key, Value: v})
+		}
+		return slog.GroupValue(attrs...), nil
+	case cborTag:
+		v, err := d.readValue(depth + 1)
+		if err != nil {
+			return slog.Value{}, err
+		}
+		return cborTagged(n, v)
+	}
+	// Major type 7: simple values and floats.
+	switch info {
+	case 20:
+		return slog.BoolValue(false), nil
+	case 21:
+		return slog.BoolValue(true), nil
+	case 22, 23:
+		return slog.AnyValue(nil), nil
+	case 25:
+		return slog.Float64Value(float64(halfToFloat32(uint16(n)))), nil
+	case 26:
+		return slog.Float64Value(float64(math.Float32frombits(uint32(n)))), nil
+	case 27:
+		return slog.Float64Value(math.Float64frombits(n)), nil
+	case 31:
+		return slog.Value{}, errCBORBreak
+	}
+	return slog.AnyValue(fmt.Sprintf("simple(%d)", n)), nil
+}
+
+// cborTagged interprets a tagged value. Unknown tags yield the value itself.
+func cborTagged(tag uint64, v slog.Value) (slog.Value, error) {
+	switch tag {
+	case cborTagBignum, cborTagNegBignum:
+		b, ok := v.Any().([]byte)
+		if v.Kind() != slog.KindAny || !ok {
+			return slog.Value{}, fmt.Errorf("tag %d: expected byte string", tag)
+		}
+		n := new(big.Int).SetBytes(b)
+		if tag == cborTagNegBignum {
+			return slog.AnyValue(n.Sub(big.NewInt(-1), n)), nil
+		}
+		if n.IsUint64() {
+			return slog.Uint64Value(n.Uint64()), nil
+		}
+		return slog.AnyValue(n), nil
+	case cborTagTime, cborTagDuration:
+		secs, nanos, err := cborSecondsNanos(v)
+		if err != nil {
+			return slog.Value{}, fmt.Errorf("tag %d: %w", tag, err)
+		}
+		if tag == cborTagTime {
+			return slog.TimeValue(time.Unix(secs, nanos).UTC()), nil
+		}
+		return slog.DurationValue(time.Duration(secs)*time.Second + time.Duration(nanos)), nil
+	}
+	return v, nil
+}
+
+// cborSecondsNanos reads the RFC 9581 map {1: seconds, -9: nanoseconds}.
+func cborSecondsNanos(v slog.Value) (secs, nanos int64, err error) {
+	if v.Kind() != slog.KindGroup {
+		return 0, 0, errors.New("expected map")
+	}
+	for _, a := range v.Group() {
+		if a.Value.Kind() != slog.KindInt64 {
+			return 0, 0, fmt.Errorf("key %s: expected integer", a.Key)
+		}
+		switch a.Key {
+		case "1":
+			secs = a.Value.Int64()
+		case "-9":
+			nanos = a.Value.Int64()
+		}
+	}
+	return secs, nanos, nil
+}
+
+// cborAny converts a decoded value into a plain Go value for use inside arrays.
+func cborAny(v slog.Value) any {
+	if v.Kind() != slog.KindGroup {
+		return v.Any()
+	}
+	m := make(map[string]any, len(v.Group()))
+	for _, a := range v.Group() {
+		m[a.Key] = cborAny(a.Value)
+	}
+	return m
+}
+
+// halfToFloat32 converts an IEEE 754 half-precision float.
+func halfToFloat32(h uint16) float32 {
+	sign := uint32(h>>15) << 31
+	exp := uint32(h>>10) & 0x1f
+	frac := uint32(h) & 0x3ff
+	switch exp {
+	case 0:
+		f := float32(frac) / (1 << 24)
+		if sign != 0 {
+			f = -f
+		}
+		return f
+	case 0x1f:
+		return math.Float32frombits(sign | 0xff<<23 | frac<<13)
+	}
+	return math.Float32frombits(sign | (exp+112)<<23 | frac<<13)
+}
@@ -0,0 +1,35 @@
+package logger
+
+import "log/slog"
+
+// handlerUnwrapper is implemented by handlers that wrap a single handler.
+type handlerUnwrapper interface {
+	Unwrap() slog.Handler
+}
+
+// walkHandlers calls fn for h and every handler it wraps, outermost first,
+// until fn returns false.
+func walkHandlers(h slog.Handler, fn func(slog.Handler) bool) bool {
+	for h != nil {
+		if !fn(h) {
+			return false
+		}
+		u, ok := h.(handlerUnwrapper)
+		if !ok {
+			break
+		}
+		h = u.Unwrap()
+	}
+	return true
+}
+
+// findHandler returns the outermost handler of type H in the chain starting at h.
+func findHandler[H slog.Handler](h slog.Handler) (H, bool) {
+	var found H
+	ok := false
+	walkHandlers(h, func(h slog.Handler) bool {
+		found, ok = h.(H)
+		return !ok
+	})
+	return found, ok
+}
@@ -1,15 +1,21 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"os"
+	"slices"
+	"sync/atomic"
 )
 
 // SlogConfig holds all parameters needed to create a *slog.Logger.
 type SlogConfig struct {
-	// HandlerType specifies the built-in handler type: "json" or "text".
-	// If empty, defaults to "json". Ignored if CustomHandler is non-nil.
+	// HandlerType names a registered handler type, such as "json" or "text"
+	// (see RegisterHandlerType). If empty, defaults to "json".
+	// Ignored if CustomHandler is non-nil.
 	HandlerType string
 
 	// Output destination for the handler. If nil, defaults to os.Stderr.
@@ -24,6 +30,92 @@ type SlogConfig struct {
 
 	// CustomHandler, if non-nil, overrides all other fields and is used directly.
 	CustomHandler slog.Handler
+
+	// Rules rewrite attributes (redaction, renaming) before they are written.
+	// They are applied after HandlerOptions.ReplaceAttr.
+	Rules []AttrRule
+
+	// Name is the dotted name of the logger, e.g. "db.pool". If non-empty,
+	// records carry it under LoggerKey. See Named.
+	Name string
+
+	// NameLevels, if non-nil, overrides the level of named loggers by name
+	// prefix (see ParseNameLevels). It takes precedence over Level and
+	// HandlerOptions.Level for names it matches.
+	NameLevels *NameLevels
+
+	// Sinks, if non-empty, replace the single HandlerType/Output pair: records
+	// are fanned out to one handler per sink, each with its own type, output
+	// and level. See WithSink.
+	Sinks []Sink
+
+	// Destination is the address of handler types that send records to a
+	// server instead of writing to Output, such as "syslog" and "gelf".
+	// It is a URL-like string whose scheme selects the transport, e.g.
+	// "udp://localhost:514" or "http://localhost:3100".
+	Destination string
+
+	// Resource holds attributes that describe the entity producing the logs,
+	// such as service.name, for handler types that send them along with the
+	// records, such as "otlp". See WithResource.
+	Resource map[string]string
+
+	// LogfmtKeyOrder lists the keys that the "logfmt" handler type writes
+	// first (see LogfmtOptions.KeyOrder). See WithLogfmtKeyOrder.
+	LogfmtKeyOrder []string
+
+	// HTTP, if non-nil, holds the batching, circuit breaker, spool and
+	// request settings of the "http" handler type. See WithHTTPOptions.
+	HTTP *HTTPOptions
+
+	// Loki, if non-nil, holds the labels, batching and request settings of
+	// the "loki" handler type. See WithLokiOptions.
+	Loki *LokiOptions
+
+	// Elasticsearch, if non-nil, holds the index, batching and request
+	// settings of the "elasticsearch" handler type.
+	// See WithElasticsearchOptions.
+	Elasticsearch *ElasticsearchOptions
+
+	// GELF, if non-nil, holds the host, compression and chunking settings
+	// of the "gelf" handler type. See WithGELFOptions.
+	GELF *GELFOptions
+
+	// Async, if non-nil, makes built handlers queue records for a background
+	// writer instead of writing on the calling goroutine. See WithAsync.
+	Async *AsyncOptions
+
+	// Sampling, if non-nil, makes built handlers drop part of the records
+	// that repeat at a high rate. See WithSampling.
+	Sampling *SamplingOptions
+
+	// RateLimit, if non-nil, caps the rate of records of built handlers.
+	// See WithRateLimit.
+	RateLimit *RateLimitOptions
+
+	// Dedup, if non-nil, makes built handlers collapse runs of identical
+	// records into one with a repeat count. See WithDedup.
+	Dedup *DedupOptions
+
+	// err collects errors reported by options that cannot return one,
+	// such as WithEnv. It is surfaced by Err and BuildHandler.
+	err error
+
+	// explicit lists the settings (SettingHandler, ...) that options set,
+	// so that MergeConfig credits a layer even if the value did not change.
+	explicit []string
+
+	// owned lists the outputs that options opened themselves, which are
+	// closed with the last logger that writes to them (see Closer).
+	owned []io.Writer
+
+	// handlers holds the handler chains built from the config and its copies
+	// (see BuildHandler). It is nil for config literals.
+	handlers *handlerCache
+
+	// chain identifies the settings of the config in handlers: copies keep
+	// it, and options that change a setting assign a new one (see touch).
+	chain uint64
 }
 
 // ConfigOption is a functional option for modifying a SlogConfig.
@@ -36,6 +128,7 @@ func NewSlogConfig(opts ...ConfigOption) SlogConfig {
 		HandlerType: "json",
 		Output:      os.Stderr,
 		Level:       slog.LevelInfo,
+		handlers:    new(handlerCache),
 	}
 	for _, opt := range opts {
 		opt(&cfg)
@@ -50,6 +143,7 @@ func SlogConfigDefault() SlogConfig {
 		HandlerType: "text",
 		Output:      os.Stderr,
 		Level:       slog.LevelInfo,
+		handlers:    new(handlerCache),
 	}
 }
 
@@ -57,13 +151,16 @@ func SlogConfigDefault() SlogConfig {
 func WithHandlerType(handlerType string) ConfigOption {
 	return func(cfg *SlogConfig) {
 		cfg.HandlerType = handlerType
+		cfg.markSet(SettingHandler)
 	}
 }
 
 // WithOutput returns a ConfigOption that sets the output destination.
+// The writer stays owned by the caller: Closer syncs it but never closes it.
 func WithOutput(w io.Writer) ConfigOption {
 	return func(cfg *SlogConfig) {
 		cfg.Output = w
+		cfg.markSet(SettingOutput)
 	}
 }
 
@@ -71,6 +168,7 @@ func WithOutput(w io.Writer) ConfigOption {
 func WithLevel(level slog.Level) ConfigOption {
 	return func(cfg *SlogConfig) {
 		cfg.Level = level
+		cfg.markSet(SettingLevel)
 	}
 }
 
@@ -78,6 +176,46 @@ func WithLevel(level slog.Level) ConfigOption {
 func WithHandlerOptions(opts *slog.HandlerOptions) ConfigOption {
 	return func(cfg *SlogConfig) {
 		cfg.HandlerOptions = opts
+		cfg.touch()
+	}
+}
+
+// WithDestination returns a ConfigOption that sets the address of network
+// handler types such as "syslog" (see SlogConfig.Destination).
+func WithDestination(dest string) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.Destination = dest
+		cfg.markSet(SettingDestination)
+	}
+}
+
+// WithResource returns a ConfigOption that adds attrs to the resource
+// attributes (see SlogConfig.Resource), replacing the values of keys that are
+// already set.
+func WithResource(attrs map[string]string) ConfigOption {
+	return func(cfg *SlogConfig) {
+		resource := maps.Clone(cfg.Resource)
+		if resource == nil {
+			resource = make(map[string]string, len(attrs))
+		}
+		maps.Copy(resource, attrs)
+		cfg.Resource = resource
+		cfg.markSet(SettingResource)
+	}
+}
+
+// WithAddSource returns a ConfigOption that controls whether the source
+// position of the log call is recorded. Existing HandlerOptions are copied,
+// not modified.
+func WithAddSource(add bool) ConfigOption {
+	return func(cfg *SlogConfig) {
+		opts := slog.HandlerOptions{}
+		if cfg.HandlerOptions != nil {
+			opts = *cfg.HandlerOptions
+		}
+		opts.AddSource = add
+		cfg.HandlerOptions = &opts
+		cfg.markSet(SettingAddSource)
 	}
 }
 
@@ -89,45 +227,227 @@ func WithCustomHandler(handler slog.Handler) ConfigOption {
 	}
 }
 
-// Clone returns a copy of the config.
+// Err returns the errors recorded by options while building the config,
+// for example invalid environment values read by WithEnv. It returns nil
+// if every option applied cleanly.
+func (c SlogConfig) Err() error {
+	return c.err
+}
+
+// addErr records an option error on the config.
+func (c *SlogConfig) addErr(err error) {
+	c.err = errors.Join(c.err, err)
+}
+
+// markSet records that an option set the given setting (see MergeConfig).
+func (c *SlogConfig) markSet(setting string) {
+	c.explicit = append(slices.Clip(c.explicit), setting)
+	c.touch()
+}
+
+// chainIDs numbers the settings of configs (see SlogConfig.chain).
+var chainIDs atomic.Uint64
+
+// touch records that an option changed the settings of the config, so that
+// its handlers no longer share the chain of the config it was copied from.
+func (c *SlogConfig) touch() {
+	c.chain = chainIDs.Add(1)
+}
+
+// own records that an option opened w, so that Closer closes it.
+func (c *SlogConfig) own(w io.Writer) {
+	c.owned = append(slices.Clip(c.owned), w)
+	if c.handlers == nil {
+		c.handlers = new(handlerCache)
+	}
+}
+
+// owns reports whether an option of the config opened w.
+func (c SlogConfig) owns(w io.Writer) bool {
+	return slices.ContainsFunc(c.owned, func(o io.Writer) bool { return sameResource(o, w) })
+}
+
+// Clone returns a copy of the config. The copy shares the handler chain of c
+// until an option changes its settings (see BuildHandler).
 func (c SlogConfig) Clone() SlogConfig {
-	// Simple shallow copy; HandlerOptions and CustomHandler are shared,
-	// but they are typically immutable after creation.
+	// HandlerOptions and CustomHandler are shared, but they are typically
+	// immutable after creation. Rules, Sinks, Resource, LogfmtKeyOrder and
+	// the HTTP, Loki, Elasticsearch, GELF, Async, Sampling, RateLimit and
+	// Dedup options are copied, so that changing the clone never affects the
+	// original. NameLevels is shared on purpose: its level variables are
+	// runtime controls.
+	if c.Rules != nil {
+		c.Rules = append([]AttrRule(nil), c.Rules...)
+	}
+	if c.Sinks != nil {
+		c.Sinks = append([]Sink(nil), c.Sinks...)
+	}
+	c.Resource = maps.Clone(c.Resource)
+	c.LogfmtKeyOrder = slices.Clone(c.LogfmtKeyOrder)
+	if c.HTTP != nil {
+		http := *c.HTTP
+		c.HTTP = &http
+	}
+	if c.Loki != nil {
+		loki := *c.Loki
+		c.Loki = &loki
+	}
+	if c.Elasticsearch != nil {
+		es := *c.Elasticsearch
+		c.Elasticsearch = &es
+	}
+	if c.GELF != nil {
+		gelf := *c.GELF
+		c.GELF = &gelf
+	}
+	if c.Async != nil {
+		async := *c.Async
+		c.Async = &async
+	}
+	if c.Sampling != nil {
+		sampling := *c.Sampling
+		c.Sampling = &sampling
+	}
+	if c.RateLimit != nil {
+		rateLimit := *c.RateLimit
+		c.RateLimit = &rateLimit
+	}
+	if c.Dedup != nil {
+		dedup := *c.Dedup
+		c.Dedup = &dedup
+	}
 	return c
 }
 
-// newHandler creates a slog.Handler based on the configuration.
-// It is unexported because it is only used internally.
-func (c SlogConfig) newHandler() slog.Handler {
-	if c.CustomHandler != nil {
-		return c.CustomHandler
-	}
-	handlerType := c.HandlerType
-	if handlerType == "" {
-		handlerType = "json"
+// resolve returns a copy of the config with all defaults filled in:
+// HandlerType, Output, Level and HandlerOptions (including its Level) are set.
+func (c SlogConfig) resolve() SlogConfig {
+	if c.HandlerType == "" {
+		c.HandlerType = "json"
 	}
-	output := c.Output
-	if output == nil {
-		output = os.Stderr
+	if c.Output == nil {
+		c.Output = os.Stderr
 	}
-	level := c.Level
-	if level == nil {
-		level = slog.LevelInfo
+	if c.Level == nil {
+		c.Level = slog.LevelInfo
 	}
 	opts := c.HandlerOptions
 	if opts == nil {
-		opts = &slog.HandlerOptions{Level: level}
+		opts = &slog.HandlerOptions{Level: c.Level}
 	} else if opts.Level == nil {
 		optsCopy := *opts
-		optsCopy.Level = level
+		optsCopy.Level = c.Level
+		opts = &optsCopy
+	}
+	if len(c.Rules) > 0 {
+		optsCopy := *opts
+		optsCopy.ReplaceAttr = replaceAttrWithRules(c.Rules, opts.ReplaceAttr)
 		opts = &optsCopy
 	}
-	switch handlerType {
-	case "json":
-		return slog.NewJSONHandler(output, opts)
-	case "text":
-		return slog.NewTextHandler(output, opts)
-	default:
-		return slog.NewJSONHandler(output, opts)
+	if lv := c.NameLevels.match(c.Name); lv != nil {
+		optsCopy := *opts
+		optsCopy.Level = lv
+		opts = &optsCopy
+		c.Level = lv
+	}
+	c.HandlerOptions = opts
+	return c
+}
+
+// BuildHandler creates a slog.Handler based on the configuration.
+// Unlike NewLogger, it reports an error if an option recorded one (see Err),
+// if HandlerType is not registered or if the registered factory fails.
+//
+// Handlers built from a config and the copies made with Named or Clone share
+// one handler chain: outputs, connections and background goroutines are set
+// up once, and each handler adds its logger name with WithAttrs. A copy gets
+// a chain of its own once an option changes one of its settings, as the
+// layers of MergeConfig do; assigning to its fields does not, so set the
+// fields of copies that must differ with options. Config literals, which are not made by
+// NewSlogConfig, SlogConfigDefault or LoadSlogConfig, build a new chain every
+// time. Loggers release their share with CloserOf; SlogConfig.Shutdown closes
+// them all.
+//
+// Unless the level is a custom slog.Leveler, the returned handler carries a
+// *slog.LevelVar that adjusts its level at runtime (see LevelVarOf). The
+// variable is part of the shared chain, so changing it adjusts every handler
+// that shares the chain; use Clone and WithLevel for a logger whose level
+// must change on its own.
+func (c SlogConfig) BuildHandler() (slog.Handler, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.CustomHandler != nil {
+		return c.withNameAttr(c.CustomHandler), nil
+	}
+	shared, err := c.handlers.acquire(c.handlerKey(), c.buildChain)
+	if err != nil {
+		return nil, err
+	}
+	return c.withNameAttr(&loggerHandler{next: shared.handler, share: &loggerShare{shared: shared}}), nil
+}
+
+// buildChain builds the handler chain of the config, without the logger name.
+func (c SlogConfig) buildChain() (slog.Handler, error) {
+	rc := c.resolve()
+	var (
+		h   slog.Handler
+		err error
+	)
+	if len(rc.Sinks) > 0 {
+		h, err = rc.buildSinks()
+	} else {
+		h, err = rc.buildResolved()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if c.Async != nil {
+		h = NewAsyncHandler(h, *c.Async)
+	}
+	// Repeats are collapsed first and sampling comes next, so that the rate
+	// limit only counts the records that survive both.
+	if c.RateLimit != nil {
+		h = NewRateLimitHandler(h, *c.RateLimit)
+	}
+	if c.Sampling != nil {
+		h = NewSamplingHandler(h, *c.Sampling)
+	}
+	if c.Dedup != nil {
+		h = NewDedupHandler(h, *c.Dedup)
+	}
+	return h, nil
+}
+
+// buildResolved builds the handler of a resolved config from its registered
+// factory and wraps it with its runtime level control.
+func (c SlogConfig) buildResolved() (slog.Handler, error) {
+	rc, lv := c.withLevelVar()
+	factory, err := lookupHandlerType(rc.HandlerType)
+	if err != nil {
+		return nil, err
+	}
+	h, err := factory(rc)
+	if err != nil {
+		return nil, fmt.Errorf("handler type %q: %w", rc.HandlerType, err)
+	}
+	return wrapLevelHandler(&outputHandler{next: h, output: rc.Output, owned: rc.owns(rc.Output)}, lv), nil
+}
+
+// newHandler creates a slog.Handler based on the configuration.
+// It is unexported because it is only used internally.
+// If the handler cannot be built, it falls back to a JSON handler so that
+// NewLogger always returns a usable logger; use BuildHandler to detect errors.
+func (c SlogConfig) newHandler() slog.Handler {
+	// Recorded option errors must not stop NewLogger from building a logger
+	// out of the options that did apply.
+	c.err = nil
+	h, err := c.BuildHandler()
+	if err != nil {
+		rc, lv := c.resolve().withLevelVar()
+		h = &outputHandler{next: slog.NewJSONHandler(rc.Output, rc.HandlerOptions), output: rc.Output, owned: rc.owns(rc.Output)}
+		shared := &sharedHandler{handler: wrapLevelHandler(h, lv), refs: 1}
+		return c.withNameAttr(&loggerHandler{next: shared.handler, share: &loggerShare{shared: shared}})
 	}
+	return h
 }
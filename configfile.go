@@ -0,0 +1,537 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// LoadSlogConfig reads a logger configuration document from r and returns
+// the resulting SlogConfig, built on top of NewSlogConfig defaults.
+//
+// The document is either a JSON object or a YAML-style document using a
+// small subset of YAML (top-level "key: value" pairs and a list of mappings
+// under "rules"). Both forms share the same schema:
+//
+//	handler:    registered handler type, e.g. "json", "text" (see HandlerTypes)
+//	output:     "stderr", "stdout" or a file path; files are opened for appending
+//	destination: server address of network handler types, e.g. "udp://localhost:514"
+//	resource:   resource attributes of the "otlp" handler type, as a mapping
+//	            (JSON only) or a string such as "service.name=shop,env=prod"
+//	level:      "debug", "info", "warn", "error", optionally with an offset
+//	            such as "info+2", or an integer level
+//	add_source: true or false
+//	rules:      list of attribute rules, each with the keys
+//	            key:    attribute key or dotted group path
+//	            action: "redact" or "rename"
+//	            to:     replacement value (redact) or new key (rename)
+//	sinks:      list of outputs that replace handler/output/level, each with
+//	            handler: registered handler type
+//	            output:  "stderr", "stdout" or a file path
+//	            level:   minimum level of the sink
+//	name_levels: per-name level overrides for named loggers, e.g.
+//	            "db=debug,http=warn,*=info" (see ParseNameLevels)
+//
+// Example:
+//
+//	handler: text
+//	output: /var/log/app.log
+//	level: debug
+//	add_source: true
+//	rules:
+//	  - key: password
+//	    action: redact
+//	  - key: msg
+//	    action: rename
+//	    to: message
+//
+// Unknown keys and invalid values are reported as *ConfigKeyError.
+func LoadSlogConfig(r io.Reader) (SlogConfig, error) {
+	opts, err := readConfigOptions(r)
+	if err != nil {
+		return SlogConfig{}, err
+	}
+	return NewSlogConfig(opts...), nil
+}
+
+// LoadSlogConfigFile reads the configuration file at path.
+// See LoadSlogConfig for the schema.
+func LoadSlogConfigFile(path string) (SlogConfig, error) {
+	opts, err := readConfigFileOptions(path)
+	if err != nil {
+		return SlogConfig{}, err
+	}
+	return NewSlogConfig(opts...), nil
+}
+
+// ConfigKeyError reports an invalid value for a configuration key.
+// Key is the offending key, using "rules[1].action" style paths for nested keys.
+type ConfigKeyError struct {
+	Key string
+	Err error
+}
+
+func (e *ConfigKeyError) Error() string {
+	return fmt.Sprintf("config key %q: %v", e.Key, e.Err)
+}
+
+func (e *ConfigKeyError) Unwrap() error {
+	return e.Err
+}
+
+// readConfigOptions parses a configuration document into the options that
+// reproduce it. Only keys present in the document produce options.
+func readConfigOptions(r io.Reader) ([]ConfigOption, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(trimmed))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("invalid JSON config: %w", err)
+		}
+	} else {
+		doc, err = parseYAMLSubset(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid YAML config: %w", err)
+		}
+	}
+	return configDocOptions(doc)
+}
+
+// readConfigFileOptions parses the configuration file at path into options.
+func readConfigFileOptions(path string) ([]ConfigOption, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	opts, err := readConfigOptions(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return opts, nil
+}
+
+// configResource converts the value of the "resource" key: a mapping of
+// strings or a spec accepted by parseResource.
+func configResource(raw any) (map[string]string, error) {
+	switch v := raw.(type) {
+	case string:
+		return parseResource(v)
+	case map[string]any:
+		resource := make(map[string]string, len(v))
+		for _, k := range slices.Sorted(maps.Keys(v)) {
+			s, ok := v[k].(string)
+			if !ok {
+				return nil, fmt.Errorf("attribute %q: expected string, got %T", k, v[k])
+			}
+			resource[k] = s
+		}
+		return resource, nil
+	default:
+		return nil, fmt.Errorf("expected mapping or string, got %T", raw)
+	}
+}
+
+// configDocOptions converts a decoded configuration document into options.
+// Keys are validated in sorted order, so that the first invalid key is
+// reported consistently. Output files are opened only after every key has
+// been validated, and closed again if one of them cannot be opened.
+func configDocOptions(doc map[string]any) ([]ConfigOption, error) {
+	var (
+		opts   []ConfigOption
+		output string
+		sinks  []sinkSpec
+	)
+	for _, key := range slices.Sorted(maps.Keys(doc)) {
+		raw := doc[key]
+		switch key {
+		case "handler":
+			s, err := configString(key, raw)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := lookupHandlerType(s); err != nil {
+				return nil, &ConfigKeyError{Key: key, Err: err}
+			}
+			opts = append(opts, WithHandlerType(s))
+		case "output":
+			s, err := configString(key, raw)
+			if err != nil {
+				return nil, err
+			}
+			if s == "" {
+				return nil, &ConfigKeyError{Key: key, Err: fmt.Errorf("empty output")}
+			}
+			output = s
+		case SettingDestination:
+			s, err := configString(key, raw)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithDestination(s))
+		case SettingResource:
+			resource, err := configResource(raw)
+			if err != nil {
+				return nil, &ConfigKeyError{Key: key, Err: err}
+			}
+			opts = append(opts, WithResource(resource))
+		case "level":
+			var text string
+			switch v := raw.(type) {
+			case string:
+				text = v
+			case json.Number:
+				text = v.String()
+			default:
+				return nil, &ConfigKeyError{Key: key, Err: fmt.Errorf("expected string or integer, got %T", raw)}
+			}
+			level, err := parseLevel(text)
+			if err != nil {
+				return nil, &ConfigKeyError{Key: key, Err: err}
+			}
+			opts = append(opts, WithLevel(level))
+		case "add_source":
+			var add bool
+			switch v := raw.(type) {
+			case bool:
+				add = v
+			case string:
+				b, err := strconv.ParseBool(v)
+				if err != nil {
+					return nil, &ConfigKeyError{Key: key, Err: fmt.Errorf("invalid boolean %q", v)}
+				}
+				add = b
+			default:
+				return nil, &ConfigKeyError{Key: key, Err: fmt.Errorf("expected boolean, got %T", raw)}
+			}
+			opts = append(opts, WithAddSource(add))
+		case SettingNameLevels:
+			spec, err := configString(key, raw)
+			if err != nil {
+				return nil, err
+			}
+			levels, err := ParseNameLevels(spec)
+			if err != nil {
+				return nil, &ConfigKeyError{Key: key, Err: err}
+			}
+			opts = append(opts, func(cfg *SlogConfig) {
+				cfg.NameLevels = levels
+				cfg.markSet(SettingNameLevels)
+			})
+		case SettingSinks:
+			var err error
+			sinks, err = configSinks(raw)
+			if err != nil {
+				return nil, err
+			}
+		case "rules":
+			rules, err := configRules(raw)
+			if err != nil {
+				return nil, err
+			}
+			opts = append(opts, WithAttrRules(rules...))
+		default:
+			return nil, &ConfigKeyError{Key: key, Err: fmt.Errorf("unknown key")}
+		}
+	}
+	var opened []io.Writer
+	if output != "" {
+		w, err := openOutput(output)
+		if err != nil {
+			return nil, &ConfigKeyError{Key: "output", Err: err}
+		}
+		opened = append(opened, w)
+		opts = append(opts, WithOutput(w), ownOutput(w))
+	}
+	for i, spec := range sinks {
+		w, err := openOutput(spec.output)
+		if err != nil {
+			closeOutputs(opened)
+			return nil, &ConfigKeyError{Key: fmt.Sprintf("sinks[%d].output", i), Err: err}
+		}
+		opened = append(opened, w)
+		opts = append(opts, WithSink(spec.handlerType, w, spec.level), ownOutput(w))
+	}
+	return opts, nil
+}
+
+// sinkSpec is a sink read from a configuration document, before its output is opened.
+type sinkSpec struct {
+	handlerType string
+	output      string
+	level       slog.Leveler
+}
+
+// configSinks decodes the "sinks" list.
+func configSinks(raw any) ([]sinkSpec, error) {
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, &ConfigKeyError{Key: "sinks", Err: fmt.Errorf("expected list, got %T", raw)}
+	}
+	specs := make([]sinkSpec, 0, len(list))
+	for i, item := range list {
+		prefix := fmt.Sprintf("sinks[%d]", i)
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, &ConfigKeyError{Key: prefix, Err: fmt.Errorf("expected mapping, got %T", item)}
+		}
+		spec := sinkSpec{output: "stderr"}
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			v := m[k]
+			key := prefix + "." + k
+			if n, ok := v.(json.Number); ok {
+				v = n.String()
+			}
+			s, err := configString(key, v)
+			if err != nil {
+				return nil, err
+			}
+			switch k {
+			case "handler":
+				if _, err := lookupHandlerType(s); err != nil {
+					return nil, &ConfigKeyError{Key: key, Err: err}
+				}
+				spec.handlerType = s
+			case "output":
+				if s == "" {
+					return nil, &ConfigKeyError{Key: key, Err: fmt.Errorf("empty output")}
+				}
+				spec.output = s
+			case "level":
+				level, err := parseLevel(s)
+				if err != nil {
+					return nil, &ConfigKeyError{Key: key, Err: err}
+				}
+				spec.level = level
+			default:
+				return nil, &ConfigKeyError{Key: key, Err: fmt.Errorf("unknown key")}
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// configRules decodes the "rules" list.
+func configRules(raw any) ([]AttrRule, error) {
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, &ConfigKeyError{Key: "rules", Err: fmt.Errorf("expected list, got %T", raw)}
+	}
+	rules := make([]AttrRule, 0, len(list))
+	for i, item := range list {
+		prefix := fmt.Sprintf("rules[%d]", i)
+		m, ok := item.(map[string]any)
+		if !ok {
+			return nil, &ConfigKeyError{Key: prefix, Err: fmt.Errorf("expected mapping, got %T", item)}
+		}
+		var rule AttrRule
+		for _, k := range slices.Sorted(maps.Keys(m)) {
+			v := m[k]
+			s, err := configString(prefix+"."+k, v)
+			if err != nil {
+				return nil, err
+			}
+			switch k {
+			case "key":
+				rule.Key = s
+			case "action":
+				rule.Action = s
+			case "to":
+				rule.To = s
+			default:
+				return nil, &ConfigKeyError{Key: prefix + "." + k, Err: fmt.Errorf("unknown key")}
+			}
+		}
+		if err := rule.Validate(); err != nil {
+			key := prefix + ".action"
+			if rule.Key == "" {
+				key = prefix + ".key"
+			} else if rule.Action == RuleRename && rule.To == "" {
+				key = prefix + ".to"
+			}
+			return nil, &ConfigKeyError{Key: key, Err: err}
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// configString asserts that a configuration value is a string.
+func configString(key string, raw any) (string, error) {
+	s, ok := raw.(string)
+	if !ok {
+		return "", &ConfigKeyError{Key: key, Err: fmt.Errorf("expected string, got %T", raw)}
+	}
+	return s, nil
+}
+
+// parseLevel parses a level name ("debug", "INFO+2") or an integer level.
+func parseLevel(s string) (slog.Level, error) {
+	s = strings.TrimSpace(s)
+	if n, err := strconv.Atoi(s); err == nil {
+		return slog.Level(n), nil
+	}
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid level %q", s)
+	}
+	return level, nil
+}
+
+// openOutput resolves an output target name: "stderr", "stdout" or a file path.
+func openOutput(target string) (io.Writer, error) {
+	switch target {
+	case "stderr":
+		return os.Stderr, nil
+	case "stdout":
+		return os.Stdout, nil
+	}
+	return os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+}
+
+// ownOutput returns a ConfigOption that records that the config opened w.
+func ownOutput(w io.Writer) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.own(w)
+	}
+}
+
+// closeOutputs closes the files among outputs opened by openOutput.
+func closeOutputs(outputs []io.Writer) {
+	for _, w := range outputs {
+		if f, ok := w.(*os.File); ok && f != os.Stdout && f != os.Stderr {
+			f.Close()
+		}
+	}
+}
+
+// parseYAMLSubset parses the YAML-style configuration form: top-level
+// "key: value" pairs, where a key with no value starts a block list whose
+// items are either scalars ("- value") or mappings ("- key: value" followed
+// by more indented "key: value" lines). Comments start with '#'.
+func parseYAMLSubset(data []byte) (map[string]any, error) {
+	doc := make(map[string]any)
+	var (
+		listKey string         // top-level key of the open block list
+		item    map[string]any // mapping of the current list item
+	)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; sc.Scan(); lineNo++ {
+		line := stripYAMLComment(sc.Text())
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed for indentation", lineNo)
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		indent := len(line) - len(trimmed)
+		if indent == 0 {
+			key, value, err := splitYAMLPair(trimmed, lineNo)
+			if err != nil {
+				return nil, err
+			}
+			if _, dup := doc[key]; dup {
+				return nil, fmt.Errorf("line %d: duplicate key %q", lineNo, key)
+			}
+			listKey, item = "", nil
+			if value == "" {
+				listKey = key
+				doc[key] = []any{}
+				continue
+			}
+			doc[key] = yamlScalar(value)
+			continue
+		}
+		if listKey == "" {
+			return nil, fmt.Errorf("line %d: unexpected indentation", lineNo)
+		}
+		list := doc[listKey].([]any)
+		if rest, ok := strings.CutPrefix(trimmed, "-"); ok {
+			rest = strings.TrimSpace(rest)
+			if !strings.Contains(rest, ":") {
+				item = nil
+				doc[listKey] = append(list, yamlScalar(rest))
+				continue
+			}
+			item = make(map[string]any)
+			doc[listKey] = append(list, item)
+			trimmed = rest
+		}
+		if item == nil {
+			return nil, fmt.Errorf("line %d: expected list item", lineNo)
+		}
+		key, value, err := splitYAMLPair(trimmed, lineNo)
+		if err != nil {
+			return nil, err
+		}
+		item[key] = yamlScalar(value)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// splitYAMLPair splits a "key: value" line.
+func splitYAMLPair(s string, lineNo int) (string, string, error) {
+	key, value, ok := strings.Cut(s, ":")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" {
+		return "", "", fmt.Errorf("line %d: expected \"key: value\"", lineNo)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+// yamlScalar converts a scalar to a string or bool, removing quotes.
+func yamlScalar(s string) any {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		if s[0] == '"' {
+			if unq, err := strconv.Unquote(s); err == nil {
+				return unq
+			}
+		}
+		return s[1 : len(s)-1]
+	}
+	switch s {
+	case "true":
+		return true
+	case "false":
+		return false
+	}
+	return s
+}
+
+// stripYAMLComment removes a trailing comment that is not inside quotes.
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
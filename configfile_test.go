@@ -0,0 +1,177 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadSlogConfigJSON(t *testing.T) {
+	doc := `{
+		"handler": "text",
+		"output": "stdout",
+		"level": "debug",
+		"add_source": true,
+		"rules": [
+			{"key": "password", "action": "redact"},
+			{"key": "msg", "action": "rename", "to": "message"}
+		]
+	}`
+	cfg, err := LoadSlogConfig(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("LoadSlogConfig: %v", err)
+	}
+	if cfg.HandlerType != "text" {
+		t.Errorf("HandlerType = %q, want text", cfg.HandlerType)
+	}
+	if cfg.Output != os.Stdout {
+		t.Errorf("Output = %v, want stdout", cfg.Output)
+	}
+	if cfg.Level != slog.LevelDebug {
+		t.Errorf("Level = %v, want DEBUG", cfg.Level)
+	}
+	if cfg.HandlerOptions == nil || !cfg.HandlerOptions.AddSource {
+		t.Errorf("HandlerOptions = %+v, want AddSource", cfg.HandlerOptions)
+	}
+	if len(cfg.Rules) != 2 {
+		t.Fatalf("Rules = %v, want 2 rules", cfg.Rules)
+	}
+}
+
+func TestLoadSlogConfigYAML(t *testing.T) {
+	doc := `
+# logger settings
+handler: json
+level: "warn+1"   # inline comment
+add_source: false
+rules:
+  - key: password
+    action: redact
+    to: "***"
+  - key: User.Email
+    action: rename
+    to: email
+`
+	cfg, err := LoadSlogConfig(strings.NewReader(doc))
+	if err != nil {
+		t.Fatalf("LoadSlogConfig: %v", err)
+	}
+	if cfg.HandlerType != "json" {
+		t.Errorf("HandlerType = %q, want json", cfg.HandlerType)
+	}
+	if cfg.Level != slog.LevelWarn+1 {
+		t.Errorf("Level = %v, want WARN+1", cfg.Level)
+	}
+	want := []AttrRule{
+		{Key: "password", Action: RuleRedact, To: "***"},
+		{Key: "User.Email", Action: RuleRename, To: "email"},
+	}
+	if len(cfg.Rules) != len(want) {
+		t.Fatalf("Rules = %v, want %v", cfg.Rules, want)
+	}
+	for i := range want {
+		if cfg.Rules[i] != want[i] {
+			t.Errorf("Rules[%d] = %+v, want %+v", i, cfg.Rules[i], want[i])
+		}
+	}
+}
+
+func TestLoadSlogConfigRulesApplied(t *testing.T) {
+	var buf bytes.Buffer
+	cfg, err := LoadSlogConfig(strings.NewReader(`{"rules": [
+		{"key": "password", "action": "redact"},
+		{"key": "msg", "action": "rename", "to": "message"}
+	]}`))
+	if err != nil {
+		t.Fatalf("LoadSlogConfig: %v", err)
+	}
+	cfg.Output = &buf
+	cfg.NewLogger().WithGroup("user").Info("login", "password", "hunter2")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("failed to parse JSON: %v", err)
+	}
+	if record["message"] != "login" {
+		t.Errorf("message = %v, want login", record["message"])
+	}
+	user, _ := record["user"].(map[string]any)
+	if user["password"] != DefaultRedaction {
+		t.Errorf("user.password = %v, want %q", user["password"], DefaultRedaction)
+	}
+}
+
+func TestLoadSlogConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	cfgPath := filepath.Join(dir, "log.yaml")
+	if err := os.WriteFile(cfgPath, []byte("output: "+logPath+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadSlogConfigFile(cfgPath)
+	if err != nil {
+		t.Fatalf("LoadSlogConfigFile: %v", err)
+	}
+	f, ok := cfg.Output.(*os.File)
+	if !ok {
+		t.Fatalf("Output = %T, want *os.File", cfg.Output)
+	}
+	defer f.Close()
+	cfg.NewLogger().Info("to file")
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "to file") {
+		t.Errorf("log file content = %q", data)
+	}
+}
+
+func TestLoadSlogConfigErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		key  string
+	}{
+		{"unknown key", `{"colour": "red"}`, "colour"},
+		{"unknown handler", `{"handler": "jsn"}`, "handler"},
+		{"bad level", `level: loud`, "level"},
+		{"bad add_source", `add_source: yes`, "add_source"},
+		{"rules not list", `{"rules": "none"}`, "rules"},
+		{"bad action", `{"rules": [{"key": "a", "action": "drop"}]}`, "rules[0].action"},
+		{"rename without to", "rules:\n  - key: a\n    action: redact\n  - key: b\n    action: rename\n", "rules[1].to"},
+		{"unknown rule key", `{"rules": [{"key": "a", "action": "redact", "with": "x"}]}`, "rules[0].with"},
+		{"several bad keys", `{"level": "loud", "colour": "red", "add_source": "maybe"}`, "add_source"},
+		{"several bad rule keys", `{"rules": [{"with": "x", "action": "drop", "key": "a"}]}`, "rules[0].with"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadSlogConfig(strings.NewReader(tt.doc))
+			var keyErr *ConfigKeyError
+			if !errors.As(err, &keyErr) {
+				t.Fatalf("error = %v, want *ConfigKeyError", err)
+			}
+			if keyErr.Key != tt.key {
+				t.Errorf("error key = %q, want %q (%v)", keyErr.Key, tt.key, err)
+			}
+		})
+	}
+}
+
+func TestLoadSlogConfigSyntaxErrors(t *testing.T) {
+	for _, doc := range []string{
+		`{"handler": `,
+		"handler json",
+		"  level: debug",
+		"rules:\n    action: redact",
+	} {
+		if _, err := LoadSlogConfig(strings.NewReader(doc)); err == nil {
+			t.Errorf("LoadSlogConfig(%q) succeeded, want error", doc)
+		}
+	}
+}
@@ -0,0 +1,313 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// DefaultConsoleTimeFormat is the timestamp layout used by ConsoleHandler
+// when ConsoleOptions.TimeFormat is empty. It has a fixed width so that
+// messages line up.
+const DefaultConsoleTimeFormat = "15:04:05.000"
+
+// ColorMode controls whether ConsoleHandler writes ANSI colors.
+type ColorMode int
+
+const (
+	ColorAuto   ColorMode = iota // colors if the output is a terminal and NO_COLOR is not set
+	ColorAlways                  // always write colors
+	ColorNever                   // never write colors
+)
+
+// ANSI escape sequences used by ConsoleHandler.
+const (
+	ansiReset   = "\x1b[0m"
+	ansiDim     = "\x1b[2m"
+	ansiRed     = "\x1b[31m"
+	ansiGreen   = "\x1b[32m"
+	ansiYellow  = "\x1b[33m"
+	ansiMagenta = "\x1b[35m"
+)
+
+// ConsoleOptions configure a ConsoleHandler.
+type ConsoleOptions struct {
+	slog.HandlerOptions
+
+	// TimeFormat is the layout of the record time. If empty,
+	// DefaultConsoleTimeFormat is used.
+	TimeFormat string
+
+	// Color selects whether levels, keys and timestamps are colored.
+	Color ColorMode
+}
+
+// ConsoleHandler writes records in a human-friendly form, one record per
+// line, meant for terminals rather than log processors:
+//
+//	12:30:45.123 INFO  request served  method=GET status=200
+//	  Session
+//	    User
+//	      ID=7
+//	      Name=bob
+//
+// Attributes with single-line values follow the message. Groups, including the
+// state group of a Stateful logger, are written as indented blocks below it,
+// with dotted keys such as "User.ID" nested into sub-blocks; multi-line values
+// such as stack traces are written as indented blocks as well.
+//
+// It is registered as the "console" handler type.
+type ConsoleHandler struct {
+	mu         *sync.Mutex // serializes writes to w across derived handlers
+	w          io.Writer
+	level      slog.Leveler
+	addSource  bool
+	timeFormat string
+	color      bool
+	scope      attrScope
+}
+
+// NewConsoleHandler returns a ConsoleHandler that writes to w.
+// A nil opts uses the defaults.
+func NewConsoleHandler(w io.Writer, opts *ConsoleOptions) *ConsoleHandler {
+	if opts == nil {
+		opts = &ConsoleOptions{}
+	}
+	h := &ConsoleHandler{
+		mu:         new(sync.Mutex),
+		w:          w,
+		level:      opts.Level,
+		addSource:  opts.AddSource,
+		timeFormat: opts.TimeFormat,
+		scope:      attrScope{replace: opts.ReplaceAttr},
+	}
+	if h.level == nil {
+		h.level = slog.LevelInfo
+	}
+	if h.timeFormat == "" {
+		h.timeFormat = DefaultConsoleTimeFormat
+	}
+	switch opts.Color {
+	case ColorAlways:
+		h.color = true
+	case ColorAuto:
+		h.color = colorSupported(w)
+	}
+	return h
+}
+
+// colorSupported reports whether w is a terminal and NO_COLOR is unset or empty.
+func colorSupported(w io.Writer) bool {
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok || f == nil {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func (h *ConsoleHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *ConsoleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope.withAttrs(attrs)
+	return &h2
+}
+
+func (h *ConsoleHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.scope = h.scope.withGroup(name)
+	return &h2
+}
+
+func (h *ConsoleHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	sep := ""
+	if !r.Time.IsZero() {
+		if a, ok := h.scope.builtin(slog.Time(slog.TimeKey, r.Time)); ok {
+			text := h.formatValue(a.Value)
+			if a.Value.Kind() == slog.KindTime {
+				text = a.Value.Time().Format(h.timeFormat)
+			}
+			h.paint(&buf, ansiDim, text)
+			sep = " "
+		}
+	}
+	if a, ok := h.scope.builtin(slog.Any(slog.LevelKey, r.Level)); ok {
+		buf.WriteString(sep)
+		text := h.formatValue(a.Value)
+		if len(text) < 5 {
+			text += strings.Repeat(" ", 5-len(text))
+		}
+		color := ""
+		if level, ok := a.Value.Any().(slog.Level); ok {
+			color = levelColor(level)
+		}
+		h.paint(&buf, color, text)
+		sep = " "
+	}
+	if a, ok := h.scope.builtin(slog.String(slog.MessageKey, r.Message)); ok {
+		buf.WriteString(sep)
+		buf.WriteString(h.formatValue(a.Value))
+		sep = "  "
+	}
+
+	root := &attrNode{}
+	if h.addSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		src := slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", frame.File, frame.Line))
+		if a, ok := h.scope.builtin(src); ok {
+			root.insert(nil, a.Key, a.Value)
+		}
+	}
+	h.scope.each(r, func(groups []string, a slog.Attr) {
+		consoleInsert(root, groups, a)
+	})
+
+	var blocks []*attrNode
+	for _, n := range root.children {
+		if n.children != nil {
+			blocks = append(blocks, n)
+			continue
+		}
+		text := h.formatValue(n.value)
+		if strings.Contains(text, "\n") {
+			blocks = append(blocks, n)
+			continue
+		}
+		buf.WriteString(sep)
+		h.writePair(&buf, n.key, text)
+		sep = " "
+	}
+	buf.WriteByte('\n')
+	for _, n := range blocks {
+		h.writeBlock(&buf, n, 1)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+// writeBlock writes a group or a multi-line value as an indented block.
+func (h *ConsoleHandler) writeBlock(buf *bytes.Buffer, n *attrNode, depth int) {
+	indent := strings.Repeat("  ", depth)
+	buf.WriteString(indent)
+	if n.children == nil {
+		h.paint(buf, ansiDim, n.key+":")
+		buf.WriteByte('\n')
+		text := strings.TrimRight(h.formatValue(n.value), "\n")
+		for _, line := range strings.Split(text, "\n") {
+			buf.WriteString(indent + "  " + line + "\n")
+		}
+		return
+	}
+	h.paint(buf, ansiDim, n.key)
+	buf.WriteByte('\n')
+	for _, child := range n.children {
+		if child.children != nil {
+			h.writeBlock(buf, child, depth+1)
+			continue
+		}
+		text := h.formatValue(child.value)
+		if strings.Contains(text, "\n") {
+			h.writeBlock(buf, child, depth+1)
+			continue
+		}
+		buf.WriteString(indent + "  ")
+		h.writePair(buf, child.key, text)
+		buf.WriteByte('\n')
+	}
+}
+
+// writePair writes key=value with a dimmed key, quoting the value if needed.
+func (h *ConsoleHandler) writePair(buf *bytes.Buffer, key, text string) {
+	h.paint(buf, ansiDim, key+"=")
+	if needsConsoleQuote(text) {
+		text = strconv.Quote(text)
+	}
+	buf.WriteString(text)
+}
+
+// paint writes s, wrapped in the ANSI color if colors are enabled.
+func (h *ConsoleHandler) paint(buf *bytes.Buffer, color, s string) {
+	if !h.color || color == "" {
+		buf.WriteString(s)
+		return
+	}
+	buf.WriteString(color)
+	buf.WriteString(s)
+	buf.WriteString(ansiReset)
+}
+
+// formatValue returns the text of an attribute value.
+func (h *ConsoleHandler) formatValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format("2006-01-02T15:04:05.000Z07:00")
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return v.String()
+}
+
+// levelColor returns the ANSI color of a level.
+func levelColor(level slog.Level) string {
+	switch {
+	case level >= slog.LevelError:
+		return ansiRed
+	case level >= slog.LevelWarn:
+		return ansiYellow
+	case level >= slog.LevelInfo:
+		return ansiGreen
+	}
+	return ansiMagenta
+}
+
+// needsConsoleQuote reports whether a single-line value must be quoted to be
+// read back unambiguously.
+func needsConsoleQuote(s string) bool {
+	if s == "" {
+		return true
+	}
+	for _, r := range s {
+		if r == '=' || r == '"' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// consoleInsert adds a to the block layout under the nested groups; inside a
+// group, dotted keys are split into nested groups as well.
+func consoleInsert(root *attrNode, groups []string, a slog.Attr) {
+	path := groups
+	key := a.Key
+	if len(groups) > 0 && strings.Contains(key, ".") {
+		parts := strings.Split(key, ".")
+		path = append(append([]string(nil), groups...), parts[:len(parts)-1]...)
+		key = parts[len(parts)-1]
+	}
+	root.insert(path, key, a.Value)
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func consoleRecord(level slog.Level, msg string, attrs ...slog.Attr) slog.Record {
+	r := slog.NewRecord(testTime, level, msg, 0)
+	r.AddAttrs(attrs...)
+	return r
+}
+
+func TestConsoleHandlerLine(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewConsoleHandler(&buf, &ConsoleOptions{Color: ColorNever})
+	h.Handle(context.Background(), consoleRecord(slog.LevelInfo, "request served",
+		slog.String("method", "GET"), slog.Int("status", 200), slog.String("path", "/a b")))
+	want := `12:30:45.123 INFO  request served  method=GET status=200 path="/a b"` + "\n"
+	if buf.String() != want {
+		t.Errorf("got  %q\nwant %q", buf.String(), want)
+	}
+}
+
+func TestConsoleHandlerGroups(t *testing.T) {
+	var buf bytes.Buffer
+	type Session struct {
+		Request string
+		User    struct {
+			ID   int
+			Name string
+		}
+	}
+	state := &Session{Request: "r-1"}
+	state.User.ID = 7
+	state.User.Name = "bob"
+	cfg := NewSlogConfig(WithCustomHandler(NewConsoleHandler(&buf, &ConsoleOptions{Color: ColorNever})))
+	NewStateful(cfg, state).Info("hello", "k", "v")
+
+	want := strings.Join([]string{
+		"INFO  hello  k=v",
+		"  Session",
+		"    Request=r-1",
+		"    User",
+		"      ID=7",
+		"      Name=bob",
+		"",
+	}, "\n")
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("got\n%s\nwant suffix\n%s", got, want)
+	}
+}
+
+func TestConsoleHandlerWithGroup(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewConsoleHandler(&buf, &ConsoleOptions{Color: ColorNever}))
+	logger.With("app", "api").WithGroup("req").With("id", 1).WithGroup("empty").Info("done")
+	want := "INFO  done  app=api\n  req\n    id=1\n"
+	if got := buf.String(); !strings.HasSuffix(got, want) {
+		t.Errorf("got %q, want suffix %q", got, want)
+	}
+}
+
+func TestConsoleHandlerMultiline(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewConsoleHandler(&buf, &ConsoleOptions{Color: ColorNever})
+	h.Handle(context.Background(), consoleRecord(slog.LevelError, "panic",
+		slog.Any("error", errors.New("first line\nsecond line")),
+		slog.String("stack", "goroutine 1 [running]:\nmain.main()\n"),
+		slog.Int("n", 1)))
+	want := strings.Join([]string{
+		"12:30:45.123 ERROR panic  n=1",
+		"  error:",
+		"    first line",
+		"    second line",
+		"  stack:",
+		"    goroutine 1 [running]:",
+		"    main.main()",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Errorf("got\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestConsoleHandlerColors(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewConsoleHandler(&buf, &ConsoleOptions{Color: ColorAlways})
+	h.Handle(context.Background(), consoleRecord(slog.LevelWarn, "careful", slog.String("k", "v")))
+	out := buf.String()
+	for _, want := range []string{ansiYellow + "WARN " + ansiReset, ansiDim + "k=" + ansiReset + "v", ansiDim + "12:30:45.123" + ansiReset} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q missing %q", out, want)
+		}
+	}
+}
+
+func TestConsoleHandlerColorAuto(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "out.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if NewConsoleHandler(f, nil).color {
+		t.Error("colors enabled for a regular file")
+	}
+	if NewConsoleHandler(&bytes.Buffer{}, nil).color {
+		t.Error("colors enabled for a buffer")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if colorSupported(os.Stderr) {
+		t.Error("colors enabled with NO_COLOR set")
+	}
+}
+
+func TestConsoleHandlerType(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithHandlerType("console"), WithOutput(&buf), WithLevel(slog.LevelDebug),
+		WithAttrRules(AttrRule{Key: "password", Action: RuleRedact}))
+	cfg.NewLogger().Debug("login", "user", "bob", "password", "hunter2")
+	out := buf.String()
+	if !strings.Contains(out, "DEBUG login  user=bob password=[REDACTED]") {
+		t.Errorf("output = %q", out)
+	}
+	if strings.Contains(out, "\x1b[") {
+		t.Errorf("colors written to a buffer: %q", out)
+	}
+}
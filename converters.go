@@ -8,6 +8,14 @@ type ContextField struct {
 	Extractor func(context.Context) any // if nil, ctx.Value(Key) is used
 }
 
+// value returns the value of the field in ctx, or nil if it has none.
+func (f ContextField) value(ctx context.Context) any {
+	if f.Extractor != nil {
+		return f.Extractor(ctx)
+	}
+	return ctx.Value(f.Key)
+}
+
 // SimpleContextField creates a ContextField that uses ctx.Value(key) as the extractor.
 // The field key is used as both the context key and the attribute key.
 func SimpleContextField(key string) ContextField {
@@ -0,0 +1,269 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultDedupTimeout is the timeout used when DedupOptions.Timeout is not positive.
+const DefaultDedupTimeout = time.Second
+
+// DedupOptions configure a DedupHandler.
+type DedupOptions struct {
+	// Timeout is the longest time a repeated record is held back: once it has
+	// passed since the first held repeat, the repeats are written even if
+	// more may follow. If not positive, DefaultDedupTimeout is used.
+	Timeout time.Duration
+}
+
+// DedupStats are the counters of a DedupHandler.
+type DedupStats struct {
+	Passed        uint64 // records passed to the wrapped handler as they came
+	Collapsed     uint64 // repeats held back and written as part of a summary
+	Summaries     uint64 // summary records written
+	SummaryErrors uint64 // summaries the wrapped handler failed to write
+}
+
+// DedupHandler collapses runs of identical records, such as those of a retry
+// loop. A record is a repeat of the one before it if both have the same
+// level, message and attributes, including those added with WithAttrs and
+// the groups opened with WithGroup; the time and source are not compared.
+//
+// The first record of a run is written right away. Its repeats are held back
+// and written as one copy carrying the number of repeats under "repeated" and
+// the times of the first and last repeat under "first" and "last":
+//
+//	level=WARN msg="connect failed" addr=db:5432 repeated=41 first=12:30:45.1 last=12:30:45.9
+//
+// The summary is written when a different record arrives, when
+// DedupOptions.Timeout has passed since the first held repeat, or on Flush,
+// which Closer calls.
+//
+// Handlers derived with WithAttrs and WithGroup share the state of their
+// parent. Records are passed on without holding the state locked, so the
+// wrapped handler may log through the same logger; a summary is written
+// before the record that ends its run, but records logged concurrently may
+// come between them.
+type DedupHandler struct {
+	next  slog.Handler
+	scope []scopeCall
+	d     *deduper
+}
+
+// scopeCall is a WithGroup or WithAttrs call on a DedupHandler.
+type scopeCall struct {
+	group string
+	attrs []slog.Attr
+}
+
+// deduper is the state shared by a DedupHandler and the handlers derived
+// from it.
+type deduper struct {
+	timeout time.Duration
+
+	mu          sync.Mutex
+	next        slog.Handler // of the handler that wrote the last record
+	scope       []scopeCall  // of the handler that wrote the last record
+	record      slog.Record  // the last record; zero before the first
+	attrs       []slog.Attr
+	repeats     int
+	first, last time.Time // of the held repeats
+	timer       *time.Timer
+	gen         uint64 // identifies the armed timer
+	stats       DedupStats
+}
+
+// NewDedupHandler returns a handler that collapses repeated records for next.
+func NewDedupHandler(next slog.Handler, opts DedupOptions) *DedupHandler {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultDedupTimeout
+	}
+	return &DedupHandler{next: next, d: &deduper{timeout: opts.Timeout}}
+}
+
+// WithDedup returns a ConfigOption that makes handlers built from the config
+// collapse repeated records through a DedupHandler (see DedupHandlerOf).
+// It has no effect on a CustomHandler.
+func WithDedup(opts DedupOptions) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.Dedup = &opts
+		cfg.touch()
+	}
+}
+
+// DedupHandlerOf returns the DedupHandler of a logger built from a config
+// with WithDedup, or nil if the logger does not collapse repeats.
+func DedupHandlerOf(l *slog.Logger) *DedupHandler {
+	if l == nil {
+		return nil
+	}
+	h, _ := findHandler[*DedupHandler](l.Handler())
+	return h
+}
+
+func (h *DedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle holds r back if it repeats the previous record and otherwise writes
+// the summary of the held repeats, if any, and r.
+func (h *DedupHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := resolveAttrs(r)
+	d := h.d
+	d.mu.Lock()
+	if d.next != nil && d.record.Level == r.Level && d.record.Message == r.Message &&
+		slices.EqualFunc(d.attrs, attrs, equalAttr) && slices.EqualFunc(d.scope, h.scope, equalScopeCall) {
+		if d.repeats == 0 {
+			d.first = r.Time
+			gen := d.gen
+			d.timer = time.AfterFunc(d.timeout, func() { d.expire(gen) })
+		}
+		d.repeats++
+		d.last = r.Time
+		d.stats.Collapsed++
+		d.mu.Unlock()
+		return nil
+	}
+	next, summary, ok := d.summary()
+	d.next, d.scope, d.record, d.attrs = h.next, h.scope, r.Clone(), attrs
+	d.stats.Passed++
+	d.mu.Unlock()
+
+	var err error
+	if ok {
+		err = d.write(next, summary)
+	}
+	if err2 := h.next.Handle(ctx, r); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (h *DedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	resolved := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		resolved[i] = slog.Attr{Key: a.Key, Value: a.Value.Resolve()}
+	}
+	scope := append(slices.Clip(h.scope), scopeCall{attrs: resolved})
+	return &DedupHandler{next: h.next.WithAttrs(attrs), scope: scope, d: h.d}
+}
+
+func (h *DedupHandler) WithGroup(name string) slog.Handler {
+	scope := h.scope
+	if name != "" {
+		scope = append(slices.Clip(scope), scopeCall{group: name})
+	}
+	return &DedupHandler{next: h.next.WithGroup(name), scope: scope, d: h.d}
+}
+
+func (h *DedupHandler) Unwrap() slog.Handler {
+	return h.next
+}
+
+// Flush writes the summary of the held repeats, if any.
+func (h *DedupHandler) Flush(context.Context) error {
+	d := h.d
+	d.mu.Lock()
+	next, summary, ok := d.summary()
+	d.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return d.write(next, summary)
+}
+
+// Stats returns a snapshot of the handler's counters.
+func (h *DedupHandler) Stats() DedupStats {
+	h.d.mu.Lock()
+	defer h.d.mu.Unlock()
+	return h.d.stats
+}
+
+// expire writes the summary when the timeout armed for generation gen fires.
+// A failure is counted in DedupStats.SummaryErrors.
+func (d *deduper) expire(gen uint64) {
+	d.mu.Lock()
+	if d.gen != gen {
+		d.mu.Unlock()
+		return
+	}
+	next, summary, ok := d.summary()
+	d.mu.Unlock()
+	if ok {
+		d.write(next, summary)
+	}
+}
+
+// summary returns a copy of the last record carrying the count and times of
+// its held repeats, and the handler to write it to. Later repeats start a new
+// summary. It reports false if no repeats are held. d.mu must be held.
+func (d *deduper) summary() (slog.Handler, slog.Record, bool) {
+	if d.repeats == 0 {
+		return nil, slog.Record{}, false
+	}
+	d.timer.Stop()
+	d.timer = nil
+	d.gen++
+	r := d.record.Clone()
+	r.Time = d.last
+	r.AddAttrs(slog.Int("repeated", d.repeats), slog.Time("first", d.first), slog.Time("last", d.last))
+	d.repeats = 0
+	d.stats.Summaries++
+	return d.next, r, true
+}
+
+// write writes a summary to next, counting a failure.
+func (d *deduper) write(next slog.Handler, summary slog.Record) error {
+	err := next.Handle(context.Background(), summary)
+	if err != nil {
+		d.mu.Lock()
+		d.stats.SummaryErrors++
+		d.mu.Unlock()
+	}
+	return err
+}
+
+// resolveAttrs returns the attributes of r with their values resolved.
+func resolveAttrs(r slog.Record) []slog.Attr {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, slog.Attr{Key: a.Key, Value: a.Value.Resolve()})
+		return true
+	})
+	return attrs
+}
+
+// equalScopeCall reports whether a and b open the same group or add the same
+// attributes.
+func equalScopeCall(a, b scopeCall) bool {
+	return a.group == b.group && slices.EqualFunc(a.attrs, b.attrs, equalAttr)
+}
+
+// equalAttr reports whether a and b have the same key and value. Unlike
+// slog.Value.Equal, it does not panic on values of uncomparable types.
+// Errors of the same type are equal if their messages are, so that the
+// errors of a retry loop, each created anew, still repeat one another.
+func equalAttr(a, b slog.Attr) bool {
+	if a.Key != b.Key || a.Value.Kind() != b.Value.Kind() {
+		return false
+	}
+	switch a.Value.Kind() {
+	case slog.KindGroup:
+		return slices.EqualFunc(a.Value.Group(), b.Value.Group(), equalAttr)
+	case slog.KindAny:
+		x, y := a.Value.Any(), b.Value.Any()
+		if ex, ok := x.(error); ok {
+			ey, ok := y.(error)
+			return ok && reflect.TypeOf(ex) == reflect.TypeOf(ey) && ex.Error() == ey.Error()
+		}
+		if x != nil && y != nil && reflect.ValueOf(x).Comparable() && reflect.ValueOf(y).Comparable() {
+			return x == y
+		}
+		return reflect.DeepEqual(x, y)
+	}
+	return a.Value.Equal(b.Value)
+}
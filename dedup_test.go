@@ -0,0 +1,213 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedupHandlerCollapse(t *testing.T) {
+	th := newTestHandler()
+	h := NewDedupHandler(th, DedupOptions{Timeout: time.Hour})
+	logger := slog.New(h)
+	handle := func(at time.Duration, level slog.Level, msg string, args ...any) {
+		r := slog.NewRecord(testTime.Add(at), level, msg, 0)
+		r.Add(args...)
+		if err := logger.Handler().Handle(context.Background(), r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handle(0, slog.LevelWarn, "connect failed", "addr", "db:5432", "tags", []string{"a"})
+	handle(time.Second, slog.LevelWarn, "connect failed", "addr", "db:5432", "tags", []string{"a"})
+	handle(2*time.Second, slog.LevelWarn, "connect failed", "addr", "db:5432", "tags", []string{"a"})
+	handle(3*time.Second, slog.LevelWarn, "connect failed", "addr", "db:5433", "tags", []string{"a"})
+	handle(4*time.Second, slog.LevelError, "connect failed", "addr", "db:5433", "tags", []string{"a"})
+	if got := strings.Join(messages(th), ","); got != "connect failed,connect failed,connect failed,connect failed" {
+		t.Fatalf("messages = %s", got)
+	}
+	summary := (*th.records)[1]
+	attrs := flattenRecord(summary)
+	if attrs["repeated"] != int64(2) || attrs["addr"] != "db:5432" || summary.Level != slog.LevelWarn {
+		t.Errorf("summary = %v at %v", attrs, summary.Level)
+	}
+	if first, last := attrs["first"], attrs["last"]; first != testTime.Add(time.Second) || last != testTime.Add(2*time.Second) {
+		t.Errorf("first = %v, last = %v", first, last)
+	}
+	if !summary.Time.Equal(testTime.Add(2 * time.Second)) {
+		t.Errorf("summary time = %v", summary.Time)
+	}
+	for _, r := range (*th.records)[2:] {
+		if _, ok := flattenRecord(r)["repeated"]; ok {
+			t.Errorf("record at %v is a summary", r.Time)
+		}
+	}
+	if st := h.Stats(); st.Passed != 3 || st.Collapsed != 2 || st.Summaries != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestDedupHandlerDerived(t *testing.T) {
+	th := newTestHandler()
+	h := NewDedupHandler(th, DedupOptions{Timeout: time.Hour})
+	a := slog.New(h).With("conn", 1)
+	b := slog.New(h).With("conn", 2)
+	a.Info("retry")
+	a.Info("retry")
+	b.Info("retry")
+	b.Info("retry")
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range *th.records {
+		attrs := flattenRecord(r)
+		got = append(got, fmt.Sprint(attrs["conn"], " ", attrs["repeated"]))
+	}
+	if strings.Join(got, ",") != "1 <nil>,1 1,2 <nil>,2 1" {
+		t.Errorf("conn and repeated = %q", got)
+	}
+}
+
+func TestDedupHandlerRepeatedErrors(t *testing.T) {
+	th := newTestHandler()
+	h := NewDedupHandler(th, DedupOptions{Timeout: time.Hour})
+	logger := slog.New(h)
+	for range 3 {
+		logger.Warn("connect failed", "err", fmt.Errorf("dial %s: connection refused", "db:5432"))
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if st := h.Stats(); st.Passed != 1 || st.Collapsed != 2 || st.Summaries != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestDedupHandlerDerivedPerCall(t *testing.T) {
+	th := newTestHandler()
+	h := NewDedupHandler(th, DedupOptions{Timeout: time.Hour})
+	logger := slog.New(h)
+	for range 3 {
+		logger.With("addr", "db:5432").WithGroup("conn").Warn("connect failed", "attempt", "same")
+	}
+	logger.With("addr", "db:5433").WithGroup("conn").Warn("connect failed", "attempt", "same")
+	logger.With("addr", "db:5433").Warn("connect failed", "attempt", "same")
+	if st := h.Stats(); st.Passed != 3 || st.Collapsed != 2 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+// reentrantHandler logs through logger when it handles a record with the
+// message "trigger", as a sink that reports its own trouble might.
+type reentrantHandler struct {
+	*testHandler
+	logger *slog.Logger
+}
+
+func (h *reentrantHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Message == "trigger" {
+		h.logger.Warn("sink is slow")
+	}
+	return h.testHandler.Handle(ctx, r)
+}
+
+func TestDedupHandlerReentrant(t *testing.T) {
+	rh := &reentrantHandler{testHandler: newTestHandler()}
+	h := NewDedupHandler(rh, DedupOptions{Timeout: time.Hour})
+	rh.logger = slog.New(h)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		rh.logger.Info("trigger")
+		rh.logger.Info("trigger")
+		h.Flush(context.Background())
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Handle deadlocked when the wrapped handler logged through the same logger")
+	}
+	if got := strings.Join(messages(rh.testHandler), ","); got != "sink is slow,trigger,sink is slow,trigger" {
+		t.Errorf("messages = %s", got)
+	}
+}
+
+func TestDedupHandlerSummaryErrors(t *testing.T) {
+	h := NewDedupHandler(failingHandler{newTestHandler()}, DedupOptions{Timeout: 10 * time.Millisecond})
+	logger := slog.New(h)
+	logger.Info("tick")
+	logger.Info("tick")
+	waitFor(t, "the summary", func() bool { return h.Stats().SummaryErrors == 1 })
+}
+
+func TestDedupHandlerTimeout(t *testing.T) {
+	th := newTestHandler()
+	h := NewDedupHandler(th, DedupOptions{Timeout: 10 * time.Millisecond})
+	logger := slog.New(h)
+	logger.Info("tick")
+	logger.Info("tick")
+	waitFor(t, "the summary", func() bool { return h.Stats().Summaries == 1 })
+	logger.Info("tick")
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if st := h.Stats(); st.Passed != 1 || st.Collapsed != 2 || st.Summaries != 2 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestEqualAttr(t *testing.T) {
+	for _, tt := range []struct {
+		a, b slog.Attr
+		want bool
+	}{
+		{slog.Int("n", 1), slog.Int("n", 1), true},
+		{slog.Int("n", 1), slog.Int64("n", 2), false},
+		{slog.Int("n", 1), slog.Int("m", 1), false},
+		{slog.Int("n", 1), slog.String("n", "1"), false},
+		{slog.Any("s", []int{1, 2}), slog.Any("s", []int{1, 2}), true},
+		{slog.Any("s", []int{1, 2}), slog.Any("s", []int{1}), false},
+		{slog.Any("m", map[string]int{"a": 1}), slog.Any("m", map[string]int{"a": 1}), true},
+		{slog.Group("g", "a", 1), slog.Group("g", "a", 1), true},
+		{slog.Group("g", "a", 1), slog.Group("g", "a", 2), false},
+		{slog.Any("v", struct{ V any }{[]int{1}}), slog.Any("v", struct{ V any }{[]int{1}}), true},
+		{slog.Any("v", struct{ V any }{[]int{1}}), slog.Any("v", struct{ V any }{[]int{2}}), false},
+		{slog.Any("err", errors.New("refused")), slog.Any("err", errors.New("refused")), true},
+		{slog.Any("err", errors.New("refused")), slog.Any("err", errors.New("timeout")), false},
+		{slog.Any("err", errors.New("refused")), slog.Any("err", "refused"), false},
+	} {
+		if got := equalAttr(tt.a, tt.b); got != tt.want {
+			t.Errorf("equalAttr(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestWithDedup(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogConfig(
+		WithHandlerType("text"),
+		WithOutput(&buf),
+		WithDedup(DedupOptions{Timeout: time.Hour}),
+	).NewLogger()
+	for range 5 {
+		logger.Warn("retrying", "attempt", "same")
+	}
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "msg=retrying"); n != 2 {
+		t.Errorf("got %d retrying records, want 2:\n%s", n, out)
+	}
+	if !strings.Contains(out, "attempt=same repeated=4 first=") {
+		t.Errorf("no summary in output:\n%s", out)
+	}
+	if DedupHandlerOf(logger) == nil {
+		t.Error("DedupHandlerOf = nil")
+	}
+}
@@ -0,0 +1,326 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DefaultElasticsearchDestination is the destination of the "elasticsearch"
+// handler type when SlogConfig.Destination is empty: the bulk API of a node
+// on this host.
+const DefaultElasticsearchDestination = "http://localhost:9200" + elasticsearchBulkPath
+
+// DefaultElasticsearchIndex is the index template used when
+// ElasticsearchOptions.Index is empty: one index per day.
+const DefaultElasticsearchIndex = "logs-{2006.01.02}"
+
+// elasticsearchBulkPath is used when a destination has no path.
+const elasticsearchBulkPath = "/_bulk"
+
+// ecsVersion is the Elastic Common Schema version the documents follow.
+const ecsVersion = "8.11.0"
+
+// ecsFields are the top-level fields an ElasticsearchHandler writes itself.
+var ecsFields = []string{"@timestamp", "log", "message", "ecs"}
+
+// ElasticsearchOptions configure an ElasticsearchHandler.
+type ElasticsearchOptions struct {
+	slog.HandlerOptions
+
+	// Index is the name of the index records are written to. Parts in braces
+	// are time layouts applied to the record time in UTC, so the default
+	// "logs-{2006.01.02}" writes to one index per day.
+	Index string
+
+	// LabelGroups lists groups whose attributes are written into the ECS
+	// "labels" object instead of the document body, such as the group of a
+	// Stateful logger. The label name is the attribute path below the top
+	// group joined with '_', with dots replaced by '_' as well, e.g.
+	// labels.Address_City for the attribute Address.City of the group User;
+	// values are written as strings.
+	LabelGroups []string
+
+	// Batch configures batching and retries.
+	Batch BatchOptions
+
+	// Gzip compresses request bodies.
+	Gzip bool
+
+	// Header is added to every request, e.g. Authorization.
+	Header http.Header
+
+	// Client sends the requests. If nil, a client with DefaultHTTPTimeout is used.
+	Client *http.Client
+}
+
+// WithElasticsearchOptions returns a ConfigOption that sets the index,
+// batching and request settings of the "elasticsearch" handler type (see
+// SlogConfig.Elasticsearch). The HandlerOptions in opts are ignored; the
+// handler takes the level and HandlerOptions of the config.
+func WithElasticsearchOptions(opts ElasticsearchOptions) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.Elasticsearch = &opts
+		cfg.touch()
+	}
+}
+
+// ElasticsearchHandler batches records and sends them to the bulk API of
+// Elasticsearch or OpenSearch as documents that follow the Elastic Common
+// Schema:
+//
+//	{"create":{"_index":"logs-2026.10.16"}}
+//	{"@timestamp":"2026-10-16T12:30:45.123456789Z","log":{"level":"info"},"message":"paid","ecs":{"version":"8.11.0"},"amount":42}
+//
+// The record time becomes @timestamp, the level log.level, the message
+// message and the source log.origin. Attributes follow, with groups as nested
+// objects; an error value under the key "error" or "err" is written as
+// error.message. Top-level attributes and groups named like the fields above
+// ("@timestamp", "log", "message" or "ecs") get a '_' appended, so that the
+// document has no duplicate keys. ReplaceAttr sees the level as a slog.Level,
+// written in lower case unless replaced, and built-in attributes renamed by
+// ReplaceAttr keep their new name.
+//
+// The bulk response is checked item by item: items rejected with status 429
+// or 5xx are retried on their own as configured by ElasticsearchOptions.Batch,
+// other rejected items are counted as failed and reported through
+// BatchOptions.OnError.
+//
+// Handlers derived with WithAttrs and WithGroup share the batches of their
+// parent. Call Flush to wait for buffered records and Close to send the rest
+// and stop the sender.
+//
+// It is registered as the "elasticsearch" handler type, which reads the
+// bulk URL from SlogConfig.Destination (see WithDestination).
+type ElasticsearchHandler struct {
+	batch       *batcher[[]byte]
+	index       string
+	labelGroups []string
+	level       slog.Leveler
+	addSource   bool
+	scope       attrScope
+}
+
+// NewElasticsearchHandler returns an ElasticsearchHandler that sends records
+// to url. If url has no path, "/_bulk" is used. A nil opts uses the defaults.
+// It fails only if url is malformed; request errors are reported through
+// BatchOptions.OnError and Stats.
+func NewElasticsearchHandler(url string, opts *ElasticsearchOptions) (*ElasticsearchHandler, error) {
+	url, err := parseHTTPDestination(url, elasticsearchBulkPath)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &ElasticsearchOptions{}
+	}
+	sender := newHTTPSender(opts.Client, url, opts.Header, opts.Gzip)
+	h := &ElasticsearchHandler{
+		index:       opts.Index,
+		labelGroups: slices.Clone(opts.LabelGroups),
+		level:       opts.Level,
+		addSource:   opts.AddSource,
+		scope:       attrScope{replace: opts.ReplaceAttr},
+	}
+	if h.index == "" {
+		h.index = DefaultElasticsearchIndex
+	}
+	if h.level == nil {
+		h.level = slog.LevelInfo
+	}
+	h.batch = newBatcher(opts.Batch, func(ctx context.Context, items [][]byte) error {
+		resp, err := sender.post(ctx, "application/x-ndjson", bytes.Join(items, nil))
+		if err != nil {
+			return err
+		}
+		return checkBulkResponse(resp, items)
+	})
+	return h, nil
+}
+
+func (h *ElasticsearchHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *ElasticsearchHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope.withAttrs(attrs)
+	return &h2
+}
+
+func (h *ElasticsearchHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.scope = h.scope.withGroup(name)
+	return &h2
+}
+
+// Handle encodes r and buffers it for the next batch. It returns
+// ErrBatchClosed after Close.
+func (h *ElasticsearchHandler) Handle(_ context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	doc := &attrNode{children: []*attrNode{}}
+	addBuiltin := func(a slog.Attr, path ...string) {
+		key := a.Key
+		if a, ok := h.scope.builtin(a); ok {
+			switch v := a.Value.Any().(type) {
+			case time.Time:
+				a.Value = slog.StringValue(v.UTC().Format(time.RFC3339Nano))
+			case slog.Level:
+				a.Value = slog.StringValue(strings.ToLower(v.String()))
+			}
+			if a.Key != key {
+				doc.insert(nil, a.Key, a.Value)
+				return
+			}
+			doc.insert(path[:len(path)-1], path[len(path)-1], a.Value)
+		}
+	}
+	addBuiltin(slog.Time(slog.TimeKey, t), "@timestamp")
+	addBuiltin(slog.Any(slog.LevelKey, r.Level), "log", "level")
+	if h.addSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		src := &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}
+		if a, ok := h.scope.builtin(slog.Any(slog.SourceKey, src)); ok {
+			if src, isSource := a.Value.Any().(*slog.Source); a.Key == slog.SourceKey && isSource {
+				doc.insert([]string{"log", "origin", "file"}, "name", slog.StringValue(src.File))
+				doc.insert([]string{"log", "origin", "file"}, "line", slog.IntValue(src.Line))
+				doc.insert([]string{"log", "origin"}, "function", slog.StringValue(src.Function))
+			} else {
+				doc.insert(nil, a.Key, a.Value)
+			}
+		}
+	}
+	addBuiltin(slog.String(slog.MessageKey, r.Message), "message")
+	doc.insert([]string{"ecs"}, "version", slog.StringValue(ecsVersion))
+	h.scope.each(r, func(groups []string, a slog.Attr) {
+		if len(groups) > 0 && slices.Contains(h.labelGroups, groups[0]) {
+			name := strings.ReplaceAll(strings.Join(append(groups[1:len(groups):len(groups)], a.Key), "_"), ".", "_")
+			doc.insert([]string{"labels"}, name, slog.StringValue(syslogValue(a.Value)))
+			return
+		}
+		if err, ok := a.Value.Any().(error); ok && len(groups) == 0 && (a.Key == "error" || a.Key == "err") {
+			doc.insert([]string{"error"}, "message", slog.StringValue(err.Error()))
+			return
+		}
+		switch {
+		case len(groups) > 0 && slices.Contains(ecsFields, groups[0]):
+			groups = append([]string{groups[0] + "_"}, groups[1:]...)
+		case len(groups) == 0 && slices.Contains(ecsFields, a.Key):
+			a.Key += "_"
+		}
+		doc.insert(groups, a.Key, a.Value)
+	})
+
+	var buf bytes.Buffer
+	action, err := json.Marshal(map[string]any{"create": map[string]string{"_index": expandIndex(h.index, t)}})
+	if err != nil {
+		return err
+	}
+	buf.Write(action)
+	buf.WriteByte('\n')
+	doc.appendJSON(&buf)
+	buf.WriteByte('\n')
+	return h.batch.add(buf.Bytes(), buf.Len())
+}
+
+// Flush sends the buffered records and waits until they have been indexed or
+// given up, or until ctx is done.
+func (h *ElasticsearchHandler) Flush(ctx context.Context) error {
+	return h.batch.flush(ctx)
+}
+
+// Close stops accepting records, sends the buffered ones and stops the
+// sender goroutine. It is safe to call more than once.
+func (h *ElasticsearchHandler) Close() error {
+	h.batch.close()
+	return nil
+}
+
+// Stats returns a snapshot of the handler's counters.
+func (h *ElasticsearchHandler) Stats() BatchStats {
+	return h.batch.snapshot()
+}
+
+// expandIndex replaces the time layouts in braces in an index template.
+func expandIndex(template string, t time.Time) string {
+	var sb strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		end := strings.IndexByte(template[start+1:], '}')
+		if start < 0 || end < 0 {
+			sb.WriteString(template)
+			return sb.String()
+		}
+		sb.WriteString(template[:start])
+		sb.WriteString(t.UTC().Format(template[start+1 : start+1+end]))
+		template = template[start+end+2:]
+	}
+}
+
+// checkBulkResponse inspects the items of a bulk response. Items rejected
+// with status 429 or 5xx are returned for a retry in a *partialError, along
+// with the number of items rejected for good.
+func checkBulkResponse(body []byte, items [][]byte) error {
+	var resp struct {
+		Errors bool                                 `json:"errors"`
+		Items  []map[string]elasticsearchItemResult `json:"items"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return &permanentError{fmt.Errorf("logger: bulk response: %w", err)}
+	}
+	if !resp.Errors {
+		return nil
+	}
+	if len(resp.Items) != len(items) {
+		return &permanentError{fmt.Errorf("logger: bulk response has %d items for %d documents", len(resp.Items), len(items))}
+	}
+	var (
+		retry  [][]byte
+		failed int
+		errs   []error
+	)
+	for i, item := range resp.Items {
+		for _, result := range item {
+			switch {
+			case result.Status < 300:
+			case result.Status == http.StatusTooManyRequests || result.Status >= 500:
+				retry = append(retry, items[i])
+			default:
+				failed++
+				if len(errs) < 3 {
+					errs = append(errs, fmt.Errorf("item %d: status %d: %s: %s", i, result.Status, result.Error.Type, result.Error.Reason))
+				}
+			}
+		}
+	}
+	if len(retry) == 0 && failed == 0 {
+		return nil
+	}
+	err := fmt.Errorf("logger: bulk request: %d of %d items rejected, %d to retry", failed, len(items), len(retry))
+	if len(errs) > 0 {
+		err = fmt.Errorf("%w: %w", err, errors.Join(errs...))
+	}
+	return &partialError[[]byte]{retry: retry, failed: failed, err: err}
+}
+
+// elasticsearchItemResult is the outcome of one bulk action.
+type elasticsearchItemResult struct {
+	Status int `json:"status"`
+	Error  struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
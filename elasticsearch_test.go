@@ -0,0 +1,306 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bulkServer is a stand-in for the bulk API. reject decides the status of
+// each document by its message; it records the documents it accepts.
+type bulkServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	indices  []string
+	docs     []map[string]any
+	requests int
+	reject   func(msg string, attempt int) int
+}
+
+func newBulkServer(t *testing.T) *bulkServer {
+	s := &bulkServer{reject: func(string, int) int { return http.StatusCreated }}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		var items []string
+		errors := false
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			var action struct {
+				Create struct {
+					Index string `json:"_index"`
+				} `json:"create"`
+			}
+			var doc map[string]any
+			if err := json.Unmarshal(sc.Bytes(), &action); err != nil || !sc.Scan() || json.Unmarshal(sc.Bytes(), &doc) != nil {
+				http.Error(w, "malformed bulk body", http.StatusBadRequest)
+				return
+			}
+			status := s.reject(fmt.Sprint(doc["message"]), s.requests)
+			if status < 300 {
+				s.indices = append(s.indices, action.Create.Index)
+				s.docs = append(s.docs, doc)
+			} else {
+				errors = true
+			}
+			items = append(items, fmt.Sprintf(`{"create":{"status":%d,"error":{"type":"mapper_parsing_exception","reason":"bad"}}}`, status))
+		}
+		fmt.Fprintf(w, `{"took":1,"errors":%v,"items":[%s]}`, errors, strings.Join(items, ","))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestElasticsearchHandlerECS(t *testing.T) {
+	srv := newBulkServer(t)
+	h, err := NewElasticsearchHandler(srv.URL, &ElasticsearchOptions{
+		HandlerOptions: slog.HandlerOptions{AddSource: true},
+		Index:          "app-{2006.01}-logs",
+		LabelGroups:    []string{"testPerson"},
+		Batch:          BatchOptions{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	state := &testPerson{Name: "Alice", Age: 30}
+	state.Address.City = "Oslo"
+	logger := NewStateful(NewSlogConfig(WithCustomHandler(h)), state)
+	logger.Warn("payment failed", "error", errors.New("card declined"), "order", slog.GroupValue(slog.Int("id", 7)))
+	slog.New(h).Info("plain")
+	if err := h.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(srv.docs) != 2 {
+		t.Fatalf("indexed %d documents, want 2", len(srv.docs))
+	}
+	if want := "app-" + time.Now().UTC().Format("2006.01") + "-logs"; srv.indices[0] != want {
+		t.Errorf("index = %s, want %s", srv.indices[0], want)
+	}
+	doc := srv.docs[0]
+	data, _ := json.Marshal(doc)
+	get := func(path string) any {
+		var v any = doc
+		for _, k := range strings.Split(path, ".") {
+			m, _ := v.(map[string]any)
+			v = m[k]
+		}
+		return v
+	}
+	for path, want := range map[string]any{
+		"log.level":               "warn",
+		"message":                 "payment failed",
+		"ecs.version":             ecsVersion,
+		"error.message":           "card declined",
+		"order.id":                7.0,
+		"labels.Name":             "Alice",
+		"labels.Age":              "30",
+		"labels.Address_City":     "Oslo",
+		"testPerson":              nil,
+		"time":                    nil,
+		"level":                   nil,
+		"msg":                     nil,
+		"log.origin.file.missing": nil,
+	} {
+		if got := get(path); got != want {
+			t.Errorf("%s = %v, want %v in %s", path, got, want, data)
+		}
+	}
+	if ts, err := time.Parse(time.RFC3339Nano, fmt.Sprint(get("@timestamp"))); err != nil || time.Since(ts) > time.Minute {
+		t.Errorf("@timestamp = %v", get("@timestamp"))
+	}
+
+	doc = srv.docs[1]
+	if file := fmt.Sprint(get("log.origin.file.name")); !strings.HasSuffix(file, "elasticsearch_test.go") {
+		t.Errorf("log.origin.file.name = %s", file)
+	}
+	if _, ok := get("log.origin.file.line").(float64); !ok || !strings.HasSuffix(fmt.Sprint(get("log.origin.function")), "TestElasticsearchHandlerECS") {
+		t.Errorf("log.origin = %v", get("log.origin"))
+	}
+}
+
+func TestElasticsearchHandlerReplaceTime(t *testing.T) {
+	srv := newBulkServer(t)
+	var kind slog.Kind
+	h, err := NewElasticsearchHandler(srv.URL, &ElasticsearchOptions{
+		HandlerOptions: slog.HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				kind = a.Value.Kind()
+				return slog.Time(a.Key, a.Value.Time().Truncate(time.Hour))
+			}
+			return a
+		}},
+		Batch: BatchOptions{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	r := slog.NewRecord(time.Date(2026, 10, 16, 12, 30, 45, 0, time.FixedZone("CEST", 2*3600)), slog.LevelInfo, "hello", 0)
+	if err := h.Handle(t.Context(), r); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if kind != slog.KindTime {
+		t.Errorf("ReplaceAttr saw the time as %v, want Time", kind)
+	}
+	if got := srv.docs[0]["@timestamp"]; got != "2026-10-16T10:00:00Z" {
+		t.Errorf("@timestamp = %v, want 2026-10-16T10:00:00Z", got)
+	}
+}
+
+func TestElasticsearchHandlerReplaceLevel(t *testing.T) {
+	srv := newBulkServer(t)
+	var seen any
+	h, err := NewElasticsearchHandler(srv.URL, &ElasticsearchOptions{
+		HandlerOptions: slog.HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.LevelKey && len(groups) == 0 {
+				seen = a.Value.Any()
+				if a.Value.Any() == slog.LevelError {
+					return slog.String(a.Key, "CRITICAL")
+				}
+			}
+			return a
+		}},
+		Batch: BatchOptions{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	logger := slog.New(h)
+	logger.Warn("hello", "message", "user", "ecs", 1, slog.Group("log", "level", "debug"), "@timestamp", "never")
+	logger.Error("failed")
+	if err := h.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	if seen != slog.LevelError {
+		t.Errorf("ReplaceAttr saw the level as %T %v, want slog.Level", seen, seen)
+	}
+	doc := srv.docs[0]
+	if got := doc["log"]; fmt.Sprint(got) != "map[level:warn]" {
+		t.Errorf("log = %v, want map[level:warn]", got)
+	}
+	for key, want := range map[string]any{"message": "hello", "message_": "user", "ecs_": 1.0, "@timestamp_": "never"} {
+		if doc[key] != want {
+			t.Errorf("%s = %v, want %v", key, doc[key], want)
+		}
+	}
+	if got := doc["log_"]; fmt.Sprint(got) != "map[level:debug]" {
+		t.Errorf("log_ = %v, want map[level:debug]", got)
+	}
+	if got := srv.docs[1]["log"]; fmt.Sprint(got) != "map[level:CRITICAL]" {
+		t.Errorf("replaced level: log = %v", got)
+	}
+}
+
+func TestElasticsearchHandlerItemErrors(t *testing.T) {
+	srv := newBulkServer(t)
+	srv.reject = func(msg string, attempt int) int {
+		switch {
+		case msg == "busy" && attempt == 1:
+			return http.StatusTooManyRequests
+		case msg == "bad":
+			return http.StatusBadRequest
+		}
+		return http.StatusCreated
+	}
+	var reported []error
+	h, err := NewElasticsearchHandler(srv.URL+"/", &ElasticsearchOptions{
+		Batch: BatchOptions{Interval: time.Hour, Retry: fastRetry, OnError: func(err error) {
+			reported = append(reported, err)
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(h)
+	logger.Info("ok")
+	logger.Info("busy")
+	logger.Info("bad")
+	if err := h.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []string
+	for _, doc := range srv.docs {
+		messages = append(messages, fmt.Sprint(doc["message"]))
+	}
+	if got := strings.Join(messages, ","); got != "ok,busy" || srv.requests != 2 {
+		t.Errorf("indexed %s in %d requests, want ok,busy in 2", got, srv.requests)
+	}
+	if st := h.Stats(); st.Sent != 2 || st.Failed != 1 || st.Retries != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+	if len(reported) != 1 || !strings.Contains(reported[0].Error(), "mapper_parsing_exception") {
+		t.Errorf("reported = %v", reported)
+	}
+}
+
+func TestExpandIndex(t *testing.T) {
+	for template, want := range map[string]string{
+		"logs":                     "logs",
+		"logs-{2006.01.02}":        "logs-2026.10.16",
+		"{2006}-x-{01}":            "2026-x-10",
+		"logs-{2006.01.02":         "logs-{2006.01.02",
+		"logs-}{15}":               "logs-}12",
+		"app-{2006.01.02}-{15}h":   "app-2026.10.16-12h",
+		"app-{2006.01.02}-{15}h{}": "app-2026.10.16-12h",
+	} {
+		if got := expandIndex(template, testTime.In(time.FixedZone("X", 3600))); got != want {
+			t.Errorf("expandIndex(%q) = %q, want %q", template, got, want)
+		}
+	}
+}
+
+func TestElasticsearchHandlerType(t *testing.T) {
+	srv := newBulkServer(t)
+	logger, err := NewSlogConfig(WithHandlerType("elasticsearch"), WithDestination(srv.URL)).BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	logger.Info("hello")
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(srv.docs) != 1 || !strings.HasPrefix(srv.indices[0], "logs-") {
+		t.Errorf("indexed %v into %v", srv.docs, srv.indices)
+	}
+}
+
+func TestElasticsearchHandlerTypeOptions(t *testing.T) {
+	srv := newBulkServer(t)
+	logger, err := NewSlogConfig(WithHandlerType("elasticsearch"), WithDestination(srv.URL), WithElasticsearchOptions(ElasticsearchOptions{
+		Index:       "app-{2006}",
+		LabelGroups: []string{"user"},
+	})).BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	logger.Info("hello", slog.Group("user", "id", 7))
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if want := "app-" + time.Now().UTC().Format("2006"); len(srv.indices) != 1 || srv.indices[0] != want {
+		t.Fatalf("indexed into %v, want %s", srv.indices, want)
+	}
+	if got := fmt.Sprint(srv.docs[0]["labels"]); got != "map[id:7]" {
+		t.Errorf("labels = %s, want map[id:7]", got)
+	}
+}
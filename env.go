@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultEnvPrefix is the prefix WithEnv uses when given an empty prefix.
+const DefaultEnvPrefix = "GSLOG"
+
+// Environment variable suffixes read by WithEnv. The full variable name is
+// the prefix, an underscore and the suffix, e.g. GSLOG_LEVEL.
+const (
+	EnvLevel       = "LEVEL"       // level, see LoadSlogConfig for accepted values
+	EnvFormat      = "FORMAT"      // registered handler type
+	EnvOutput      = "OUTPUT"      // "stderr", "stdout" or a file path
+	EnvDestination = "DESTINATION" // server address of network handler types
+	EnvResource    = "RESOURCE"    // resource attributes, e.g. "service.name=shop,env=prod"
+	EnvAddSource   = "ADD_SOURCE"  // boolean, as accepted by strconv.ParseBool
+)
+
+// WithEnv returns a ConfigOption that overrides the config with values from
+// environment variables named <prefix>_LEVEL, <prefix>_FORMAT, <prefix>_OUTPUT,
+// <prefix>_DESTINATION, <prefix>_RESOURCE and <prefix>_ADD_SOURCE. Unset or
+// empty variables leave the config unchanged. If prefix is empty,
+// DefaultEnvPrefix is used.
+//
+// Invalid values are not applied; they are recorded as *ConfigKeyError
+// (keyed by the variable name) and reported by SlogConfig.Err and BuildHandler.
+// If any value is invalid, the file named by <prefix>_OUTPUT is not opened.
+func WithEnv(prefix string) ConfigOption {
+	return func(cfg *SlogConfig) {
+		for _, opt := range envOptions(prefix, cfg) {
+			opt(cfg)
+		}
+	}
+}
+
+// envOptions returns the options for the environment variables that are set,
+// recording invalid values on cfg.
+func envOptions(prefix string, cfg *SlogConfig) []ConfigOption {
+	if prefix == "" {
+		prefix = DefaultEnvPrefix
+	}
+	if !strings.HasSuffix(prefix, "_") {
+		prefix += "_"
+	}
+	var opts []ConfigOption
+	if name, value, ok := lookupEnv(prefix, EnvFormat); ok {
+		if _, err := lookupHandlerType(value); err != nil {
+			cfg.addErr(&ConfigKeyError{Key: name, Err: err})
+		} else {
+			opts = append(opts, WithHandlerType(value))
+		}
+	}
+	if name, value, ok := lookupEnv(prefix, EnvLevel); ok {
+		if level, err := parseLevel(value); err != nil {
+			cfg.addErr(&ConfigKeyError{Key: name, Err: err})
+		} else {
+			opts = append(opts, WithLevel(level))
+		}
+	}
+	if name, value, ok := lookupEnv(prefix, EnvAddSource); ok {
+		if add, err := strconv.ParseBool(value); err != nil {
+			cfg.addErr(&ConfigKeyError{Key: name, Err: err})
+		} else {
+			opts = append(opts, WithAddSource(add))
+		}
+	}
+	if _, value, ok := lookupEnv(prefix, EnvDestination); ok {
+		opts = append(opts, WithDestination(value))
+	}
+	if name, value, ok := lookupEnv(prefix, EnvResource); ok {
+		if resource, err := parseResource(value); err != nil {
+			cfg.addErr(&ConfigKeyError{Key: name, Err: err})
+		} else {
+			opts = append(opts, WithResource(resource))
+		}
+	}
+	// The output is opened last, and not at all if the config is already
+	// invalid, so that a file is never opened for a config that cannot build.
+	if name, value, ok := lookupEnv(prefix, EnvOutput); ok && cfg.err == nil {
+		if w, err := openOutput(value); err != nil {
+			cfg.addErr(&ConfigKeyError{Key: name, Err: err})
+		} else {
+			opts = append(opts, WithOutput(w), ownOutput(w))
+		}
+	}
+	return opts
+}
+
+// lookupEnv returns the full variable name and its trimmed value.
+// ok is false if the variable is unset or empty.
+func lookupEnv(prefix, suffix string) (name, value string, ok bool) {
+	name = prefix + suffix
+	value = strings.TrimSpace(os.Getenv(name))
+	return name, value, value != ""
+}
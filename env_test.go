@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWithEnv(t *testing.T) {
+	t.Setenv("GSLOG_LEVEL", "debug")
+	t.Setenv("GSLOG_FORMAT", "text")
+	t.Setenv("GSLOG_OUTPUT", "stdout")
+	t.Setenv("GSLOG_ADD_SOURCE", "true")
+
+	cfg := NewSlogConfig(WithLevel(slog.LevelError), WithEnv(""))
+	if err := cfg.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if cfg.Level != slog.LevelDebug {
+		t.Errorf("Level = %v, want DEBUG", cfg.Level)
+	}
+	if cfg.HandlerType != "text" {
+		t.Errorf("HandlerType = %q, want text", cfg.HandlerType)
+	}
+	if cfg.Output != os.Stdout {
+		t.Errorf("Output = %v, want stdout", cfg.Output)
+	}
+	if cfg.HandlerOptions == nil || !cfg.HandlerOptions.AddSource {
+		t.Errorf("HandlerOptions = %+v, want AddSource", cfg.HandlerOptions)
+	}
+}
+
+func TestWithEnvCustomPrefix(t *testing.T) {
+	t.Setenv("APP_LOG_LEVEL", "warn")
+	t.Setenv("GSLOG_LEVEL", "debug")
+	cfg := NewSlogConfig(WithEnv("APP_LOG_"))
+	if cfg.Level != slog.LevelWarn {
+		t.Errorf("Level = %v, want WARN", cfg.Level)
+	}
+}
+
+func TestWithEnvUnsetKeepsConfig(t *testing.T) {
+	cfg := NewSlogConfig(WithHandlerType("text"), WithLevel(slog.LevelWarn), WithEnv("GSLOG_TEST_UNSET"))
+	if cfg.HandlerType != "text" || cfg.Level != slog.LevelWarn {
+		t.Errorf("config changed without env: %+v", cfg)
+	}
+}
+
+func TestWithEnvOutputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "env.log")
+	t.Setenv("GSLOG_OUTPUT", path)
+	cfg := NewSlogConfig(WithEnv(""))
+	f, ok := cfg.Output.(*os.File)
+	if !ok {
+		t.Fatalf("Output = %T, want *os.File", cfg.Output)
+	}
+	defer f.Close()
+	if f.Name() != path {
+		t.Errorf("Output file = %q, want %q", f.Name(), path)
+	}
+}
+
+func TestWithEnvInvalidValues(t *testing.T) {
+	t.Setenv("GSLOG_LEVEL", "loud")
+	t.Setenv("GSLOG_FORMAT", "jsn")
+	t.Setenv("GSLOG_ADD_SOURCE", "maybe")
+
+	cfg := NewSlogConfig(WithLevel(slog.LevelWarn), WithEnv(""))
+	err := cfg.Err()
+	if err == nil {
+		t.Fatal("Err() = nil, want error")
+	}
+	var keyErr *ConfigKeyError
+	if !errors.As(err, &keyErr) {
+		t.Fatalf("Err() = %v, want *ConfigKeyError", err)
+	}
+	if !errors.Is(err, ErrUnknownHandlerType) {
+		t.Errorf("Err() = %v, want ErrUnknownHandlerType for GSLOG_FORMAT", err)
+	}
+	if cfg.Level != slog.LevelWarn {
+		t.Errorf("invalid level applied: %v", cfg.Level)
+	}
+	if _, err := cfg.BuildLogger(); err == nil {
+		t.Error("BuildLogger succeeded despite invalid env values")
+	}
+	if cfg.NewLogger() == nil {
+		t.Error("NewLogger returned nil")
+	}
+}
+
+func TestWithEnvInvalidSkipsOutputFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "env.log")
+	t.Setenv("GSLOG_LEVEL", "loud")
+	t.Setenv("GSLOG_OUTPUT", path)
+
+	cfg := NewSlogConfig(WithEnv(""))
+	if cfg.Err() == nil {
+		t.Fatal("Err() = nil, want error")
+	}
+	if cfg.Output != os.Stderr {
+		t.Errorf("Output = %v, want stderr", cfg.Output)
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("output file opened for an invalid config: %v", err)
+	}
+}
@@ -0,0 +1,278 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"strings"
+)
+
+// DefaultGELFDestination is the destination of the "gelf" handler type when
+// SlogConfig.Destination is empty: a Graylog GELF UDP input on this host.
+const DefaultGELFDestination = "udp://localhost:12201"
+
+// DefaultGELFChunkSize is the largest UDP datagram a GELFHandler sends.
+// It fits the usual Ethernet MTU.
+const DefaultGELFChunkSize = 1420
+
+// gelfMaxChunks is the largest number of chunks a message may be split into.
+const gelfMaxChunks = 128
+
+// gelfChunkHeader is the size of the chunk header: the magic bytes 0x1e 0x0f,
+// an 8-byte message id, the sequence number and the sequence count.
+const gelfChunkHeader = 12
+
+// GELFCompression selects how GELF messages sent over UDP are compressed.
+type GELFCompression int
+
+const (
+	GELFGzip          GELFCompression = iota // gzip, the default
+	GELFZlib                                 // zlib
+	GELFNoCompression                        // plain JSON
+)
+
+// GELFOptions configure a GELFHandler.
+type GELFOptions struct {
+	slog.HandlerOptions
+
+	// Host is the "host" field of every message. It defaults to os.Hostname.
+	Host string
+
+	// Compression applies to UDP only; GELF over TCP is never compressed.
+	Compression GELFCompression
+
+	// ChunkSize is the largest datagram sent over UDP; larger messages are
+	// split into chunks. If zero, DefaultGELFChunkSize is used.
+	ChunkSize int
+}
+
+// WithGELFOptions returns a ConfigOption that sets the host, compression and
+// chunking settings of the "gelf" handler type (see SlogConfig.GELF). The
+// HandlerOptions in opts are ignored; the handler takes the level and
+// HandlerOptions of the config.
+func WithGELFOptions(opts GELFOptions) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.GELF = &opts
+		cfg.touch()
+	}
+}
+
+// GELFHandler sends records to Graylog as GELF 1.1 messages:
+//
+//	{"_User_ID":7,"host":"web-1","level":6,"short_message":"user logged in","timestamp":1792153845.123,"version":"1.1"}
+//
+// Levels map to the syslog severities used by SyslogHandler. Attributes
+// become additional fields: their names are prefixed with '_', groups are
+// joined with '_', so that the "User" group of a Stateful logger yields
+// "_User_ID", and characters other than letters, digits, '_' and '-'
+// (including '.') are replaced by '_'. The reserved name "_id" is written
+// as "_id_". Numbers stay numbers; other values are sent as strings.
+// With AddSource, the fields "_file", "_line" and "_function" are added.
+//
+// The destination selects the transport. Over "udp://host:port" messages
+// are compressed and, if still larger than the chunk size, split into at
+// most 128 chunks. Over "tcp://host:port" every message is terminated by a
+// null byte. The connection is opened on the first record and reopened after
+// a failed write.
+//
+// It is registered as the "gelf" handler type, which reads the destination
+// from SlogConfig.Destination (see WithDestination).
+type GELFHandler struct {
+	conn        *reconnConn
+	stream      bool
+	host        string
+	compression GELFCompression
+	chunkSize   int
+	level       slog.Leveler
+	addSource   bool
+	scope       attrScope
+}
+
+// NewGELFHandler returns a GELFHandler that sends records to dest.
+// A nil opts uses the defaults. It fails only if dest is malformed;
+// connection errors are reported by Handle.
+func NewGELFHandler(dest string, opts *GELFOptions) (*GELFHandler, error) {
+	network, address, err := parseDestination(dest, "udp", "tcp")
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &GELFOptions{}
+	}
+	h := &GELFHandler{
+		conn:        newReconnConn(network, address),
+		stream:      network == "tcp",
+		host:        opts.Host,
+		compression: opts.Compression,
+		chunkSize:   opts.ChunkSize,
+		level:       opts.Level,
+		addSource:   opts.AddSource,
+		scope:       attrScope{replace: opts.ReplaceAttr},
+	}
+	if h.host == "" {
+		h.host, _ = os.Hostname()
+	}
+	if h.chunkSize <= gelfChunkHeader {
+		h.chunkSize = DefaultGELFChunkSize
+	}
+	if h.level == nil {
+		h.level = slog.LevelInfo
+	}
+	return h, nil
+}
+
+func (h *GELFHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *GELFHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope.withAttrs(attrs)
+	return &h2
+}
+
+func (h *GELFHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.scope = h.scope.withGroup(name)
+	return &h2
+}
+
+func (h *GELFHandler) Handle(_ context.Context, r slog.Record) error {
+	msg, err := h.encode(r)
+	if err != nil {
+		return err
+	}
+	if h.stream {
+		return h.conn.write(append(msg, 0))
+	}
+	if msg, err = gelfCompress(msg, h.compression); err != nil {
+		return err
+	}
+	chunks, err := gelfChunks(msg, h.chunkSize, rand.Uint64())
+	if err != nil {
+		return err
+	}
+	return h.conn.write(chunks...)
+}
+
+// Close closes the connection to the server. Handlers derived with WithAttrs
+// and WithGroup share the connection.
+func (h *GELFHandler) Close() error {
+	return h.conn.Close()
+}
+
+// encode returns the GELF JSON document for r.
+func (h *GELFHandler) encode(r slog.Record) ([]byte, error) {
+	msg := map[string]any{
+		"version": "1.1",
+		"host":    h.host,
+		"level":   syslogSeverity(r.Level),
+	}
+	if !r.Time.IsZero() {
+		msg["timestamp"] = float64(r.Time.UnixMicro()) / 1e6
+	}
+	// short_message is required, so a message dropped by ReplaceAttr is
+	// sent as an empty string.
+	msg["short_message"] = ""
+	if a, ok := h.scope.builtin(slog.String(slog.MessageKey, r.Message)); ok {
+		msg["short_message"] = syslogValue(a.Value)
+	}
+	if h.addSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		msg["_file"] = frame.File
+		msg["_line"] = frame.Line
+		msg["_function"] = frame.Function
+	}
+	h.scope.each(r, func(groups []string, a slog.Attr) {
+		msg[gelfFieldName(append(groups[:len(groups):len(groups)], a.Key))] = gelfValue(a.Value)
+	})
+	return json.Marshal(msg)
+}
+
+// gelfFieldName returns the additional field name for a group path.
+func gelfFieldName(path []string) string {
+	name := "_" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, strings.Join(path, "_"))
+	if name == "_id" {
+		name = "_id_"
+	}
+	return name
+}
+
+// gelfValue returns an attribute value as a JSON number or string.
+func gelfValue(v slog.Value) any {
+	switch v.Kind() {
+	case slog.KindInt64:
+		return v.Int64()
+	case slog.KindUint64:
+		return v.Uint64()
+	case slog.KindFloat64:
+		if f := v.Float64(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f
+		}
+	}
+	return syslogValue(v)
+}
+
+// gelfCompress compresses a message for UDP.
+func gelfCompress(msg []byte, c GELFCompression) ([]byte, error) {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	switch c {
+	case GELFNoCompression:
+		return msg, nil
+	case GELFZlib:
+		w = zlib.NewWriter(&buf)
+	default:
+		w = gzip.NewWriter(&buf)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// gelfChunks splits msg into datagrams of at most size bytes. A message that
+// fits is sent as is; otherwise every chunk carries the chunk header with the
+// given message id.
+func gelfChunks(msg []byte, size int, id uint64) ([][]byte, error) {
+	if len(msg) <= size {
+		return [][]byte{msg}, nil
+	}
+	data := size - gelfChunkHeader
+	count := (len(msg) + data - 1) / data
+	if count > gelfMaxChunks {
+		return nil, fmt.Errorf("logger: GELF message of %d bytes needs %d chunks, more than %d", len(msg), count, gelfMaxChunks)
+	}
+	chunks := make([][]byte, 0, count)
+	for seq := 0; seq < count; seq++ {
+		part := msg[seq*data : min((seq+1)*data, len(msg))]
+		chunk := make([]byte, 0, gelfChunkHeader+len(part))
+		chunk = append(chunk, 0x1e, 0x0f)
+		chunk = binary.BigEndian.AppendUint64(chunk, id)
+		chunk = append(chunk, byte(seq), byte(count))
+		chunks = append(chunks, append(chunk, part...))
+	}
+	return chunks, nil
+}
@@ -0,0 +1,195 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recvGELF reads datagrams until a complete message has arrived, joins its
+// chunks, decompresses it and decodes the JSON document.
+func recvGELF(t *testing.T, recv func() []byte) map[string]any {
+	t.Helper()
+	var (
+		id     []byte
+		chunks [][]byte
+		got    int
+		msg    []byte
+	)
+	for msg == nil {
+		d := recv()
+		if !bytes.HasPrefix(d, []byte{0x1e, 0x0f}) {
+			msg = d
+			break
+		}
+		if id == nil {
+			id = d[2:10]
+			chunks = make([][]byte, d[11])
+		}
+		if !bytes.Equal(id, d[2:10]) || int(d[11]) != len(chunks) {
+			t.Fatalf("chunk header %x does not match message %x of %d chunks", d[:12], id, len(chunks))
+		}
+		if chunks[d[10]] == nil {
+			got++
+		}
+		chunks[d[10]] = d[12:]
+		if got == len(chunks) {
+			msg = bytes.Join(chunks, nil)
+		}
+	}
+	switch {
+	case bytes.HasPrefix(msg, []byte{0x1f, 0x8b}):
+		zr, err := gzip.NewReader(bytes.NewReader(msg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, _ = io.ReadAll(zr)
+	case msg[0] == 0x78:
+		zr, err := zlib.NewReader(bytes.NewReader(msg))
+		if err != nil {
+			t.Fatal(err)
+		}
+		msg, _ = io.ReadAll(zr)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(msg, &doc); err != nil {
+		t.Fatalf("decode %q: %v", msg, err)
+	}
+	return doc
+}
+
+func TestGELFHandlerUDP(t *testing.T) {
+	addr, recv := listenPacket(t, "udp", "127.0.0.1:0")
+	h, err := NewGELFHandler("udp://"+addr, &GELFOptions{Host: "web-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	logger := slog.New(h).With("id", "abc").WithGroup("http")
+	logger.Error("request failed", "status", 502, "took", 1.5, "ok", false, "peer.addr", "10.0.0.1")
+
+	want := map[string]any{
+		"version":         "1.1",
+		"host":            "web-1",
+		"level":           3.0,
+		"short_message":   "request failed",
+		"_id_":            "abc",
+		"_http_status":    502.0,
+		"_http_took":      1.5,
+		"_http_ok":        "false",
+		"_http_peer_addr": "10.0.0.1",
+	}
+	got := recvGELF(t, recv)
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %#v, want %#v", k, got[k], v)
+		}
+	}
+	if ts, _ := got["timestamp"].(float64); time.Since(time.UnixMicro(int64(ts*1e6))) > time.Minute {
+		t.Errorf("timestamp = %v", got["timestamp"])
+	}
+}
+
+func TestGELFHandlerTypeOptions(t *testing.T) {
+	addr, recv := listenPacket(t, "udp", "127.0.0.1:0")
+	logger, err := NewSlogConfig(WithHandlerType("gelf"), WithDestination("udp://"+addr), WithGELFOptions(GELFOptions{
+		Host:        "web-1",
+		Compression: GELFNoCompression,
+	})).BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	defer CloserOf(logger).Close()
+	logger.Info("hello")
+	if got := recvGELF(t, recv); got["host"] != "web-1" || got["short_message"] != "hello" {
+		t.Errorf("message = %v", got)
+	}
+}
+
+func TestGELFHandlerChunking(t *testing.T) {
+	addr, recv := listenPacket(t, "udp", "127.0.0.1:0")
+	for _, c := range []GELFCompression{GELFGzip, GELFZlib, GELFNoCompression} {
+		h, err := NewGELFHandler("udp://"+addr, &GELFOptions{Compression: c, ChunkSize: 512})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Random-looking data keeps the message large after compression.
+		var sb strings.Builder
+		for i := range 4000 {
+			sb.WriteByte("0123456789abcdef"[(i*7919)%16^(i/16)%16])
+		}
+		slog.New(h).Info("big", "data", sb.String())
+		if got := recvGELF(t, recv); got["_data"] != sb.String() {
+			t.Errorf("compression %d: _data mangled (%d bytes)", c, len(got["_data"].(string)))
+		}
+		h.Close()
+	}
+
+	if _, err := gelfChunks(make([]byte, 129*100), 112, 1); err == nil {
+		t.Error("gelfChunks accepted a message needing 129 chunks")
+	}
+	chunks, err := gelfChunks(make([]byte, 250), 112, 0x0102030405060708)
+	if err != nil || len(chunks) != 3 {
+		t.Fatalf("gelfChunks = %d chunks, %v", len(chunks), err)
+	}
+	if want := []byte{0x1e, 0x0f, 1, 2, 3, 4, 5, 6, 7, 8, 2, 3}; !bytes.Equal(chunks[2][:12], want) {
+		t.Errorf("header = %x, want %x", chunks[2][:12], want)
+	}
+}
+
+func TestGELFHandlerTCP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	msgs := make(chan string, 10)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		for {
+			msg, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+			msgs <- strings.TrimSuffix(msg, "\x00")
+		}
+	}()
+
+	cfg := NewSlogConfig(WithHandlerType("gelf"), WithDestination("tcp://"+ln.Addr().String()))
+	state := &testPerson{Name: "Alice", Age: 30}
+	state.Address.City = "Oslo"
+	logger := NewStateful(cfg, state)
+	logger.Info("hello", "note", "line1\nline2")
+	logger.Info("again")
+	for _, want := range []string{"hello", "again"} {
+		select {
+		case msg := <-msgs:
+			var doc map[string]any
+			if err := json.Unmarshal([]byte(msg), &doc); err != nil {
+				t.Fatalf("decode %q: %v", msg, err)
+			}
+			if doc["short_message"] != want || doc["_testPerson_Name"] != "Alice" || doc["_testPerson_Age"] != 30.0 ||
+				doc["_testPerson_Address_City"] != "Oslo" {
+				t.Errorf("message = %s", msg)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no message received")
+		}
+	}
+	if err := logger.Closer().Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+}
@@ -24,13 +24,7 @@ func (h *ContextExtractorHandler) Enabled(ctx context.Context, level slog.Level)
 func (h *ContextExtractorHandler) Handle(ctx context.Context, r slog.Record) error {
 	var attrs []slog.Attr
 	for _, f := range h.fields {
-		var val any
-		if f.Extractor != nil {
-			val = f.Extractor(ctx)
-		} else {
-			val = ctx.Value(f.Key)
-		}
-		if val != nil {
+		if val := f.value(ctx); val != nil {
 			attrs = append(attrs, slog.Any(f.Key, val))
 		}
 	}
@@ -70,6 +64,11 @@ func (h *ContextExtractorHandler) WithGroup(name string) slog.Handler {
 	}
 }
 
+// Unwrap returns the wrapped handler.
+func (h *ContextExtractorHandler) Unwrap() slog.Handler {
+	return h.next
+}
+
 // WrapHandlerWithContext wraps an existing slog.Handler with a ContextExtractorHandler.
 // The returned handler will add the specified fields from the context to every log record.
 // If group is non-empty, the fields are grouped under that name.
@@ -115,3 +114,7 @@ func (h *contextHandler) WithGroup(name string) slog.Handler {
 		extractor: h.extractor,
 	}
 }
+
+func (h *contextHandler) Unwrap() slog.Handler {
+	return h.next
+}
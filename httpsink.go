@@ -0,0 +1,527 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is the error of batches that an HTTPHandler does not send
+// because its circuit breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// errSpoolBacklog is the error of batches that an HTTPHandler spools to keep
+// them behind older spooled batches.
+var errSpoolBacklog = errors.New("spool not yet replayed")
+
+// Defaults of CircuitOptions and HTTPOptions.
+const (
+	DefaultCircuitFailures = 5
+	DefaultCircuitCooldown = 30 * time.Second
+	DefaultSpoolMaxBytes   = 64 << 20
+)
+
+// spoolExt is the extension of spool files.
+const spoolExt = ".ndjson"
+
+// HTTPOptions configure an HTTPHandler.
+type HTTPOptions struct {
+	slog.HandlerOptions
+
+	// Batch configures batching and retries.
+	Batch BatchOptions
+
+	// Circuit configures the circuit breaker.
+	Circuit CircuitOptions
+
+	// SpoolDir, if non-empty, is a directory where batches that cannot be
+	// delivered are kept until the endpoint recovers. It is created if
+	// needed; batches left in it by an earlier process are sent as well.
+	SpoolDir string
+
+	// SpoolMaxBytes limits the size of the spool; batches that do not fit
+	// are given up. If not positive, DefaultSpoolMaxBytes is used.
+	SpoolMaxBytes int64
+
+	// Gzip compresses request bodies.
+	Gzip bool
+
+	// Header is added to every request, e.g. Authorization.
+	Header http.Header
+
+	// Client sends the requests. If nil, a client with DefaultHTTPTimeout is used.
+	Client *http.Client
+}
+
+// WithHTTPOptions returns a ConfigOption that sets the batching, circuit
+// breaker, spool and request settings of the "http" handler type (see
+// SlogConfig.HTTP). The HandlerOptions in opts are ignored; the handler
+// takes the level and HandlerOptions of the config.
+func WithHTTPOptions(opts HTTPOptions) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.HTTP = &opts
+		cfg.touch()
+	}
+}
+
+// CircuitOptions configure the circuit breaker of an HTTPHandler. After
+// Failures failed requests in a row the circuit opens: batches are not sent
+// but spooled, or given up if there is no spool, for Cooldown. Then a single
+// request probes the endpoint; if it succeeds the circuit closes, otherwise
+// it stays open for another Cooldown. Requests rejected as malformed, such as
+// with HTTP status 400, do not count as failures.
+type CircuitOptions struct {
+	// Failures is the number of failed requests in a row that opens the
+	// circuit. If not positive, DefaultCircuitFailures is used.
+	Failures int
+
+	// Cooldown is how long the circuit stays open before it is probed.
+	// If not positive, DefaultCircuitCooldown is used.
+	Cooldown time.Duration
+}
+
+// HTTPStats are the counters of an HTTPHandler.
+type HTTPStats struct {
+	BatchStats
+
+	Spooled      uint64 // records written to the spool
+	Replayed     uint64 // spooled records delivered
+	SpoolDropped uint64 // records that did not fit in the spool or were rejected on replay
+	SpoolPending int    // records in the spool
+	CircuitOpen  bool   // whether the circuit breaker is open
+	CircuitTrips uint64 // times the circuit breaker opened
+}
+
+// HTTPHandler batches records and posts them as newline-delimited JSON to an
+// HTTP endpoint, for collectors that do not speak a standard protocol. Each
+// line has the form written by slog.JSONHandler:
+//
+//	{"time":"2026-10-16T12:30:45.123456789Z","level":"INFO","msg":"paid","amount":42}
+//
+// Requests have the content type application/x-ndjson. Failed requests are
+// retried with exponential backoff as configured by HTTPOptions.Batch, and
+// repeated failures open a circuit breaker (see CircuitOptions). With a
+// SpoolDir, batches that cannot be delivered are written to disk instead of
+// being given up. While the spool holds batches, new batches are appended to
+// it, and a background goroutine sends them in order once the circuit allows
+// it. Stats reports the spool and circuit along with the batch counters.
+//
+// Handlers derived with WithAttrs and WithGroup share the batches of their
+// parent. Call Flush to wait for buffered records and Close to send the rest
+// and stop the sender; batches still in the spool stay there for the next
+// process.
+//
+// It is registered as the "http" handler type, which reads the endpoint from
+// SlogConfig.Destination (see WithDestination).
+type HTTPHandler struct {
+	*httpSink
+	level     slog.Leveler
+	addSource bool
+	scope     attrScope
+}
+
+// httpSink is the part of an HTTPHandler shared with derived handlers.
+type httpSink struct {
+	batch   *batcher[[]byte]
+	sender  *httpSender
+	circuit *circuitBreaker
+	spool   *diskSpool // nil without SpoolDir
+
+	replayWake chan struct{}
+	stop       chan struct{}
+	replayDone chan struct{}
+	closeOnce  sync.Once
+}
+
+// NewHTTPHandler returns an HTTPHandler that posts records to url. A nil opts
+// uses the defaults. It fails if url is malformed or the spool directory
+// cannot be opened; request errors are reported through BatchOptions.OnError
+// and Stats.
+func NewHTTPHandler(url string, opts *HTTPOptions) (*HTTPHandler, error) {
+	url, err := parseHTTPDestination(url, "/")
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &HTTPOptions{}
+	}
+	s := &httpSink{
+		sender:     newHTTPSender(opts.Client, url, opts.Header, opts.Gzip),
+		circuit:    newCircuitBreaker(opts.Circuit),
+		replayWake: make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		replayDone: make(chan struct{}),
+	}
+	var spill func([][]byte, error) int
+	if opts.SpoolDir != "" {
+		if s.spool, err = openSpool(opts.SpoolDir, opts.SpoolMaxBytes); err != nil {
+			return nil, err
+		}
+		spill = s.spill
+		go s.replay()
+		s.signalReplay()
+	} else {
+		close(s.replayDone)
+	}
+	s.batch = newSpillingBatcher(opts.Batch, s.send, spill)
+	h := &HTTPHandler{
+		httpSink:  s,
+		level:     opts.Level,
+		addSource: opts.AddSource,
+		scope:     attrScope{replace: opts.ReplaceAttr},
+	}
+	if h.level == nil {
+		h.level = slog.LevelInfo
+	}
+	return h, nil
+}
+
+func (h *HTTPHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *HTTPHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope.withAttrs(attrs)
+	return &h2
+}
+
+func (h *HTTPHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.scope = h.scope.withGroup(name)
+	return &h2
+}
+
+// Handle encodes r as a JSON line and buffers it for the next batch. It
+// returns ErrBatchClosed after Close.
+func (h *HTTPHandler) Handle(_ context.Context, r slog.Record) error {
+	line := &attrNode{children: []*attrNode{}}
+	addBuiltin := func(a slog.Attr) {
+		if a, ok := h.scope.builtin(a); ok {
+			line.insert(nil, a.Key, a.Value)
+		}
+	}
+	if !r.Time.IsZero() {
+		addBuiltin(slog.Time(slog.TimeKey, r.Time))
+	}
+	addBuiltin(slog.Any(slog.LevelKey, r.Level))
+	if h.addSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		addBuiltin(slog.Any(slog.SourceKey, &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}))
+	}
+	addBuiltin(slog.String(slog.MessageKey, r.Message))
+	h.scope.each(r, func(groups []string, a slog.Attr) {
+		line.insert(groups, a.Key, a.Value)
+	})
+	var buf bytes.Buffer
+	line.appendJSON(&buf)
+	buf.WriteByte('\n')
+	return h.batch.add(buf.Bytes(), buf.Len())
+}
+
+// Flush sends the buffered records and waits until they have been delivered,
+// spooled or given up, or until ctx is done. It does not wait for the spool
+// to be replayed.
+func (h *HTTPHandler) Flush(ctx context.Context) error {
+	return h.batch.flush(ctx)
+}
+
+// Close stops accepting records, sends or spools the buffered ones and stops
+// the sender and replay goroutines. It is safe to call more than once.
+func (h *HTTPHandler) Close() error {
+	h.batch.close()
+	h.closeOnce.Do(func() { close(h.stop) })
+	<-h.replayDone
+	return nil
+}
+
+// Stats returns a snapshot of the handler's counters.
+func (h *HTTPHandler) Stats() HTTPStats {
+	st := HTTPStats{BatchStats: h.batch.snapshot()}
+	st.CircuitOpen, st.CircuitTrips = h.circuit.state()
+	if h.spool != nil {
+		h.spool.mu.Lock()
+		st.Spooled, st.Replayed, st.SpoolDropped = h.spool.spooled, h.spool.replayed, h.spool.dropped
+		h.spool.mu.Unlock()
+		st.SpoolPending = h.spool.pending()
+	}
+	return st
+}
+
+// send posts a batch unless the circuit is open or older batches wait in the
+// spool.
+func (s *httpSink) send(ctx context.Context, lines [][]byte) error {
+	if s.spool != nil && s.spool.pending() > 0 {
+		return &permanentError{errSpoolBacklog}
+	}
+	if !s.circuit.allow() {
+		return &permanentError{ErrCircuitOpen}
+	}
+	_, err := s.post(ctx, bytes.Join(lines, nil))
+	return err
+}
+
+// post sends an NDJSON body and records the outcome with the circuit breaker.
+func (s *httpSink) post(ctx context.Context, body []byte) ([]byte, error) {
+	resp, err := s.sender.post(ctx, "application/x-ndjson", body)
+	if err == nil || !retryable(err) {
+		s.circuit.success()
+	} else {
+		s.circuit.failure()
+	}
+	return resp, err
+}
+
+// spill writes a failed batch to the spool, unless it was rejected as
+// malformed.
+func (s *httpSink) spill(lines [][]byte, err error) int {
+	if !retryable(err) && !errors.Is(err, ErrCircuitOpen) && !errors.Is(err, errSpoolBacklog) {
+		return 0
+	}
+	n := s.spool.write(lines)
+	s.signalReplay()
+	return n
+}
+
+// signalReplay wakes the replay goroutine without blocking.
+func (s *httpSink) signalReplay() {
+	if s.spool == nil {
+		return
+	}
+	select {
+	case s.replayWake <- struct{}{}:
+	default:
+	}
+}
+
+// replay is the goroutine that sends spooled batches, oldest first, whenever
+// a batch was spooled and at least every cooldown, which probes an open
+// circuit.
+func (s *httpSink) replay() {
+	defer close(s.replayDone)
+	ticker := time.NewTicker(s.circuit.cooldown)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.replayWake:
+		case <-ticker.C:
+		case <-s.stop:
+			return
+		}
+		for s.replayOne() {
+		}
+	}
+}
+
+// replayOne sends the oldest spooled batch and reports whether the next one
+// should follow. A batch rejected as malformed is discarded.
+func (s *httpSink) replayOne() bool {
+	f, body, ok := s.spool.oldest()
+	if !ok || !s.circuit.allow() {
+		return false
+	}
+	_, err := s.post(context.Background(), body)
+	if err != nil && retryable(err) {
+		return false
+	}
+	s.spool.remove(f, err == nil)
+	return true
+}
+
+// circuitBreaker stops requests to an endpoint after repeated failures.
+type circuitBreaker struct {
+	failures int
+	cooldown time.Duration
+	now      func() time.Time
+
+	mu          sync.Mutex
+	consecutive int
+	open        bool
+	openUntil   time.Time // when the next probe is allowed
+	trips       uint64
+}
+
+func newCircuitBreaker(opts CircuitOptions) *circuitBreaker {
+	if opts.Failures <= 0 {
+		opts.Failures = DefaultCircuitFailures
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = DefaultCircuitCooldown
+	}
+	return &circuitBreaker{failures: opts.Failures, cooldown: opts.Cooldown, now: time.Now}
+}
+
+// allow reports whether a request may be sent. Once the cooldown of an open
+// circuit is over, it allows one probe per cooldown.
+func (c *circuitBreaker) allow() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.open {
+		return true
+	}
+	if now := c.now(); !now.Before(c.openUntil) {
+		c.openUntil = now.Add(c.cooldown)
+		return true
+	}
+	return false
+}
+
+func (c *circuitBreaker) success() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consecutive = 0
+	c.open = false
+}
+
+func (c *circuitBreaker) failure() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.consecutive++
+	if !c.open && c.consecutive >= c.failures {
+		c.open = true
+		c.openUntil = c.now().Add(c.cooldown)
+		c.trips++
+	}
+}
+
+func (c *circuitBreaker) state() (open bool, trips uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.open, c.trips
+}
+
+// diskSpool keeps batches of NDJSON lines in files named after the time they
+// were written, so that they sort oldest first.
+type diskSpool struct {
+	dir      string
+	maxBytes int64
+
+	mu                         sync.Mutex
+	files                      []spoolFile // oldest first
+	size                       int64
+	seq                        uint64
+	spooled, replayed, dropped uint64
+}
+
+type spoolFile struct {
+	name    string
+	records int
+	size    int64
+}
+
+// openSpool creates dir if needed and picks up the batches already in it.
+func openSpool(dir string, maxBytes int64) (*diskSpool, error) {
+	if maxBytes <= 0 {
+		maxBytes = DefaultSpoolMaxBytes
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("logger: spool: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("logger: spool: %w", err)
+	}
+	s := &diskSpool{dir: dir, maxBytes: maxBytes}
+	for _, e := range entries {
+		if !e.Type().IsRegular() || !strings.HasSuffix(e.Name(), spoolExt) {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("logger: spool: %w", err)
+		}
+		s.files = append(s.files, spoolFile{name: e.Name(), records: bytes.Count(data, []byte{'\n'}), size: int64(len(data))})
+		s.size += int64(len(data))
+	}
+	slices.SortFunc(s.files, func(a, b spoolFile) int { return strings.Compare(a.name, b.name) })
+	return s, nil
+}
+
+// write stores a batch and returns the number of lines kept: all of them, or
+// none if the batch does not fit or cannot be written.
+func (s *diskSpool) write(lines [][]byte) int {
+	body := bytes.Join(lines, nil)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.size+int64(len(body)) > s.maxBytes {
+		s.dropped += uint64(len(lines))
+		return 0
+	}
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq%1e6, spoolExt)
+	tmp := filepath.Join(s.dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, body, 0o600); err != nil {
+		os.Remove(tmp)
+		s.dropped += uint64(len(lines))
+		return 0
+	}
+	if err := os.Rename(tmp, filepath.Join(s.dir, name)); err != nil {
+		os.Remove(tmp)
+		s.dropped += uint64(len(lines))
+		return 0
+	}
+	s.files = append(s.files, spoolFile{name: name, records: len(lines), size: int64(len(body))})
+	s.size += int64(len(body))
+	s.spooled += uint64(len(lines))
+	return len(lines)
+}
+
+// pending returns the number of spooled records.
+func (s *diskSpool) pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, f := range s.files {
+		n += f.records
+	}
+	return n
+}
+
+// oldest returns the oldest batch. Files that cannot be read are discarded.
+func (s *diskSpool) oldest() (spoolFile, []byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.files) > 0 {
+		f := s.files[0]
+		data, err := os.ReadFile(filepath.Join(s.dir, f.name))
+		if err == nil {
+			return f, data, true
+		}
+		s.removeLocked(f)
+		s.dropped += uint64(f.records)
+	}
+	return spoolFile{}, nil, false
+}
+
+// remove deletes a batch that was delivered, or rejected for good.
+func (s *diskSpool) remove(f spoolFile, delivered bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.removeLocked(f)
+	if delivered {
+		s.replayed += uint64(f.records)
+	} else {
+		s.dropped += uint64(f.records)
+	}
+}
+
+func (s *diskSpool) removeLocked(f spoolFile) {
+	os.Remove(filepath.Join(s.dir, f.name))
+	if i := slices.IndexFunc(s.files, func(g spoolFile) bool { return g.name == f.name }); i >= 0 {
+		s.files = slices.Delete(s.files, i, i+1)
+		s.size -= f.size
+	}
+}
@@ -0,0 +1,300 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// ndjsonServer is a stand-in for a collector that accepts NDJSON. While down
+// is set it fails every request with 503.
+type ndjsonServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	lines    []string
+	requests int
+	down     bool
+}
+
+func newNDJSONServer(t *testing.T) *ndjsonServer {
+	s := &ndjsonServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ingest" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		if s.down {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		sc := bufio.NewScanner(r.Body)
+		for sc.Scan() {
+			s.lines = append(s.lines, sc.Text())
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *ndjsonServer) setDown(down bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.down = down
+}
+
+func (s *ndjsonServer) received() (lines []string, requests int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.lines...), s.requests
+}
+
+// waitFor polls cond until it holds or a few seconds have passed.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+}
+
+func TestHTTPHandlerNDJSON(t *testing.T) {
+	srv := newNDJSONServer(t)
+	h, err := NewHTTPHandler(srv.URL+"/ingest", &HTTPOptions{
+		HandlerOptions: slog.HandlerOptions{ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				return slog.Attr{}
+			}
+			return a
+		}},
+		Batch: BatchOptions{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	logger := slog.New(h).With("service", "billing").WithGroup("req")
+	logger.Info("paid", "amount", 42)
+	logger.Warn("slow", "took", time.Second)
+	if err := h.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	lines, requests := srv.received()
+	want := []string{
+		`{"level":"INFO","msg":"paid","service":"billing","req":{"amount":42}}`,
+		`{"level":"WARN","msg":"slow","service":"billing","req":{"took":1000000000}}`,
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") || requests != 1 {
+		t.Errorf("got %d requests with lines\n%s\nwant\n%s", requests, strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+	if st := h.Stats(); st.Sent != 2 || st.Spooled != 0 || st.CircuitOpen {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestHTTPHandlerSpool(t *testing.T) {
+	srv := newNDJSONServer(t)
+	srv.setDown(true)
+	dir := t.TempDir()
+	h, err := NewHTTPHandler(srv.URL+"/ingest", &HTTPOptions{
+		Batch:    BatchOptions{Interval: time.Hour, Retry: fastRetry},
+		Circuit:  CircuitOptions{Failures: 2, Cooldown: 20 * time.Millisecond},
+		SpoolDir: dir,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	logger := slog.New(h)
+	logger.Info("one")
+	h.Flush(t.Context())
+	if st := h.Stats(); st.Spooled != 1 || st.SpoolPending != 1 || !st.CircuitOpen || st.CircuitTrips != 1 || st.Failed != 0 {
+		t.Errorf("after the endpoint went down: stats = %+v", st)
+	}
+	logger.Info("two")
+	h.Flush(t.Context())
+	if st := h.Stats(); st.Spooled != 2 || st.SpoolPending != 2 {
+		t.Errorf("with the circuit open: stats = %+v", st)
+	}
+
+	srv.setDown(false)
+	waitFor(t, "the spool to be replayed", func() bool { return h.Stats().Replayed == 2 })
+	logger.Info("three")
+	h.Flush(t.Context())
+	lines, _ := srv.received()
+	if len(lines) != 3 || !strings.Contains(lines[0], `"one"`) || !strings.Contains(lines[1], `"two"`) || !strings.Contains(lines[2], `"three"`) {
+		t.Errorf("lines = %q", lines)
+	}
+	if st := h.Stats(); st.SpoolPending != 0 || st.CircuitOpen || st.Sent != 1 {
+		t.Errorf("after recovery: stats = %+v", st)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("spool directory holds %d files", len(entries))
+	}
+}
+
+func TestHTTPHandlerSpoolRestart(t *testing.T) {
+	dir := t.TempDir()
+	spool, err := openSpool(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spool.write([][]byte{[]byte(`{"msg":"left over"}` + "\n"), []byte(`{"msg":"from before"}` + "\n")})
+
+	srv := newNDJSONServer(t)
+	h, err := NewHTTPHandler(srv.URL+"/ingest", &HTTPOptions{SpoolDir: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	waitFor(t, "the spool to be replayed", func() bool { return h.Stats().Replayed == 2 })
+	if lines, _ := srv.received(); strings.Join(lines, ",") != `{"msg":"left over"},{"msg":"from before"}` {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestHTTPHandlerSpoolFull(t *testing.T) {
+	srv := newNDJSONServer(t)
+	srv.setDown(true)
+	var reported []error
+	h, err := NewHTTPHandler(srv.URL+"/ingest", &HTTPOptions{
+		Batch: BatchOptions{MaxRecords: 1, Interval: time.Hour, Retry: RetryPolicy{MaxAttempts: 1}, OnError: func(err error) {
+			reported = append(reported, err)
+		}},
+		SpoolDir:      t.TempDir(),
+		SpoolMaxBytes: 150,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(h)
+	logger.Info("fits", "pad", strings.Repeat("x", 20))
+	logger.Info("does not fit", "pad", strings.Repeat("x", 100))
+	h.Close()
+	if st := h.Stats(); st.Spooled != 1 || st.SpoolDropped != 1 || st.Failed != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+	if len(reported) != 1 || !errors.Is(reported[0], errSpoolBacklog) {
+		t.Errorf("reported = %v", reported)
+	}
+}
+
+func TestHTTPHandlerCircuitWithoutSpool(t *testing.T) {
+	srv := newNDJSONServer(t)
+	srv.setDown(true)
+	var reported []error
+	h, err := NewHTTPHandler(srv.URL+"/ingest", &HTTPOptions{
+		Batch: BatchOptions{Interval: time.Hour, Retry: fastRetry, OnError: func(err error) {
+			reported = append(reported, err)
+		}},
+		Circuit: CircuitOptions{Failures: 3, Cooldown: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	slog.New(h).Info("one")
+	h.Flush(t.Context())
+	slog.New(h).Info("two")
+	h.Flush(t.Context())
+	if _, requests := srv.received(); requests != 3 {
+		t.Errorf("got %d requests, want 3", requests)
+	}
+	if st := h.Stats(); st.Failed != 2 || !st.CircuitOpen {
+		t.Errorf("stats = %+v", st)
+	}
+	if len(reported) != 2 || !errors.Is(reported[1], ErrCircuitOpen) {
+		t.Errorf("reported = %v", reported)
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	clock := &fakeClock{t: testTime}
+	c := newCircuitBreaker(CircuitOptions{Failures: 2, Cooldown: time.Minute})
+	c.now = clock.now
+	c.failure()
+	if !c.allow() {
+		t.Fatal("circuit opened after one failure")
+	}
+	c.failure()
+	if c.allow() {
+		t.Fatal("circuit closed after two failures")
+	}
+	clock.advance(time.Minute)
+	if !c.allow() || c.allow() {
+		t.Fatal("want exactly one probe after the cooldown")
+	}
+	c.failure() // the probe failed
+	clock.advance(time.Minute - time.Second)
+	if c.allow() {
+		t.Fatal("circuit closed before another cooldown")
+	}
+	clock.advance(time.Second)
+	if !c.allow() {
+		t.Fatal("no probe after another cooldown")
+	}
+	c.success()
+	if open, trips := c.state(); open || trips != 1 || !c.allow() {
+		t.Errorf("after a successful probe: open = %v, trips = %d", open, trips)
+	}
+}
+
+func TestHTTPHandlerType(t *testing.T) {
+	if _, err := NewSlogConfig(WithHandlerType("http")).BuildLogger(); err == nil {
+		t.Error("BuildLogger succeeded without a destination")
+	}
+	srv := newNDJSONServer(t)
+	logger, err := NewSlogConfig(WithHandlerType("http"), WithDestination(srv.URL+"/ingest")).BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	logger.Info("hello")
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if lines, _ := srv.received(); len(lines) != 1 || !strings.Contains(lines[0], `"msg":"hello"`) {
+		t.Errorf("lines = %q", lines)
+	}
+}
+
+func TestHTTPHandlerTypeSpool(t *testing.T) {
+	srv := newNDJSONServer(t)
+	srv.setDown(true)
+	dir := t.TempDir()
+	logger, err := NewSlogConfig(WithHandlerType("http"), WithDestination(srv.URL+"/ingest"), WithHTTPOptions(HTTPOptions{
+		Batch:    BatchOptions{Interval: time.Hour, Retry: fastRetry},
+		Circuit:  CircuitOptions{Failures: 1, Cooldown: time.Hour},
+		SpoolDir: dir,
+	})).BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	defer CloserOf(logger).Close()
+	logger.Info("held back")
+	if err := CloserOf(logger).Sync(); err != nil {
+		t.Fatal(err)
+	}
+	h, ok := findHandler[*HTTPHandler](logger.Handler())
+	if !ok {
+		t.Fatal("no HTTPHandler in the chain")
+	}
+	if st := h.Stats(); st.Spooled != 1 || st.SpoolPending != 1 || !st.CircuitOpen {
+		t.Errorf("stats = %+v", st)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("spool directory holds %d files, want 1", len(entries))
+	}
+}
@@ -1,8 +1,15 @@
 package config
 
+import (
+	"github.com/Galdoba/gslog"
+)
+
 type Config struct {
+	Log gslog.SlogConfig
 }
 
 func Initialize() Config {
-	return Config{}
+	return Config{
+		Log: gslog.NewSlogConfig(gslog.WithEnv("")),
+	}
 }
@@ -2,6 +2,7 @@ package commands
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -89,15 +90,27 @@ func readAction(cfg config.Config) cli.ActionFunc {
 		}
 		entries := []string{}
 
-		for _, file := range files {
-			f, _ := os.Open(file)
+		for _, file := range gslog.OrderLogFiles(files) {
+			f, err := gslog.OpenLogFile(file)
+			if err != nil {
+				gslog.Warn("open log file", "file", file, "error", err)
+				continue
+			}
 			localEntries := gslog.ExtractStructures(f, nil)
+			f.Close()
 			fmt.Println(len(localEntries))
 			entries = append(entries, localEntries...)
 
 		}
 
-		fmt.Println("config:", cfg)
+		if err := cfg.Log.Validate(); err != nil {
+			return fmt.Errorf("invalid logger config: %w", err)
+		}
+		logCfg, err := json.MarshalIndent(cfg.Log, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode logger config: %w", err)
+		}
+		fmt.Println("config:", string(logCfg))
 		for _, e := range entries {
 			fmt.Println(e)
 		}
@@ -0,0 +1,228 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultJournaldDestination is the destination of the "journald" handler
+// type when SlogConfig.Destination is empty: the socket of systemd-journald.
+const DefaultJournaldDestination = "unixgram:///run/systemd/journal/socket"
+
+// JournaldOptions configure a JournaldHandler.
+type JournaldOptions struct {
+	slog.HandlerOptions
+
+	// Identifier is written as SYSLOG_IDENTIFIER. It defaults to the program
+	// name; journalctl -t selects records by it.
+	Identifier string
+}
+
+// JournaldHandler sends records to systemd-journald over its native
+// protocol, so that attributes arrive as journal fields instead of text:
+//
+//	MESSAGE=user logged in
+//	PRIORITY=6
+//	SYSLOG_IDENTIFIER=app
+//	CODE_FILE=/src/app/main.go
+//	CODE_LINE=42
+//	CODE_FUNC=main.main
+//	USER=bob
+//	SESSION_USER_ID=7
+//
+// Attribute keys are uppercased, with groups joined by '_' and characters
+// other than A-Z, 0-9 and '_' replaced by '_'. Keys starting with a digit or
+// '_' (reserved for fields set by journald itself) are prefixed with "X".
+// Levels map to PRIORITY as for SyslogHandler, and CODE_FILE, CODE_LINE and
+// CODE_FUNC are written when AddSource is set. Values may contain newlines
+// and arbitrary bytes.
+//
+// Records too large for a datagram are written to a temporary file whose
+// descriptor is passed to journald instead, as journald's own client does.
+//
+// It is registered as the "journald" handler type, which reads the socket
+// from SlogConfig.Destination (see WithDestination).
+type JournaldHandler struct {
+	conn       *journalConn
+	identifier string
+	level      slog.Leveler
+	addSource  bool
+	scope      attrScope
+}
+
+// NewJournaldHandler returns a JournaldHandler that sends records to the
+// journal socket at dest, such as DefaultJournaldDestination. A nil opts uses
+// the defaults. It fails only if dest is malformed; socket errors are
+// reported by Handle.
+func NewJournaldHandler(dest string, opts *JournaldOptions) (*JournaldHandler, error) {
+	_, path, err := parseDestination(dest, "unixgram")
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &JournaldOptions{}
+	}
+	h := &JournaldHandler{
+		conn:       &journalConn{addr: &net.UnixAddr{Name: path, Net: "unixgram"}},
+		identifier: opts.Identifier,
+		level:      opts.Level,
+		addSource:  opts.AddSource,
+		scope:      attrScope{replace: opts.ReplaceAttr},
+	}
+	if h.identifier == "" {
+		h.identifier = filepath.Base(os.Args[0])
+	}
+	if h.level == nil {
+		h.level = slog.LevelInfo
+	}
+	return h, nil
+}
+
+func (h *JournaldHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *JournaldHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope.withAttrs(attrs)
+	return &h2
+}
+
+func (h *JournaldHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.scope = h.scope.withGroup(name)
+	return &h2
+}
+
+func (h *JournaldHandler) Handle(_ context.Context, r slog.Record) error {
+	return h.conn.send(h.format(r))
+}
+
+// Close closes the socket. Handlers derived with WithAttrs and WithGroup
+// share it.
+func (h *JournaldHandler) Close() error {
+	return h.conn.Close()
+}
+
+// format returns the datagram for r.
+func (h *JournaldHandler) format(r slog.Record) []byte {
+	var buf bytes.Buffer
+	message := ""
+	if a, ok := h.scope.builtin(slog.String(slog.MessageKey, r.Message)); ok {
+		message = syslogValue(a.Value)
+	}
+	writeJournalField(&buf, "MESSAGE", message)
+	writeJournalField(&buf, "PRIORITY", strconv.Itoa(syslogSeverity(r.Level)))
+	writeJournalField(&buf, "SYSLOG_IDENTIFIER", h.identifier)
+	if h.addSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		writeJournalField(&buf, "CODE_FILE", frame.File)
+		writeJournalField(&buf, "CODE_LINE", strconv.Itoa(frame.Line))
+		writeJournalField(&buf, "CODE_FUNC", frame.Function)
+	}
+	h.scope.each(r, func(groups []string, a slog.Attr) {
+		name := journalFieldName(append(groups[:len(groups):len(groups)], a.Key))
+		writeJournalField(&buf, name, syslogValue(a.Value))
+	})
+	return buf.Bytes()
+}
+
+// writeJournalField writes a field in the native protocol: "NAME=value\n",
+// or, for values containing a newline, the name, a newline, the value length
+// as a little-endian uint64, the value and a newline.
+func writeJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+		return
+	}
+	buf.WriteByte('\n')
+	buf.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(value))))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
+
+// journalFieldName returns a valid journal field name for a group path:
+// uppercase letters, digits and '_', not starting with a digit or '_', at
+// most 64 bytes.
+func journalFieldName(path []string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, strings.Join(path, "_"))
+	if name == "" || name[0] == '_' || (name[0] >= '0' && name[0] <= '9') {
+		name = "X" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
+
+// journalConn is an unbound datagram socket that sends to the journal
+// socket. Since every datagram is addressed to the socket path, a restarted
+// journald is reached without reconnecting. It is safe for concurrent use.
+type journalConn struct {
+	addr *net.UnixAddr
+
+	mu     sync.Mutex
+	conn   *net.UnixConn
+	closed bool
+}
+
+// send writes payload as one datagram, falling back to passing a file
+// descriptor if the payload is too large for the socket.
+func (c *journalConn) send(payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	if c.conn == nil {
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Net: "unixgram"})
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+	}
+	_, _, err := c.conn.WriteMsgUnix(payload, nil, c.addr)
+	if isTooLarge(err) {
+		err = c.sendFile(payload)
+	}
+	return err
+}
+
+// Close closes the socket. Later sends fail with net.ErrClosed.
+func (c *journalConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package logger
+
+import "errors"
+
+// isTooLarge reports false: without descriptor passing there is no fallback
+// for datagrams that are too large.
+func isTooLarge(err error) bool {
+	return false
+}
+
+// sendFile reports that descriptor passing is unavailable on this platform.
+func (c *journalConn) sendFile(payload []byte) error {
+	return errors.New("logger: journald record too large for a datagram")
+}
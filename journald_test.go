@@ -0,0 +1,189 @@
+//go:build unix
+
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// listenJournal starts a stand-in journal socket and returns its destination
+// and a function that waits for the next record and parses its fields,
+// reading the record from a passed descriptor if there is one.
+func listenJournal(t *testing.T) (string, func() map[string]string) {
+	t.Helper()
+	path := shortSocketPath(t, "journal")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return "unixgram://" + path, func() map[string]string {
+		t.Helper()
+		buf := make([]byte, 1<<16)
+		oob := make([]byte, syscall.CmsgSpace(4))
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, oobn, _, _, err := conn.ReadMsgUnix(buf, oob)
+		if err != nil {
+			t.Fatalf("read datagram: %v", err)
+		}
+		data := buf[:n]
+		if oobn > 0 {
+			data = readPassedFile(t, oob[:oobn])
+		}
+		fields, err := parseJournalFields(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fields
+	}
+}
+
+func readPassedFile(t *testing.T, oob []byte) []byte {
+	t.Helper()
+	msgs, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("control messages: %v, %v", msgs, err)
+	}
+	fds, err := syscall.ParseUnixRights(&msgs[0])
+	if err != nil || len(fds) != 1 {
+		t.Fatalf("passed descriptors: %v, %v", fds, err)
+	}
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// parseJournalFields decodes the native protocol.
+func parseJournalFields(data []byte) (map[string]string, error) {
+	fields := map[string]string{}
+	for len(data) > 0 {
+		line, rest, ok := bytes.Cut(data, []byte("\n"))
+		if !ok {
+			return nil, io.ErrUnexpectedEOF
+		}
+		if name, value, ok := bytes.Cut(line, []byte("=")); ok {
+			fields[string(name)] = string(value)
+			data = rest
+			continue
+		}
+		if len(rest) < 8 {
+			return nil, io.ErrUnexpectedEOF
+		}
+		n := binary.LittleEndian.Uint64(rest)
+		rest = rest[8:]
+		if uint64(len(rest)) < n+1 || rest[n] != '\n' {
+			return nil, io.ErrUnexpectedEOF
+		}
+		fields[string(line)] = string(rest[:n])
+		data = rest[n+1:]
+	}
+	return fields, nil
+}
+
+func TestJournaldHandlerFields(t *testing.T) {
+	dest, recv := listenJournal(t)
+	h, err := NewJournaldHandler(dest, &JournaldOptions{
+		HandlerOptions: slog.HandlerOptions{AddSource: true},
+		Identifier:     "app",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	logger := slog.New(h).With("service", "billing").WithGroup("Session")
+	logger.Warn("quota exceeded", "User.ID", 7, "_uid", 0, "stack", "a\nb")
+
+	got := recv()
+	want := map[string]string{
+		"MESSAGE":           "quota exceeded",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "app",
+		"SERVICE":           "billing",
+		"SESSION_USER_ID":   "7",
+		"SESSION__UID":      "0",
+		"SESSION_STACK":     "a\nb",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if !strings.HasSuffix(got["CODE_FILE"], "journald_test.go") || got["CODE_FUNC"] == "" {
+		t.Errorf("CODE_FILE, CODE_FUNC = %q, %q", got["CODE_FILE"], got["CODE_FUNC"])
+	}
+	if _, err := strconv.Atoi(got["CODE_LINE"]); err != nil {
+		t.Errorf("CODE_LINE = %q", got["CODE_LINE"])
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	for _, tt := range []struct {
+		path []string
+		want string
+	}{
+		{[]string{"user"}, "USER"},
+		{[]string{"http", "status-code"}, "HTTP_STATUS_CODE"},
+		{[]string{"_SYSTEMD_UNIT"}, "X_SYSTEMD_UNIT"},
+		{[]string{"2fa"}, "X2FA"},
+		{[]string{"ключ"}, "X____"},
+		{[]string{strings.Repeat("a", 70)}, strings.Repeat("A", 64)},
+	} {
+		if got := journalFieldName(tt.path); got != tt.want {
+			t.Errorf("journalFieldName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestJournaldHandlerLargeRecord(t *testing.T) {
+	dest, recv := listenJournal(t)
+	h, err := NewJournaldHandler(dest, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	big := strings.Repeat("x", 4<<20)
+	slog.New(h).Info("dump", "payload", big)
+	got := recv()
+	if got["MESSAGE"] != "dump" || got["PAYLOAD"] != big {
+		t.Errorf("MESSAGE = %q, len(PAYLOAD) = %d", got["MESSAGE"], len(got["PAYLOAD"]))
+	}
+}
+
+func TestJournaldHandlerType(t *testing.T) {
+	dest, recv := listenJournal(t)
+	logger, err := NewSlogConfig(WithHandlerType("journald"), WithDestination(dest)).BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	logger.Error("failed")
+	if got := recv(); got["MESSAGE"] != "failed" || got["PRIORITY"] != "3" {
+		t.Errorf("fields = %v", got)
+	}
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if err := logger.Handler().Handle(context.Background(), slog.NewRecord(testTime, slog.LevelInfo, "late", 0)); err == nil {
+		t.Error("Handle after Close succeeded")
+	}
+	if _, err := NewJournaldHandler("udp://localhost:514", nil); err == nil {
+		t.Error("NewJournaldHandler accepted a udp destination")
+	}
+}
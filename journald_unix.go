@@ -0,0 +1,37 @@
+//go:build unix
+
+package logger
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// isTooLarge reports whether err means that a datagram was too large to send.
+func isTooLarge(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS)
+}
+
+// sendFile writes payload to an unlinked temporary file and passes its
+// descriptor to journald, which reads the record from it. /dev/shm is
+// preferred so that the data stays in memory.
+func (c *journalConn) sendFile(payload []byte) error {
+	dir := "/dev/shm"
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		dir = os.TempDir()
+	}
+	f, err := os.CreateTemp(dir, "gslog-journal-")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(payload); err != nil {
+		return err
+	}
+	_, _, err = c.conn.WriteMsgUnix(nil, syscall.UnixRights(int(f.Fd())), c.addr)
+	return err
+}
@@ -0,0 +1,262 @@
+package logger
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+)
+
+// Names of the standard configuration layers, lowest precedence first.
+const (
+	LayerDefaults = "defaults"
+	LayerFile     = "file"
+	LayerEnv      = "env"
+	LayerFlags    = "flags"
+)
+
+// Setting names reported in ConfigSources. They match the configuration file keys.
+const (
+	SettingHandler     = "handler"
+	SettingOutput      = "output"
+	SettingDestination = "destination"
+	SettingResource    = "resource"
+	SettingLevel       = "level"
+	SettingAddSource   = "add_source"
+	SettingRules       = "rules"
+	SettingSinks       = "sinks"
+	SettingNameLevels  = "name_levels"
+)
+
+// ConfigLayer is a named group of options applied on top of lower layers.
+type ConfigLayer struct {
+	Name    string
+	Options []ConfigOption
+}
+
+// ConfigSources maps each setting (SettingHandler, SettingLevel, ...) to the
+// name of the layer its effective value came from.
+type ConfigSources map[string]string
+
+// FileLayer returns a layer holding the settings of the configuration file at
+// path (see LoadSlogConfig). Only keys present in the file override lower
+// layers. If the file cannot be read, the error is reported by MergeConfig.
+func FileLayer(path string) ConfigLayer {
+	layer := ConfigLayer{Name: LayerFile}
+	opts, err := readConfigFileOptions(path)
+	if err != nil {
+		layer.Options = []ConfigOption{func(cfg *SlogConfig) { cfg.addErr(err) }}
+		return layer
+	}
+	layer.Options = opts
+	return layer
+}
+
+// EnvLayer returns a layer holding the environment overrides read by WithEnv.
+func EnvLayer(prefix string) ConfigLayer {
+	return ConfigLayer{Name: LayerEnv, Options: []ConfigOption{WithEnv(prefix)}}
+}
+
+// MergeConfig applies layers to base in order, so that later layers take
+// precedence over earlier ones, and returns the final config together with
+// the layer each setting came from. A layer is credited with every setting its
+// options set, even to the value it already had; options that do not record
+// what they set, such as hand-written ones, are credited with the settings
+// they change. Settings no layer set are attributed to LayerDefaults.
+//
+// Output files opened by a layer, such as the output of a configuration file
+// or of a repeated -log-output flag, are closed again if a later layer
+// replaces them, since no logger built from the result would ever use them.
+//
+// base is usually NewSlogConfig() or SlogConfigDefault(); it is cloned, not
+// modified. Errors recorded by the options of any layer are returned, prefixed
+// with the layer name, and are also kept on the returned config (see Err).
+//
+// A typical precedence chain is defaults < file < env < flags:
+//
+//	flags := BindFlags(flag.CommandLine)
+//	flag.Parse()
+//	cfg, sources, err := MergeConfig(NewSlogConfig(),
+//		FileLayer("log.yaml"), EnvLayer(""), flags.Layer())
+func MergeConfig(base SlogConfig, layers ...ConfigLayer) (SlogConfig, ConfigSources, error) {
+	cfg := base.Clone()
+	sources := ConfigSources{
+		SettingHandler:     LayerDefaults,
+		SettingOutput:      LayerDefaults,
+		SettingDestination: LayerDefaults,
+		SettingResource:    LayerDefaults,
+		SettingLevel:       LayerDefaults,
+		SettingAddSource:   LayerDefaults,
+		SettingRules:       LayerDefaults,
+		SettingSinks:       LayerDefaults,
+		SettingNameLevels:  LayerDefaults,
+	}
+	errs := cfg.err
+	for _, layer := range layers {
+		next := cfg.Clone()
+		next.err = nil
+		next.explicit = nil
+		for _, opt := range layer.Options {
+			opt(&next)
+		}
+		if len(layer.Options) > 0 {
+			next.touch() // hand-written options may set fields directly
+		}
+		if next.err != nil {
+			errs = errors.Join(errs, fmt.Errorf("%s layer: %w", layer.Name, next.err))
+		}
+		for _, setting := range next.explicit {
+			sources[setting] = layer.Name
+		}
+		for _, setting := range changedSettings(cfg, next) {
+			sources[setting] = layer.Name
+		}
+		cfg = next
+	}
+	cfg.err = errs
+	closeSuperseded(&cfg, base.owned)
+	return cfg, sources, errs
+}
+
+// closeSuperseded closes the outputs cfg owns but no longer uses, except
+// those in kept, and forgets them.
+func closeSuperseded(cfg *SlogConfig, kept []io.Writer) {
+	var owned []io.Writer
+	for _, w := range cfg.owned {
+		same := func(o io.Writer) bool { return sameResource(o, w) }
+		used := same(cfg.Output) || slices.ContainsFunc(cfg.Sinks, func(s Sink) bool { return same(s.Output) })
+		if used || slices.ContainsFunc(kept, same) {
+			owned = append(owned, w)
+			continue
+		}
+		if c, ok := w.(io.Closer); ok && w != os.Stdout && w != os.Stderr {
+			c.Close()
+		}
+	}
+	cfg.owned = owned
+}
+
+// changedSettings lists the settings whose values differ between a and b.
+func changedSettings(a, b SlogConfig) []string {
+	var changed []string
+	if a.HandlerType != b.HandlerType {
+		changed = append(changed, SettingHandler)
+	}
+	if !sameWriter(a.Output, b.Output) {
+		changed = append(changed, SettingOutput)
+	}
+	if a.Destination != b.Destination {
+		changed = append(changed, SettingDestination)
+	}
+	if !maps.Equal(a.Resource, b.Resource) {
+		changed = append(changed, SettingResource)
+	}
+	if !sameLevel(a.Level, b.Level) {
+		changed = append(changed, SettingLevel)
+	}
+	if addSource(a.HandlerOptions) != addSource(b.HandlerOptions) {
+		changed = append(changed, SettingAddSource)
+	}
+	if !slices.Equal(a.Rules, b.Rules) {
+		changed = append(changed, SettingRules)
+	}
+	if !slices.EqualFunc(a.Sinks, b.Sinks, sameSink) {
+		changed = append(changed, SettingSinks)
+	}
+	if a.NameLevels != b.NameLevels {
+		changed = append(changed, SettingNameLevels)
+	}
+	return changed
+}
+
+// sameWriter reports whether a and b are the same writer. Writers of
+// non-comparable types are assumed to be unchanged.
+func sameWriter(a, b io.Writer) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	ta, tb := reflect.TypeOf(a), reflect.TypeOf(b)
+	if ta != tb {
+		return false
+	}
+	if !ta.Comparable() {
+		return true
+	}
+	return a == b
+}
+
+// sameSink reports whether a and b describe the same sink.
+func sameSink(a, b Sink) bool {
+	return a.HandlerType == b.HandlerType && sameWriter(a.Output, b.Output) && sameLevel(a.Level, b.Level)
+}
+
+// sameLevel reports whether a and b currently resolve to the same level.
+func sameLevel(a, b slog.Leveler) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Level() == b.Level()
+}
+
+// addSource reports whether opts enable AddSource.
+func addSource(opts *slog.HandlerOptions) bool {
+	return opts != nil && opts.AddSource
+}
+
+// ConfigFlags holds the logger settings given on the command line.
+// Create it with BindFlags and turn it into a layer with Layer after parsing.
+type ConfigFlags struct {
+	opts []ConfigOption
+}
+
+// Command-line flags registered by BindFlags.
+const (
+	FlagLevel  = "log-level"
+	FlagFormat = "log-format"
+	FlagOutput = "log-output"
+)
+
+// BindFlags registers the -log-level, -log-format and -log-output flags on fs
+// (flag.CommandLine if fs is nil). Values are validated while parsing, so
+// invalid values are reported by fs.Parse. The last of repeated flags wins;
+// MergeConfig closes the files opened for earlier -log-output flags.
+func BindFlags(fs *flag.FlagSet) *ConfigFlags {
+	if fs == nil {
+		fs = flag.CommandLine
+	}
+	f := &ConfigFlags{}
+	fs.Func(FlagLevel, "minimum log level (debug, info, warn, error)", func(s string) error {
+		level, err := parseLevel(s)
+		if err != nil {
+			return err
+		}
+		f.opts = append(f.opts, WithLevel(level))
+		return nil
+	})
+	fs.Func(FlagFormat, "log handler type (json, text, ...)", func(s string) error {
+		if _, err := lookupHandlerType(s); err != nil {
+			return err
+		}
+		f.opts = append(f.opts, WithHandlerType(s))
+		return nil
+	})
+	fs.Func(FlagOutput, "log output: stderr, stdout or a file path", func(s string) error {
+		w, err := openOutput(s)
+		if err != nil {
+			return err
+		}
+		f.opts = append(f.opts, WithOutput(w), ownOutput(w))
+		return nil
+	})
+	return f
+}
+
+// Layer returns the flags that were set as a configuration layer.
+func (f *ConfigFlags) Layer() ConfigLayer {
+	return ConfigLayer{Name: LayerFlags, Options: slices.Clone(f.opts)}
+}
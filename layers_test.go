@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMergeConfigPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "log.yaml")
+	doc := "handler: text\nlevel: warn\nadd_source: true\n"
+	if err := os.WriteFile(cfgPath, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GSLOG_LEVEL", "error")
+	t.Setenv("GSLOG_FORMAT", "json")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := BindFlags(fs)
+	if err := fs.Parse([]string{"-log-format", "text", "-log-output", "stdout"}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+
+	cfg, sources, err := MergeConfig(NewSlogConfig(), FileLayer(cfgPath), EnvLayer(""), flags.Layer())
+	if err != nil {
+		t.Fatalf("MergeConfig: %v", err)
+	}
+	if cfg.HandlerType != "text" {
+		t.Errorf("HandlerType = %q, want text", cfg.HandlerType)
+	}
+	if cfg.Level != slog.LevelError {
+		t.Errorf("Level = %v, want ERROR", cfg.Level)
+	}
+	if cfg.Output != os.Stdout {
+		t.Errorf("Output = %v, want stdout", cfg.Output)
+	}
+	if !addSource(cfg.HandlerOptions) {
+		t.Error("AddSource not set from file")
+	}
+	want := ConfigSources{
+		SettingHandler:   LayerFlags,
+		SettingOutput:    LayerFlags,
+		SettingLevel:     LayerEnv,
+		SettingAddSource: LayerFile,
+		SettingRules:     LayerDefaults,
+	}
+	for setting, layer := range want {
+		if sources[setting] != layer {
+			t.Errorf("sources[%q] = %q, want %q", setting, sources[setting], layer)
+		}
+	}
+}
+
+func TestMergeConfigCreditsUnchangedValues(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "log.yaml")
+	if err := os.WriteFile(cfgPath, []byte("handler: json\nlevel: info\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	custom := ConfigLayer{Name: "custom", Options: []ConfigOption{WithAddSource(false)}}
+	_, sources, err := MergeConfig(NewSlogConfig(), FileLayer(cfgPath), custom)
+	if err != nil {
+		t.Fatalf("MergeConfig: %v", err)
+	}
+	want := ConfigSources{
+		SettingHandler:   LayerFile,
+		SettingLevel:     LayerFile,
+		SettingAddSource: "custom",
+		SettingOutput:    LayerDefaults,
+	}
+	for setting, layer := range want {
+		if sources[setting] != layer {
+			t.Errorf("sources[%q] = %q, want %q", setting, sources[setting], layer)
+		}
+	}
+}
+
+func TestMergeConfigDoesNotModifyBase(t *testing.T) {
+	base := SlogConfigDefault()
+	layer := ConfigLayer{Name: "custom", Options: []ConfigOption{WithHandlerType("json")}}
+	cfg, sources, err := MergeConfig(base, layer)
+	if err != nil {
+		t.Fatalf("MergeConfig: %v", err)
+	}
+	if base.HandlerType != "text" {
+		t.Errorf("base HandlerType = %q, want text", base.HandlerType)
+	}
+	if cfg.HandlerType != "json" || sources[SettingHandler] != "custom" {
+		t.Errorf("HandlerType = %q from %q, want json from custom", cfg.HandlerType, sources[SettingHandler])
+	}
+}
+
+func TestMergeConfigErrors(t *testing.T) {
+	t.Setenv("GSLOG_LEVEL", "loud")
+	cfg, _, err := MergeConfig(NewSlogConfig(), FileLayer(filepath.Join(t.TempDir(), "missing.yaml")), EnvLayer(""))
+	if err == nil {
+		t.Fatal("MergeConfig error = nil, want file and env errors")
+	}
+	if cfg.Err() == nil {
+		t.Error("returned config does not carry the error")
+	}
+}
+
+func TestBindFlagsInvalidValues(t *testing.T) {
+	for _, args := range [][]string{
+		{"-log-level", "loud"},
+		{"-log-format", "jsn"},
+	} {
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		BindFlags(fs)
+		if err := fs.Parse(args); err == nil {
+			t.Errorf("Parse(%v) succeeded, want error", args)
+		}
+	}
+}
+
+func TestBindFlagsUnsetLeavesConfig(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := BindFlags(fs)
+	if err := fs.Parse(nil); err != nil {
+		t.Fatal(err)
+	}
+	cfg, sources, err := MergeConfig(NewSlogConfig(WithLevel(slog.LevelWarn)), flags.Layer())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Level != slog.LevelWarn || sources[SettingLevel] != LayerDefaults {
+		t.Errorf("Level = %v from %q, want WARN from defaults", cfg.Level, sources[SettingLevel])
+	}
+}
+
+func TestMergeConfigClosesSupersededOutputs(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "log.yaml")
+	doc := "output: " + filepath.Join(dir, "file.log") + "\n"
+	if err := os.WriteFile(cfgPath, []byte(doc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := BindFlags(fs)
+	if err := fs.Parse([]string{"-log-output", filepath.Join(dir, "first.log"), "-log-output", filepath.Join(dir, "last.log")}); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	fileLayer, flagLayer := FileLayer(cfgPath), flags.Layer()
+	var outputs []io.Writer
+	for _, opt := range append(fileLayer.Options, flagLayer.Options...) {
+		var c SlogConfig
+		if opt(&c); c.Output != nil {
+			outputs = append(outputs, c.Output)
+		}
+	}
+	if len(outputs) != 3 {
+		t.Fatalf("got %d outputs from the layers, want 3", len(outputs))
+	}
+
+	base := NewSlogConfig(WithFileOutput(filepath.Join(dir, "base.log"), RotationPolicy{}))
+	defer base.Shutdown(context.Background())
+	cfg, _, err := MergeConfig(base, fileLayer, flagLayer)
+	if err != nil {
+		t.Fatalf("MergeConfig: %v", err)
+	}
+	defer cfg.Shutdown(context.Background())
+	for _, w := range outputs[:2] {
+		if _, err := w.Write([]byte("x\n")); !errors.Is(err, os.ErrClosed) {
+			t.Errorf("write to superseded %v: err = %v, want os.ErrClosed", w.(*os.File).Name(), err)
+		}
+	}
+	if cfg.Output != outputs[2] || !cfg.owns(cfg.Output) {
+		t.Errorf("Output = %v, want the last -log-output, owned", cfg.Output)
+	}
+	if len(cfg.owned) != 2 {
+		t.Errorf("config owns %d outputs, want the base file and the last flag", len(cfg.owned))
+	}
+	if _, err := base.Output.Write([]byte("x\n")); err != nil {
+		t.Errorf("write to the base output: %v", err)
+	}
+}
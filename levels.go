@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+// WithLevelVar returns a ConfigOption that uses lv as the minimum logging level.
+// Every logger built from the config shares lv, so calling lv.Set changes
+// their level at runtime.
+func WithLevelVar(lv *slog.LevelVar) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.Level = lv
+		cfg.markSet(SettingLevel)
+	}
+}
+
+// LevelVarOf returns the variable controlling the level of a logger built
+// from a SlogConfig, or nil if the logger's level cannot be changed at runtime
+// (for example, it uses a CustomHandler or a custom slog.Leveler). With
+// sinks, it controls the sinks that have no level of their own, and is nil
+// if every sink has one.
+// Loggers derived with With or WithGroup share the variable of their parent,
+// and loggers built from the same config share one (see BuildHandler).
+func LevelVarOf(l *slog.Logger) *slog.LevelVar {
+	if l == nil {
+		return nil
+	}
+	return handlerLevelVar(l.Handler())
+}
+
+// LevelVar returns the variable controlling the level of the logger, or nil if
+// its level cannot be changed at runtime. Loggers derived with With, WithGroup,
+// WithContextValue or UpdateState share the variable of their parent.
+func (l *Stateful[T]) LevelVar() *slog.LevelVar {
+	return handlerLevelVar(l.logger.Handler())
+}
+
+// handlerLevelVar returns the level variable of the first levelHandler or
+// MultiHandler in the chain.
+func handlerLevelVar(h slog.Handler) *slog.LevelVar {
+	var lv *slog.LevelVar
+	walkHandlers(h, func(h slog.Handler) bool {
+		switch h := h.(type) {
+		case *levelHandler:
+			lv = h.level
+		case *MultiHandler:
+			lv = h.level
+		default:
+			return true
+		}
+		return false
+	})
+	return lv
+}
+
+// withLevelVar returns a copy of a resolved config whose HandlerOptions.Level
+// is a *slog.LevelVar, along with that variable. A fixed slog.Level is turned
+// into a new variable; custom Levelers are left alone and yield a nil variable.
+func (c SlogConfig) withLevelVar() (SlogConfig, *slog.LevelVar) {
+	switch level := c.HandlerOptions.Level.(type) {
+	case *slog.LevelVar:
+		return c, level
+	case slog.Level:
+		lv := new(slog.LevelVar)
+		lv.Set(level)
+		opts := *c.HandlerOptions
+		opts.Level = lv
+		c.HandlerOptions = &opts
+		return c, lv
+	}
+	return c, nil
+}
+
+// wrapLevelHandler wraps h in a levelHandler controlled by lv.
+// If lv is nil, h is returned unchanged.
+func wrapLevelHandler(h slog.Handler, lv *slog.LevelVar) slog.Handler {
+	if lv == nil {
+		return h
+	}
+	return &levelHandler{next: h, level: lv}
+}
+
+// levelHandler enforces a runtime-adjustable level on top of the handler
+// built from a config, even if the handler factory ignored HandlerOptions.Level,
+// and makes the level variable discoverable through the handler chain.
+type levelHandler struct {
+	next  slog.Handler
+	level *slog.LevelVar
+}
+
+func (h *levelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.level.Level() && h.next.Enabled(ctx, level)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &levelHandler{next: h.next.WithAttrs(attrs), level: h.level}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{next: h.next.WithGroup(name), level: h.level}
+}
+
+func (h *levelHandler) Unwrap() slog.Handler {
+	return h.next
+}
@@ -0,0 +1,110 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLevelVarOfNewLogger(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithHandlerType("text"), WithOutput(&buf))
+	logger := cfg.NewLogger()
+	derived := logger.With("k", "v").WithGroup("g")
+
+	derived.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("debug record written at Info level: %q", buf.String())
+	}
+	lv := LevelVarOf(logger)
+	if lv == nil {
+		t.Fatal("LevelVarOf returned nil")
+	}
+	if lv.Level() != slog.LevelInfo {
+		t.Errorf("initial level = %v, want INFO", lv.Level())
+	}
+	lv.Set(slog.LevelDebug)
+	derived.Debug("shown")
+	if !strings.Contains(buf.String(), "shown") {
+		t.Errorf("derived logger did not follow level change: %q", buf.String())
+	}
+	lv.Set(slog.LevelInfo)
+	buf.Reset()
+	logger.Debug("hidden again")
+	if buf.Len() != 0 {
+		t.Errorf("level not restored: %q", buf.String())
+	}
+}
+
+func TestLevelVarOfSharedChain(t *testing.T) {
+	cfg := NewSlogConfig()
+	a, b := LevelVarOf(cfg.NewLogger()), LevelVarOf(cfg.Named("b").NewLogger())
+	if a != b {
+		t.Error("loggers built from one config have different LevelVars")
+	}
+	debug := cfg.Clone()
+	WithLevel(slog.LevelDebug)(&debug)
+	if c := LevelVarOf(debug.NewLogger()); c == a || c.Level() != slog.LevelDebug {
+		t.Errorf("logger of a config with another level shares the LevelVar or has level %v", c.Level())
+	}
+}
+
+func TestWithLevelVar(t *testing.T) {
+	var buf bytes.Buffer
+	lv := new(slog.LevelVar)
+	lv.Set(slog.LevelWarn)
+	cfg := NewSlogConfig(WithHandlerType("text"), WithOutput(&buf), WithLevelVar(lv))
+	a, b := cfg.NewLogger(), cfg.NewLogger()
+	if LevelVarOf(a) != lv || LevelVarOf(b) != lv {
+		t.Fatal("loggers do not expose the configured LevelVar")
+	}
+	a.Info("hidden")
+	lv.Set(slog.LevelInfo)
+	b.Info("shown")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestLevelVarOfNotAdjustable(t *testing.T) {
+	if lv := LevelVarOf(NewSlogConfig(WithCustomHandler(newTestHandler())).NewLogger()); lv != nil {
+		t.Error("custom handler logger reports a LevelVar")
+	}
+	if lv := LevelVarOf(slog.Default()); lv != nil {
+		t.Error("default logger reports a LevelVar")
+	}
+}
+
+func TestLevelHandlerOverridesFactory(t *testing.T) {
+	th := newTestHandler()
+	RegisterHandlerType("test-levelless", func(c SlogConfig) (slog.Handler, error) {
+		return th, nil
+	})
+	logger := NewSlogConfig(WithHandlerType("test-levelless"), WithLevel(slog.LevelWarn)).NewLogger()
+	logger.Info("hidden")
+	if th.lastRecord() != nil {
+		t.Error("record below configured level reached factory handler")
+	}
+}
+
+func TestStatefulLevelVar(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithHandlerType("text"), WithOutput(&buf))
+	state := &testPerson{Name: "Alice"}
+	logger := NewStateful(cfg, state)
+	derived := logger.With("k", "v").WithGroup("g").
+		WithContextValue("x", func(ctx context.Context) any { return nil }).
+		UpdateState(&testPerson{Name: "Bob"})
+
+	lv := logger.LevelVar()
+	if lv == nil || derived.LevelVar() != lv {
+		t.Fatal("derived Stateful logger does not share the LevelVar")
+	}
+	lv.Set(slog.LevelDebug)
+	derived.Debug("debug now")
+	if !strings.Contains(buf.String(), "debug now") {
+		t.Errorf("output = %q", buf.String())
+	}
+}
@@ -0,0 +1,399 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// Closer flushes and releases the resources owned by the handler chain of a
+// logger: records queued by an AsyncHandler, handlers that implement
+// Flush(context.Context) error, Sync() error or io.Closer, and the outputs
+// that a config opened itself, such as the files named in a configuration
+// file or by WithFileOutput. Writers passed to WithOutput or WithSink belong
+// to the caller: they are synced but never closed, and neither are os.Stdout
+// and os.Stderr.
+//
+// Loggers built from the same config share one handler chain (see
+// SlogConfig.BuildHandler). Closing one of them releases its share, and the
+// chain is closed with the last one.
+//
+// The chain is walked outermost first, through wrappers and MultiHandler
+// children, so queued records reach their outputs before those are closed.
+type Closer struct {
+	handler slog.Handler
+}
+
+// NewCloser returns a Closer for the handler chain starting at h.
+func NewCloser(h slog.Handler) *Closer {
+	return &Closer{handler: h}
+}
+
+// CloserOf returns a Closer for the handler chain of l.
+// Loggers derived with With or WithGroup share the reference of their parent
+// to the chain, so closing any of them releases it.
+func CloserOf(l *slog.Logger) *Closer {
+	return NewCloser(l.Handler())
+}
+
+// Closer returns a Closer for the handler chain of the logger.
+func (l *Stateful[T]) Closer() *Closer {
+	return NewCloser(l.logger.Handler())
+}
+
+// Sync writes out queued records and commits outputs to stable storage.
+func (c *Closer) Sync() error {
+	var errs []error
+	for _, r := range handlerResources(c.handler) {
+		errs = append(errs, syncResource(context.Background(), r))
+	}
+	return errors.Join(errs...)
+}
+
+// Close writes out queued records and closes handlers and outputs.
+// Closing an already closed logger is not an error.
+func (c *Closer) Close() error {
+	return closeResources(handlerResources(c.handler))
+}
+
+// Shutdown is like Close but gives up when ctx is done and returns ctx.Err().
+// Closing continues in the background after Shutdown returns.
+func (c *Closer) Shutdown(ctx context.Context) error {
+	return runUntilDone(ctx, c.Close)
+}
+
+// Shutdown closes every logger built from the config, or from configs derived
+// from it with Named, Clone or MergeConfig, that is still open, giving up when
+// ctx is done. Call it on the way out of main, for example once SIGINT or
+// SIGTERM arrives:
+//
+//	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	<-ctx.Done()
+//	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+//	defer cancel()
+//	cfg.Shutdown(shutdownCtx)
+//
+// Loggers built from a config literal, which has no record of its loggers,
+// are not affected; close them with CloserOf.
+func (c SlogConfig) Shutdown(ctx context.Context) error {
+	return runUntilDone(ctx, c.handlers.closeAll)
+}
+
+// runUntilDone runs fn in the background and returns its error, or ctx.Err()
+// if ctx is done first.
+func runUntilDone(ctx context.Context, fn func() error) error {
+	done := make(chan error, 1)
+	go func() { done <- fn() }()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// handlerCache holds the handler chains built from a config and the configs
+// copied from it, so that loggers built from copies with the same settings
+// share one chain, and with it one set of outputs, connections and background
+// goroutines. A nil *handlerCache builds a new chain for every logger.
+type handlerCache struct {
+	mu     sync.Mutex
+	shared []*sharedHandler // in the order they were built
+	index  map[handlerKey]*sharedHandler
+}
+
+// sharedHandler is a handler chain used by the loggers built from copies of
+// a config with the same settings.
+type sharedHandler struct {
+	cache   *handlerCache // nil if the chain is not cached
+	key     handlerKey
+	handler slog.Handler
+	refs    int  // loggers that have not been closed
+	closed  bool // the chain was closed by Shutdown
+}
+
+// handlerKey selects a shared chain: the settings of the config (see
+// SlogConfig.chain) and the NameLevels override that its name selects.
+type handlerKey struct {
+	chain uint64
+	level *slog.LevelVar
+}
+
+// handlerKey returns the key of the chain of the config.
+func (c SlogConfig) handlerKey() handlerKey {
+	return handlerKey{chain: c.chain, level: c.NameLevels.match(c.Name)}
+}
+
+// acquire returns the chain for key, calling build if the cache holds none,
+// and takes a reference to it.
+func (hc *handlerCache) acquire(key handlerKey, build func() (slog.Handler, error)) (*sharedHandler, error) {
+	if hc == nil {
+		h, err := build()
+		if err != nil {
+			return nil, err
+		}
+		return &sharedHandler{handler: h, refs: 1}, nil
+	}
+	hc.mu.Lock()
+	defer hc.mu.Unlock()
+	if sh, ok := hc.index[key]; ok {
+		sh.refs++
+		return sh, nil
+	}
+	h, err := build()
+	if err != nil {
+		return nil, err
+	}
+	sh := &sharedHandler{cache: hc, key: key, handler: h, refs: 1}
+	hc.shared = append(hc.shared, sh)
+	if hc.index == nil {
+		hc.index = make(map[handlerKey]*sharedHandler)
+	}
+	hc.index[key] = sh
+	return sh, nil
+}
+
+// release drops a reference to the chain and closes it with the last one.
+// Resources that another chain of the cache still uses, such as an output
+// file shared by a config and its clones, are left open.
+func (sh *sharedHandler) release() error {
+	hc := sh.cache
+	if hc == nil {
+		return closeResources(handlerResources(sh.handler))
+	}
+	hc.mu.Lock()
+	sh.refs--
+	if sh.refs > 0 || sh.closed {
+		hc.mu.Unlock()
+		return nil
+	}
+	sh.closed = true
+	hc.shared = slices.DeleteFunc(hc.shared, func(x *sharedHandler) bool { return x == sh })
+	delete(hc.index, sh.key)
+	others := slices.Clone(hc.shared)
+	hc.mu.Unlock()
+	return sh.closeUnused(others)
+}
+
+// closeUnused closes the resources of the chain that others do not use.
+func (sh *sharedHandler) closeUnused(others []*sharedHandler) error {
+	var inUse []any
+	for _, other := range others {
+		inUse = append(inUse, handlerResources(other.handler)...)
+	}
+	resources := slices.DeleteFunc(handlerResources(sh.handler), func(r any) bool {
+		return slices.ContainsFunc(inUse, func(u any) bool { return sameResource(r, u) })
+	})
+	return closeResources(resources)
+}
+
+// closeAll closes every chain of the cache.
+func (hc *handlerCache) closeAll() error {
+	if hc == nil {
+		return nil
+	}
+	hc.mu.Lock()
+	shared := hc.shared
+	hc.shared = nil
+	clear(hc.index)
+	for _, sh := range shared {
+		sh.closed = true
+	}
+	hc.mu.Unlock()
+	// Each chain is drained before the outputs it shares with later ones
+	// are closed.
+	var errs []error
+	for i, sh := range shared {
+		errs = append(errs, sh.closeUnused(shared[i+1:]))
+	}
+	return errors.Join(errs...)
+}
+
+// loggerHandler is the root of the chain of a logger built from a config.
+// It holds the logger's share of the chain, which Closer releases.
+type loggerHandler struct {
+	next  slog.Handler
+	share *loggerShare
+}
+
+func (h *loggerHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *loggerHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *loggerHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &loggerHandler{next: h.next.WithAttrs(attrs), share: h.share}
+}
+
+func (h *loggerHandler) WithGroup(name string) slog.Handler {
+	return &loggerHandler{next: h.next.WithGroup(name), share: h.share}
+}
+
+func (h *loggerHandler) Unwrap() slog.Handler {
+	return h.next
+}
+
+// loggerShare is the reference of a logger, and of the loggers derived from
+// it, to a shared chain.
+type loggerShare struct {
+	shared *sharedHandler
+	once   sync.Once
+	err    error
+}
+
+// Flush flushes and syncs the shared chain.
+func (s *loggerShare) Flush(ctx context.Context) error {
+	var errs []error
+	for _, r := range handlerResources(s.shared.handler) {
+		errs = append(errs, syncResource(ctx, r))
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases the reference. Only the first call has an effect.
+func (s *loggerShare) Close() error {
+	s.once.Do(func() { s.err = s.shared.release() })
+	return s.err
+}
+
+// outputHandler records the output of a handler built from a config so that
+// Closer can sync it and, if the config opened it, close it.
+type outputHandler struct {
+	next   slog.Handler
+	output io.Writer
+	owned  bool
+}
+
+func (h *outputHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *outputHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *outputHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &outputHandler{next: h.next.WithAttrs(attrs), output: h.output, owned: h.owned}
+}
+
+func (h *outputHandler) WithGroup(name string) slog.Handler {
+	return &outputHandler{next: h.next.WithGroup(name), output: h.output, owned: h.owned}
+}
+
+func (h *outputHandler) Unwrap() slog.Handler {
+	return h.next
+}
+
+// syncOnly exposes only the Sync method of an output that Closer must not close.
+type syncOnly struct {
+	s interface{ Sync() error }
+}
+
+func (s syncOnly) Sync() error {
+	return s.s.Sync()
+}
+
+// handlerResources lists the handlers and outputs in the tree rooted at h
+// that can be flushed, synced or closed, outermost first and without duplicates.
+func handlerResources(h slog.Handler) []any {
+	var resources, keys []any
+	add := func(r, key any) {
+		switch r.(type) {
+		case interface{ Flush(context.Context) error }, interface{ Sync() error }, io.Closer:
+		default:
+			return
+		}
+		for _, seen := range keys {
+			if sameResource(seen, key) {
+				return
+			}
+		}
+		resources = append(resources, r)
+		keys = append(keys, key)
+	}
+	walkHandlerTree(h, func(h slog.Handler) bool {
+		switch h := h.(type) {
+		case *loggerHandler:
+			add(h.share, h.share)
+			return false // the share releases the chain below
+		case *outputHandler:
+			if h.output == nil || sameWriter(h.output, os.Stdout) || sameWriter(h.output, os.Stderr) {
+				break
+			}
+			if h.owned {
+				add(h.output, h.output)
+			} else if s, ok := h.output.(interface{ Sync() error }); ok {
+				add(syncOnly{s}, h.output)
+			}
+		case *AsyncHandler:
+			add(h, h.queue) // derived AsyncHandlers share one queue
+		default:
+			add(h, h)
+		}
+		return true
+	})
+	return resources
+}
+
+// walkHandlerTree calls fn for h and every handler it wraps, outermost first,
+// descending into the children of a MultiHandler. It does not descend below
+// a handler for which fn returns false.
+func walkHandlerTree(h slog.Handler, fn func(slog.Handler) bool) {
+	walkHandlers(h, func(h slog.Handler) bool {
+		if !fn(h) {
+			return false
+		}
+		if mh, ok := h.(*MultiHandler); ok {
+			for _, child := range mh.Handlers() {
+				walkHandlerTree(child, fn)
+			}
+		}
+		return true
+	})
+}
+
+// closeResources closes the resources that can be closed and syncs the others.
+func closeResources(resources []any) error {
+	var errs []error
+	for _, r := range resources {
+		if closer, ok := r.(io.Closer); ok {
+			if err := closer.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		errs = append(errs, syncResource(context.Background(), r))
+	}
+	return errors.Join(errs...)
+}
+
+// syncResource flushes or syncs r.
+func syncResource(ctx context.Context, r any) error {
+	switch r := r.(type) {
+	case interface{ Flush(context.Context) error }:
+		return r.Flush(ctx)
+	case interface{ Sync() error }:
+		if err := r.Sync(); err != nil && !errors.Is(err, os.ErrClosed) {
+			return err
+		}
+	}
+	return nil
+}
+
+// sameResource reports whether a and b are the same value. Values of
+// non-comparable types are never the same.
+func sameResource(a, b any) bool {
+	if !reflect.ValueOf(a).Comparable() || !reflect.ValueOf(b).Comparable() {
+		return false
+	}
+	return a == b
+}
@@ -0,0 +1,277 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// syncBuffer is a writer that counts Sync and Close calls.
+type syncBuffer struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	syncs  int
+	closes int
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closes > 0 {
+		return 0, os.ErrClosed
+	}
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) Sync() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.syncs++
+	return nil
+}
+
+func (b *syncBuffer) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closes++
+	return nil
+}
+
+func (b *syncBuffer) counts() (syncs, closes int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.syncs, b.closes
+}
+
+func TestCloserClosesFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg := NewSlogConfig(WithFileOutput(path, RotationPolicy{}))
+	f := cfg.Output.(*RotatingFile)
+	logger := cfg.NewLogger()
+	logger.Info("hello")
+	if err := CloserOf(logger.With("k", "v")).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := f.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("output still open after Close: %v", err)
+	}
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Errorf("second Close: %v", err)
+	}
+}
+
+func TestCloserKeepsCallerOutput(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	logger := NewSlogConfig(WithOutput(f)).NewLogger()
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := f.Write([]byte("x")); err != nil {
+		t.Errorf("caller's output closed: %v", err)
+	}
+}
+
+func TestCloserClosesSharedOutputWithLastLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg := NewSlogConfig(WithFileOutput(path, RotationPolicy{}))
+	a, b := cfg.NewLogger(), cfg.Named("b").NewLogger()
+	if err := CloserOf(a).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	b.Info("still open")
+	if err := CloserOf(b).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "still open") {
+		t.Errorf("record lost after closing another logger: %q", data)
+	}
+	if _, err := cfg.Output.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("output still open after closing every logger: %v", err)
+	}
+}
+
+func TestNewLoggerSharesHandlers(t *testing.T) {
+	cfg := NewSlogConfig(WithFileOutput(filepath.Join(t.TempDir(), "app.log"), RotationPolicy{}))
+	defer cfg.Shutdown(context.Background())
+	for i := range 1000 {
+		cfg.Named(fmt.Sprint("req", i)).NewLogger().Info("hello")
+		NewStateful(cfg, &struct{ ID int }{ID: i}).Info("hello")
+	}
+	cfg.handlers.mu.Lock()
+	n := len(cfg.handlers.shared)
+	cfg.handlers.mu.Unlock()
+	if n != 1 {
+		t.Errorf("%d handler chains built, want 1", n)
+	}
+}
+
+func TestCloneSharesHandlers(t *testing.T) {
+	var builds atomic.Int32
+	RegisterHandlerType("test-counting-json", func(c SlogConfig) (slog.Handler, error) {
+		builds.Add(1)
+		return slog.NewJSONHandler(c.Output, c.HandlerOptions), nil
+	})
+	cfg := NewSlogConfig(WithHandlerType("test-counting-json"), WithOutput(io.Discard),
+		WithAsync(AsyncOptions{}), WithSampling(SamplingOptions{}), WithRateLimit(RateLimitOptions{}), WithDedup(DedupOptions{}))
+	defer cfg.Shutdown(context.Background())
+	cfg.NewLogger().Info("original")
+	cfg.Clone().NewLogger().Info("clone")
+	cfg.Clone().Named("db").NewLogger().Info("named clone")
+	if n := builds.Load(); n != 1 {
+		t.Errorf("factory called %d times for a config and its clones, want 1", n)
+	}
+	debug := cfg.Clone()
+	WithLevel(slog.LevelDebug)(&debug)
+	debug.NewLogger().Debug("debug")
+	merged, _, err := MergeConfig(cfg, ConfigLayer{Name: "custom", Options: []ConfigOption{WithAddSource(true)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	merged.NewLogger().Info("merged")
+	if n := builds.Load(); n != 3 {
+		t.Errorf("factory called %d times, want 3: changed settings need chains of their own", n)
+	}
+}
+
+func TestHandlerFactoryCalledOncePerConfig(t *testing.T) {
+	srv := newLokiServer(t)
+	loki, err := lookupHandlerType("loki")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var builds atomic.Int32
+	RegisterHandlerType("test-counting-loki", func(c SlogConfig) (slog.Handler, error) {
+		builds.Add(1)
+		return loki(c)
+	})
+	cfg := NewSlogConfig(WithHandlerType("test-counting-loki"), WithDestination(srv.URL+"/"))
+	for i := range 100 {
+		cfg.Named(fmt.Sprint("req", i)).NewLogger().Info("served")
+		NewStateful(cfg, &struct{ ID int }{ID: i}).Info("served")
+	}
+	if n := builds.Load(); n != 1 {
+		t.Errorf("factory called %d times for 200 loggers of one config, want 1", n)
+	}
+	if err := cfg.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	values := 0
+	for _, push := range srv.pushes {
+		for _, stream := range push.Streams {
+			values += len(stream.Values)
+		}
+	}
+	if values != 200 {
+		t.Errorf("pushed %d records, want 200", values)
+	}
+}
+
+func TestCloserKeepsStdStreams(t *testing.T) {
+	logger := NewSlogConfig(WithOutput(os.Stdout)).NewLogger()
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stdout.Stat(); err != nil {
+		t.Errorf("stdout closed: %v", err)
+	}
+}
+
+func TestCloserSync(t *testing.T) {
+	out := &syncBuffer{}
+	logger := NewSlogConfig(WithOutput(out)).NewLogger()
+	if err := CloserOf(logger).Sync(); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if syncs, closes := out.counts(); syncs != 1 || closes != 0 {
+		t.Errorf("syncs, closes = %d, %d; want 1, 0", syncs, closes)
+	}
+}
+
+func TestCloserDrainsAsyncBeforeClosingOutputs(t *testing.T) {
+	shared, debug := &syncBuffer{}, &syncBuffer{}
+	cfg := NewSlogConfig(
+		WithSink("json", shared, slog.LevelInfo),
+		WithSink("text", shared, slog.LevelInfo),
+		WithSink("json", debug, slog.LevelDebug),
+		WithAsync(AsyncOptions{}),
+	)
+	sl := NewStateful(cfg, &struct{ ID int }{ID: 7})
+	sl.Info("last words")
+	if err := sl.Closer().Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	for name, out := range map[string]*syncBuffer{"shared": shared, "debug": debug} {
+		if syncs, closes := out.counts(); syncs != 1 || closes != 0 {
+			t.Errorf("%s output: syncs, closes = %d, %d; want 1, 0", name, syncs, closes)
+		}
+		if !strings.Contains(out.buf.String(), "last words") {
+			t.Errorf("%s output lost queued record: %q", name, out.buf.String())
+		}
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	cfg := NewSlogConfig(WithFileOutput(path, RotationPolicy{}), WithAsync(AsyncOptions{}))
+	cfg.NewLogger().Info("bye")
+	db := cfg.Named("db")
+	WithLevel(slog.LevelDebug)(&db)
+	db.NewLogger().Debug("bye from db")
+	if err := cfg.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"bye"`) || !strings.Contains(string(data), "bye from db") {
+		t.Errorf("records lost on Shutdown: %q", data)
+	}
+	if _, err := cfg.Output.Write([]byte("x")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("output still open after Shutdown: %v", err)
+	}
+	cfg.handlers.mu.Lock()
+	n := len(cfg.handlers.shared)
+	cfg.handlers.mu.Unlock()
+	if n != 0 {
+		t.Errorf("%d handler chains still open after Shutdown", n)
+	}
+}
+
+func TestCloserShutdownTimeout(t *testing.T) {
+	gh := newGateHandler()
+	ah := NewAsyncHandler(gh, AsyncOptions{})
+	ah.Handle(context.Background(), asyncRecordMsg("stuck"))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := NewCloser(ah).Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown error = %v, want deadline exceeded", err)
+	}
+	close(gh.gate)
+	if err := NewCloser(ah).Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if gh.lastRecord() == nil {
+		t.Error("queued record lost")
+	}
+}
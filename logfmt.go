@@ -0,0 +1,226 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// LogfmtOptions configure a LogfmtHandler.
+type LogfmtOptions struct {
+	slog.HandlerOptions
+
+	// KeyOrder lists keys that are written first, in this order; the other
+	// keys follow in record order. Keys are matched after ReplaceAttr and
+	// Rules, using dotted group paths such as "Session.User.ID".
+	// If nil, time, level and msg come first; an empty non-nil slice keeps
+	// record order.
+	KeyOrder []string
+}
+
+// WithLogfmtKeyOrder returns a ConfigOption that sets the keys the "logfmt"
+// handler type writes first, in this order (see LogfmtOptions.KeyOrder).
+// Calling it with no keys writes every key in record order.
+func WithLogfmtKeyOrder(keys ...string) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.LogfmtKeyOrder = append([]string{}, keys...)
+		cfg.touch()
+	}
+}
+
+// LogfmtHandler writes records as logfmt lines:
+//
+//	time=2026-10-16T12:30:45.123456789Z level=INFO msg="user logged in" Session.User.ID=7
+//
+// Values are written bare unless they contain spaces, '=', '"', control
+// characters or invalid UTF-8, in which case they are quoted with backslash
+// escapes. Empty values are written as "key=" and nil values as "null".
+// Groups, including the state group of a Stateful logger, become dotted key
+// prefixes. It is registered as the "logfmt" handler type.
+type LogfmtHandler struct {
+	mu        *sync.Mutex // serializes writes to w across derived handlers
+	w         io.Writer
+	level     slog.Leveler
+	addSource bool
+	keyOrder  []string
+	scope     attrScope
+}
+
+// NewLogfmtHandler returns a LogfmtHandler that writes to w.
+// A nil opts uses the defaults.
+func NewLogfmtHandler(w io.Writer, opts *LogfmtOptions) *LogfmtHandler {
+	if opts == nil {
+		opts = &LogfmtOptions{}
+	}
+	h := &LogfmtHandler{
+		mu:        new(sync.Mutex),
+		w:         w,
+		level:     opts.Level,
+		addSource: opts.AddSource,
+		keyOrder:  opts.KeyOrder,
+		scope:     attrScope{replace: opts.ReplaceAttr},
+	}
+	if h.level == nil {
+		h.level = slog.LevelInfo
+	}
+	if h.keyOrder == nil {
+		h.keyOrder = []string{slog.TimeKey, slog.LevelKey, slog.MessageKey}
+	}
+	return h
+}
+
+func (h *LogfmtHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *LogfmtHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope.withAttrs(attrs)
+	return &h2
+}
+
+func (h *LogfmtHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.scope = h.scope.withGroup(name)
+	return &h2
+}
+
+func (h *LogfmtHandler) Handle(_ context.Context, r slog.Record) error {
+	var pairs []slog.Attr
+	addBuiltin := func(a slog.Attr) {
+		if a, ok := h.scope.builtin(a); ok {
+			pairs = append(pairs, a)
+		}
+	}
+	if !r.Time.IsZero() {
+		addBuiltin(slog.Time(slog.TimeKey, r.Time))
+	}
+	addBuiltin(slog.Any(slog.LevelKey, r.Level))
+	if h.addSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		addBuiltin(slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", frame.File, frame.Line)))
+	}
+	addBuiltin(slog.String(slog.MessageKey, r.Message))
+	h.scope.each(r, func(groups []string, a slog.Attr) {
+		if len(groups) > 0 {
+			a.Key = strings.Join(groups, ".") + "." + a.Key
+		}
+		pairs = append(pairs, a)
+	})
+
+	var buf bytes.Buffer
+	written := make([]bool, len(pairs))
+	write := func(i int) {
+		if buf.Len() > 0 {
+			buf.WriteByte(' ')
+		}
+		writeLogfmtKey(&buf, pairs[i].Key)
+		buf.WriteByte('=')
+		writeLogfmtValue(&buf, pairs[i].Value)
+		written[i] = true
+	}
+	for _, key := range h.keyOrder {
+		for i, a := range pairs {
+			if !written[i] && a.Key == key {
+				write(i)
+				break
+			}
+		}
+	}
+	for i := range pairs {
+		if !written[i] {
+			write(i)
+		}
+	}
+	buf.WriteByte('\n')
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.w.Write(buf.Bytes())
+	return err
+}
+
+// writeLogfmtKey writes a key, replacing characters that logfmt does not
+// allow in keys (spaces, '=', '"', control characters) with '_'.
+func writeLogfmtKey(buf *bytes.Buffer, key string) {
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			r = '_'
+		}
+		buf.WriteRune(r)
+	}
+}
+
+// writeLogfmtValue writes a value, quoting it if needed.
+func writeLogfmtValue(buf *bytes.Buffer, v slog.Value) {
+	var s string
+	switch v.Kind() {
+	case slog.KindTime:
+		s = v.Time().Format(time.RFC3339Nano)
+	case slog.KindDuration:
+		s = v.Duration().String()
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case nil:
+			buf.WriteString("null")
+			return
+		case error:
+			s = x.Error()
+		default:
+			s = v.String()
+		}
+	default:
+		s = v.String()
+	}
+	if strings.IndexFunc(s, needsLogfmtQuote) < 0 {
+		buf.WriteString(s)
+		return
+	}
+	writeLogfmtQuoted(buf, s)
+}
+
+// needsLogfmtQuote reports whether r forces a value to be quoted.
+func needsLogfmtQuote(r rune) bool {
+	return r <= ' ' || r == '=' || r == '"' || r == 0x7f || r == utf8.RuneError
+}
+
+// writeLogfmtQuoted writes s as a double-quoted string with backslash escapes
+// for '"', '\\', control characters and invalid UTF-8.
+func writeLogfmtQuoted(buf *bytes.Buffer, s string) {
+	const hex = "0123456789abcdef"
+	buf.WriteByte('"')
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch {
+		case r == '"' || r == '\\':
+			buf.WriteByte('\\')
+			buf.WriteRune(r)
+		case r == '\n':
+			buf.WriteString(`\n`)
+		case r == '\r':
+			buf.WriteString(`\r`)
+		case r == '\t':
+			buf.WriteString(`\t`)
+		case r == utf8.RuneError && size == 1:
+			buf.WriteString(`\ufffd`)
+		case r < ' ' || r == 0x7f:
+			buf.WriteString(`\u00`)
+			buf.WriteByte(hex[r>>4])
+			buf.WriteByte(hex[r&0xf])
+		default:
+			buf.WriteRune(r)
+		}
+	}
+	buf.WriteByte('"')
+}
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogfmtHandler(t *testing.T) {
+	var buf bytes.Buffer
+	h := NewLogfmtHandler(&buf, nil)
+	r := slog.NewRecord(testTime, slog.LevelInfo, "user logged in", 0)
+	r.AddAttrs(
+		slog.String("plain", "abc"),
+		slog.String("empty", ""),
+		slog.Any("nil", nil),
+		slog.String("quote", `say "hi"`),
+		slog.String("eq", "a=b"),
+		slog.String("ctl", "tab\there\nnl\x01\\"),
+		slog.String("bad", "x\xffy"),
+		slog.Duration("took", 1500*time.Millisecond),
+		slog.Any("err", errors.New("boom")),
+		slog.String("key with space", "v"),
+	)
+	h.Handle(context.Background(), r)
+	want := `time=2026-10-16T12:30:45.123456789Z level=INFO msg="user logged in" plain=abc empty= nil=null` +
+		` quote="say \"hi\"" eq="a=b" ctl="tab\there\nnl\u0001\\" bad="x\ufffdy" took=1.5s err=boom key_with_space=v` + "\n"
+	if buf.String() != want {
+		t.Errorf("got  %s\nwant %s", buf.String(), want)
+	}
+}
+
+func TestLogfmtHandlerGroups(t *testing.T) {
+	var buf bytes.Buffer
+	type Session struct {
+		User struct{ ID int }
+	}
+	state := &Session{}
+	state.User.ID = 7
+	cfg := NewSlogConfig(WithCustomHandler(NewLogfmtHandler(&buf, &LogfmtOptions{KeyOrder: []string{}})))
+	sl := NewStateful(cfg, state)
+	sl.WithGroup("req").With("id", 1).Info("hi", slog.Group("g", "k", "v"))
+	if got := buf.String(); !strings.Contains(got, "req.id=1 req.g.k=v req.Session.User.ID=7") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLogfmtHandlerKeyOrder(t *testing.T) {
+	var buf bytes.Buffer
+	opts := &LogfmtOptions{
+		HandlerOptions: slog.HandlerOptions{ReplaceAttr: replaceAttrWithRules(
+			[]AttrRule{{Key: slog.MessageKey, Action: RuleRename, To: "message"}}, nil)},
+		KeyOrder: []string{"request_id", "level", "message"},
+	}
+	logger := slog.New(NewLogfmtHandler(&buf, opts))
+	logger.Warn("slow", "took", "2s", "request_id", "r-1")
+	want := "request_id=r-1 level=WARN message=slow time="
+	if got := buf.String(); !strings.HasPrefix(got, want) || !strings.HasSuffix(got, " took=2s\n") {
+		t.Errorf("got %q, want prefix %q", got, want)
+	}
+}
+
+func TestLogfmtHandlerType(t *testing.T) {
+	var buf bytes.Buffer
+	NewSlogConfig(WithHandlerType("logfmt"), WithOutput(&buf)).Named("db").NewLogger().Info("ok")
+	if got := buf.String(); !strings.Contains(got, " level=INFO msg=ok logger=db\n") {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestLogfmtHandlerTypeKeyOrder(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithHandlerType("logfmt"), WithOutput(&buf), WithLogfmtKeyOrder(slog.MessageKey, "id"))
+	cfg.NewLogger().Info("ok", "user", "ann", "id", 7)
+	if got := buf.String(); !strings.HasPrefix(got, "msg=ok id=7 time=") || !strings.HasSuffix(got, " level=INFO user=ann\n") {
+		t.Errorf("got %q", got)
+	}
+}
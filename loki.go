@@ -0,0 +1,282 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultLokiDestination is the destination of the "loki" handler type when
+// SlogConfig.Destination is empty: the push API of a Loki on this host.
+const DefaultLokiDestination = "http://localhost:3100" + lokiPushPath
+
+// lokiPushPath is used when a destination has no path.
+const lokiPushPath = "/loki/api/v1/push"
+
+// LokiOptions configure a LokiHandler.
+type LokiOptions struct {
+	slog.HandlerOptions
+
+	// Labels are added to the stream labels of every record.
+	Labels map[string]string
+
+	// LabelKeys lists the attributes promoted to stream labels instead of
+	// being written into the line. A key matches only the attribute with the
+	// same dotted path, such as "service" or "User.Role" for a field of a
+	// Stateful[User] logger; a key naming a group promotes none of its
+	// attributes, since each distinct value starts a new stream. The label
+	// name is the path with '_' in place of dots and other characters that
+	// labels do not allow.
+	LabelKeys []string
+
+	// GroupLabel, if non-empty, names a label set to the dotted group path
+	// of a record: the groups opened with WithGroup followed by the state
+	// group of a Stateful logger, such as "billing" for records of
+	// logger.WithGroup("billing") or "User" for those of a Stateful[User].
+	// Records logged outside any group do not carry it.
+	GroupLabel string
+
+	// Batch configures batching and retries.
+	Batch BatchOptions
+
+	// Gzip compresses request bodies.
+	Gzip bool
+
+	// Header is added to every request, e.g. X-Scope-OrgID or Authorization.
+	Header http.Header
+
+	// Client sends the requests. If nil, a client with DefaultHTTPTimeout is used.
+	Client *http.Client
+}
+
+// WithLokiOptions returns a ConfigOption that sets the labels, batching and
+// request settings of the "loki" handler type (see SlogConfig.Loki). The
+// HandlerOptions in opts are ignored; the handler takes the level and
+// HandlerOptions of the config.
+func WithLokiOptions(opts LokiOptions) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.Loki = &opts
+		cfg.touch()
+	}
+}
+
+// LokiHandler batches records and pushes them to the Loki push API
+// (POST /loki/api/v1/push). Records with the same labels form a stream;
+// each line is a JSON object holding the level, message, source and the
+// attributes that were not promoted to labels, with groups as nested
+// objects:
+//
+//	{"streams":[{"stream":{"service":"billing"},"values":[["1792153845123456789","{\"level\":\"INFO\",\"msg\":\"paid\",\"amount\":42}"]]}]}
+//
+// Batches are sent from a background goroutine and retried as configured by
+// LokiOptions.Batch. Handlers derived with WithAttrs and WithGroup share the
+// batches of their parent. Call Flush to wait for buffered records and Close
+// to send the rest and stop the sender.
+//
+// It is registered as the "loki" handler type, which reads the push URL from
+// SlogConfig.Destination (see WithDestination).
+type LokiHandler struct {
+	batch      *batcher[lokiEntry]
+	labels     map[string]string
+	labelKeys  []string
+	groupLabel string
+	level      slog.Leveler
+	addSource  bool
+	scope      attrScope
+}
+
+// lokiEntry is a line of a stream.
+type lokiEntry struct {
+	labels map[string]string
+	stream string // canonical form of labels
+	time   int64
+	line   string
+}
+
+// NewLokiHandler returns a LokiHandler that pushes records to url. If url
+// has no path, the standard push path is used. A nil opts uses the
+// defaults. It fails only if url is malformed; push errors are reported
+// through BatchOptions.OnError and Stats.
+func NewLokiHandler(url string, opts *LokiOptions) (*LokiHandler, error) {
+	url, err := parseHTTPDestination(url, lokiPushPath)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &LokiOptions{}
+	}
+	sender := newHTTPSender(opts.Client, url, opts.Header, opts.Gzip)
+	h := &LokiHandler{
+		labels:     make(map[string]string, len(opts.Labels)),
+		labelKeys:  slices.Clone(opts.LabelKeys),
+		groupLabel: opts.GroupLabel,
+		level:      opts.Level,
+		addSource:  opts.AddSource,
+		scope:      attrScope{replace: opts.ReplaceAttr},
+	}
+	for k, v := range opts.Labels {
+		h.labels[lokiLabelName(k)] = v
+	}
+	if h.level == nil {
+		h.level = slog.LevelInfo
+	}
+	h.batch = newBatcher(opts.Batch, func(ctx context.Context, entries []lokiEntry) error {
+		body, err := encodeLokiPush(entries)
+		if err != nil {
+			return &permanentError{err}
+		}
+		_, err = sender.post(ctx, "application/json", body)
+		return err
+	})
+	return h, nil
+}
+
+func (h *LokiHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *LokiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope.withAttrs(attrs)
+	return &h2
+}
+
+func (h *LokiHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.scope = h.scope.withGroup(name)
+	return &h2
+}
+
+// Handle encodes r and buffers it for the next batch. It returns
+// ErrBatchClosed after Close.
+func (h *LokiHandler) Handle(ctx context.Context, r slog.Record) error {
+	labels := make(map[string]string, len(h.labels))
+	for k, v := range h.labels {
+		labels[k] = v
+	}
+	if h.groupLabel != "" {
+		groups := h.scope.groups
+		if name, ok := stateGroup(ctx, r); ok {
+			groups = append(slices.Clip(groups), name)
+		}
+		if len(groups) > 0 {
+			labels[lokiLabelName(h.groupLabel)] = strings.Join(groups, ".")
+		}
+	}
+	line := &attrNode{children: []*attrNode{}}
+	addBuiltin := func(a slog.Attr) {
+		if a, ok := h.scope.builtin(a); ok {
+			line.insert(nil, a.Key, a.Value)
+		}
+	}
+	addBuiltin(slog.Any(slog.LevelKey, r.Level))
+	addBuiltin(slog.String(slog.MessageKey, r.Message))
+	if h.addSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		addBuiltin(slog.Any(slog.SourceKey, &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}))
+	}
+	h.scope.each(r, func(groups []string, a slog.Attr) {
+		if path := append(groups[:len(groups):len(groups)], a.Key); h.isLabel(path) {
+			labels[lokiLabelName(strings.Join(path, "_"))] = syslogValue(a.Value)
+			return
+		}
+		line.insert(groups, a.Key, a.Value)
+	})
+
+	var buf bytes.Buffer
+	line.appendJSON(&buf)
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	e := lokiEntry{labels: labels, stream: lokiStreamKey(labels), time: t.UnixNano(), line: buf.String()}
+	return h.batch.add(e, len(e.line)+len(e.stream))
+}
+
+// isLabel reports whether the attribute at path is promoted to a label.
+func (h *LokiHandler) isLabel(path []string) bool {
+	if len(h.labelKeys) == 0 {
+		return false
+	}
+	return slices.Contains(h.labelKeys, strings.Join(path, "."))
+}
+
+// Flush sends the buffered records and waits until they have been pushed or
+// given up, or until ctx is done.
+func (h *LokiHandler) Flush(ctx context.Context) error {
+	return h.batch.flush(ctx)
+}
+
+// Close stops accepting records, sends the buffered ones and stops the
+// sender goroutine. It is safe to call more than once.
+func (h *LokiHandler) Close() error {
+	h.batch.close()
+	return nil
+}
+
+// Stats returns a snapshot of the handler's counters.
+func (h *LokiHandler) Stats() BatchStats {
+	return h.batch.snapshot()
+}
+
+// encodeLokiPush returns the push request for entries, with one stream per
+// label set in order of first appearance.
+func encodeLokiPush(entries []lokiEntry) ([]byte, error) {
+	type stream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+	var streams []*stream
+	index := make(map[string]*stream)
+	for _, e := range entries {
+		s, ok := index[e.stream]
+		if !ok {
+			s = &stream{Stream: e.labels}
+			index[e.stream] = s
+			streams = append(streams, s)
+		}
+		s.Values = append(s.Values, [2]string{strconv.FormatInt(e.time, 10), e.line})
+	}
+	return json.Marshal(map[string]any{"streams": streams})
+}
+
+// lokiStreamKey returns a canonical form of a label set.
+func lokiStreamKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		fmt.Fprintf(&sb, "%s=%q,", k, labels[k])
+	}
+	return sb.String()
+}
+
+// lokiLabelName returns a valid label name: letters, digits and '_', not
+// starting with a digit.
+func lokiLabelName(s string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		}
+		return '_'
+	}, s)
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
@@ -0,0 +1,218 @@
+package logger
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type lokiPushRequest struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+// lokiServer is a stand-in for the Loki push API. It fails the first
+// failures requests with 503.
+type lokiServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	pushes   []lokiPushRequest
+	headers  []http.Header
+	failures int
+}
+
+func newLokiServer(t *testing.T) *lokiServer {
+	s := &lokiServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/loki/api/v1/push" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failures > 0 {
+			s.failures--
+			http.Error(w, "ingester unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		body := io.Reader(r.Body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		var push lokiPushRequest
+		if err := json.NewDecoder(body).Decode(&push); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.pushes = append(s.pushes, push)
+		s.headers = append(s.headers, r.Header)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestLokiHandlerStreams(t *testing.T) {
+	srv := newLokiServer(t)
+	srv.failures = 1
+	h, err := NewLokiHandler(srv.URL, &LokiOptions{
+		Labels:    map[string]string{"env": "prod", "app.name": "shop"},
+		LabelKeys: []string{"service", "testPerson.Name"},
+		Gzip:      true,
+		Header:    http.Header{"X-Scope-Orgid": {"tenant-1"}},
+		Batch:     BatchOptions{Interval: time.Hour, Retry: fastRetry},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewSlogConfig(WithCustomHandler(h))
+	state := &testPerson{Name: "Alice"}
+	logger := NewStateful(cfg, state).With("service", "billing")
+	logger.Info("paid", "amount", 42, "card", slog.GroupValue(slog.String("brand", "visa")))
+	logger.UpdateState(&testPerson{Name: "Bob"}).Warn("refused")
+	if err := h.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	if st := h.Stats(); st.Sent != 2 || st.Batches != 1 || st.Retries != 1 {
+		t.Errorf("stats = %+v", st)
+	}
+	if len(srv.pushes) != 1 {
+		t.Fatalf("got %d pushes, want 1", len(srv.pushes))
+	}
+	if got := srv.headers[0].Get("X-Scope-OrgID"); got != "tenant-1" {
+		t.Errorf("X-Scope-OrgID = %q", got)
+	}
+	streams := srv.pushes[0].Streams
+	if len(streams) != 2 {
+		t.Fatalf("got %d streams, want 2: %+v", len(streams), streams)
+	}
+	wantLabels := map[string]string{"env": "prod", "app_name": "shop", "service": "billing", "testPerson_Name": "Alice"}
+	for k, v := range wantLabels {
+		if streams[0].Stream[k] != v {
+			t.Errorf("label %s = %q, want %q", k, streams[0].Stream[k], v)
+		}
+	}
+	if streams[1].Stream["testPerson_Name"] != "Bob" {
+		t.Errorf("second stream labels = %v", streams[1].Stream)
+	}
+	if want := `{"level":"INFO","msg":"paid","amount":42,"card":{"brand":"visa"}}`; streams[0].Values[0][1] != want {
+		t.Errorf("line = %s, want %s", streams[0].Values[0][1], want)
+	}
+	if ts := streams[0].Values[0][0]; len(ts) != 19 {
+		t.Errorf("timestamp = %q, want nanoseconds", ts)
+	}
+}
+
+func TestLokiHandlerLabelPaths(t *testing.T) {
+	srv := newLokiServer(t)
+	h, err := NewLokiHandler(srv.URL, &LokiOptions{
+		LabelKeys:  []string{"card", "billing.req.method"},
+		GroupLabel: "component",
+		Batch:      BatchOptions{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger := slog.New(h)
+	logger.Info("paid", "card", slog.GroupValue(slog.String("number", "4111")))
+	logger.WithGroup("billing").Info("served", "req", slog.GroupValue(slog.String("method", "GET"), slog.String("id", "r1")))
+	if err := h.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	streams := srv.pushes[0].Streams
+	if len(streams) != 2 {
+		t.Fatalf("got %d streams, want 2: %+v", len(streams), streams)
+	}
+	if len(streams[0].Stream) != 0 {
+		t.Errorf("group key promoted its attributes: %v", streams[0].Stream)
+	}
+	if want := map[string]string{"component": "billing", "billing_req_method": "GET"}; !maps.Equal(streams[1].Stream, want) {
+		t.Errorf("labels = %v, want %v", streams[1].Stream, want)
+	}
+}
+
+func TestLokiHandlerStatefulGroupLabel(t *testing.T) {
+	srv := newLokiServer(t)
+	h, err := NewLokiHandler(srv.URL, &LokiOptions{
+		GroupLabel: "component",
+		Batch:      BatchOptions{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := NewSlogConfig(WithCustomHandler(h))
+	NewStateful(cfg, &testPerson{Name: "Alice"}).Info("paid", "card", slog.GroupValue(slog.String("brand", "visa")))
+	NewStateful(cfg, &testPerson{Name: "Bob"}).WithGroup("billing").Info("refused")
+	NewStateful(cfg, &testPerson{}).Info("anonymous")
+	NewStateful(cfg, &testPerson{Name: "Carol"}, WithGroupName[testPerson]("user")).InfoContext(context.Background(), "renamed")
+	if err := h.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, stream := range srv.pushes[0].Streams {
+		got = append(got, fmt.Sprintf("%s:%d", stream.Stream["component"], len(stream.Values)))
+	}
+	if want := "testPerson:1,billing.testPerson:1,:1,user:1"; strings.Join(got, ",") != want {
+		t.Errorf("component labels = %s, want %s", strings.Join(got, ","), want)
+	}
+}
+
+func TestLokiHandlerType(t *testing.T) {
+	srv := newLokiServer(t)
+	logger, err := NewSlogConfig(WithHandlerType("loki"), WithDestination(srv.URL+"/")).BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	logger.Info("one")
+	logger.Info("two")
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(srv.pushes) != 1 || len(srv.pushes[0].Streams) != 1 || len(srv.pushes[0].Streams[0].Values) != 2 {
+		t.Errorf("pushes = %+v", srv.pushes)
+	}
+	if _, err := NewLokiHandler("loki:3100", nil); err == nil {
+		t.Error("NewLokiHandler accepted a URL without scheme")
+	}
+}
+
+func TestLokiHandlerTypeOptions(t *testing.T) {
+	srv := newLokiServer(t)
+	logger, err := NewSlogConfig(WithHandlerType("loki"), WithDestination(srv.URL), WithLokiOptions(LokiOptions{
+		Labels:     map[string]string{"env": "prod"},
+		LabelKeys:  []string{"service"},
+		GroupLabel: "component",
+	})).BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	logger.With("service", "shop").WithGroup("billing").Info("paid")
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(srv.pushes) != 1 || len(srv.pushes[0].Streams) != 1 {
+		t.Fatalf("pushes = %+v", srv.pushes)
+	}
+	want := map[string]string{"env": "prod", "service": "shop", "component": "billing"}
+	if got := srv.pushes[0].Streams[0].Stream; !maps.Equal(got, want) {
+		t.Errorf("labels = %v, want %v", got, want)
+	}
+}
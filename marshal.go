@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// configJSON is the serialized form of SlogConfig. It follows the
+// configuration file schema described in LoadSlogConfig.
+type configJSON struct {
+	Handler     string            `json:"handler"`
+	Output      string            `json:"output"`
+	Destination string            `json:"destination,omitempty"`
+	Resource    map[string]string `json:"resource,omitempty"`
+	Level       string            `json:"level"`
+	AddSource   bool              `json:"add_source"`
+	Sinks       []sinkJSON        `json:"sinks,omitempty"`
+	Rules       []AttrRule        `json:"rules,omitempty"`
+	NameLevels  string            `json:"name_levels,omitempty"`
+}
+
+// sinkJSON is the serialized form of a Sink.
+type sinkJSON struct {
+	Handler string `json:"handler"`
+	Output  string `json:"output"`
+	Level   string `json:"level"`
+}
+
+// Validate reports whether the config can build a handler. It checks that
+// HandlerType is registered, that Level and Output are usable and that all
+// Rules are well formed, and includes errors recorded by options (see Err).
+// Problems are reported as *ConfigKeyError values joined together.
+// A config with a CustomHandler only reports recorded option errors.
+func (c SlogConfig) Validate() error {
+	errs := []error{c.err}
+	if c.CustomHandler != nil {
+		return errors.Join(errs...)
+	}
+	if c.HandlerType != "" {
+		if _, err := lookupHandlerType(c.HandlerType); err != nil {
+			errs = append(errs, &ConfigKeyError{Key: SettingHandler, Err: err})
+		}
+	}
+	if isNilValue(c.Level) {
+		errs = append(errs, &ConfigKeyError{Key: SettingLevel, Err: fmt.Errorf("nil %T", c.Level)})
+	}
+	if c.HandlerOptions != nil && isNilValue(c.HandlerOptions.Level) {
+		errs = append(errs, &ConfigKeyError{Key: SettingLevel, Err: fmt.Errorf("nil %T in HandlerOptions", c.HandlerOptions.Level)})
+	}
+	if err := validateOutput(c.Output); err != nil {
+		errs = append(errs, &ConfigKeyError{Key: SettingOutput, Err: err})
+	}
+	for i, sink := range c.Sinks {
+		prefix := fmt.Sprintf("sinks[%d]", i)
+		if sink.HandlerType != "" {
+			if _, err := lookupHandlerType(sink.HandlerType); err != nil {
+				errs = append(errs, &ConfigKeyError{Key: prefix + ".handler", Err: err})
+			}
+		}
+		if isNilValue(sink.Level) {
+			errs = append(errs, &ConfigKeyError{Key: prefix + ".level", Err: fmt.Errorf("nil %T", sink.Level)})
+		}
+		if err := validateOutput(sink.Output); err != nil {
+			errs = append(errs, &ConfigKeyError{Key: prefix + ".output", Err: err})
+		}
+	}
+	for i, rule := range c.Rules {
+		if err := rule.Validate(); err != nil {
+			errs = append(errs, &ConfigKeyError{Key: fmt.Sprintf("%s[%d]", SettingRules, i), Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validateOutput checks that w is not a nil pointer or a closed file.
+func validateOutput(w io.Writer) error {
+	if isNilValue(w) {
+		return fmt.Errorf("nil %T", w)
+	}
+	if f, ok := w.(*os.File); ok {
+		if _, err := f.Stat(); errors.Is(err, os.ErrClosed) {
+			return fmt.Errorf("file %s is closed", f.Name())
+		}
+	}
+	return nil
+}
+
+// isNilValue reports whether v holds a typed nil pointer, map, slice, func or channel.
+func isNilValue(v any) bool {
+	if v == nil {
+		return false
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	}
+	return false
+}
+
+// outputName returns the target name of w: "stderr", "stdout", the name of
+// a named writer such as *os.File, or "<type>" for writers without a name.
+func outputName(w io.Writer) string {
+	switch {
+	case w == nil || sameWriter(w, os.Stderr):
+		return "stderr"
+	case sameWriter(w, os.Stdout):
+		return "stdout"
+	}
+	if named, ok := w.(interface{ Name() string }); ok && !isNilValue(w) {
+		return named.Name()
+	}
+	return fmt.Sprintf("<%T>", w)
+}
+
+// isPlaceholderOutput reports whether name is a "<type>" writer placeholder.
+func isPlaceholderOutput(name string) bool {
+	return strings.HasPrefix(name, "<") && strings.HasSuffix(name, ">")
+}
+
+// MarshalJSON encodes the config in the configuration file schema (see
+// LoadSlogConfig) with defaults filled in. Writers are shown as named targets:
+// "stderr", "stdout", a file path, or "<type>" for writers without a name.
+// HandlerOptions.ReplaceAttr and CustomHandler cannot be serialized and are omitted.
+func (c SlogConfig) MarshalJSON() ([]byte, error) {
+	rc := c.resolve()
+	var sinks []sinkJSON
+	for _, sink := range c.Sinks {
+		handlerType := sink.HandlerType
+		if handlerType == "" {
+			handlerType = rc.HandlerType
+		}
+		level := sink.Level
+		if level == nil {
+			level = rc.Level
+		}
+		sinks = append(sinks, sinkJSON{
+			Handler: handlerType,
+			Output:  outputName(sink.Output),
+			Level:   level.Level().String(),
+		})
+	}
+	return json.Marshal(configJSON{
+		Handler:     rc.HandlerType,
+		Output:      outputName(rc.Output),
+		Destination: c.Destination,
+		Resource:    c.Resource,
+		Level:       rc.HandlerOptions.Level.Level().String(),
+		AddSource:   rc.HandlerOptions.AddSource,
+		Sinks:       sinks,
+		Rules:       c.Rules,
+		NameLevels:  c.NameLevels.String(),
+	})
+}
+
+// UnmarshalJSON decodes a config in the configuration file schema and applies
+// the keys present in data on top of the receiver. File outputs are opened for
+// appending; "<type>" writer placeholders leave Output unchanged.
+func (c *SlogConfig) UnmarshalJSON(data []byte) error {
+	var doc map[string]any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return err
+	}
+	if name, ok := doc[SettingOutput].(string); ok && isPlaceholderOutput(name) {
+		delete(doc, SettingOutput)
+	}
+	if _, ok := doc[SettingSinks]; ok {
+		c.Sinks = nil // sinks in data replace the receiver's sinks
+	}
+	opts, err := configDocOptions(doc)
+	if err != nil {
+		return err
+	}
+	if c.handlers == nil {
+		c.handlers = new(handlerCache)
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.touch()
+	return nil
+}
+
+// String returns the JSON form of the config.
+func (c SlogConfig) String() string {
+	data, err := c.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("SlogConfig(%v)", err)
+	}
+	return string(data)
+}
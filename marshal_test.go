@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	closed, err := os.CreateTemp(t.TempDir(), "closed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed.Close()
+
+	tests := []struct {
+		name string
+		cfg  SlogConfig
+		key  string
+	}{
+		{"defaults", NewSlogConfig(), ""},
+		{"zero value", SlogConfig{}, ""},
+		{"unknown handler", NewSlogConfig(WithHandlerType("jsn")), SettingHandler},
+		{"nil level var", NewSlogConfig(func(c *SlogConfig) { c.Level = (*slog.LevelVar)(nil) }), SettingLevel},
+		{"nil output", NewSlogConfig(WithOutput((*os.File)(nil))), SettingOutput},
+		{"closed output", NewSlogConfig(WithOutput(closed)), SettingOutput},
+		{"bad rule", NewSlogConfig(WithAttrRules(AttrRule{Key: "a", Action: "drop"})), "rules[0]"},
+		{"custom handler", NewSlogConfig(WithHandlerType("jsn"), WithCustomHandler(newTestHandler())), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.key == "" {
+				if err != nil {
+					t.Errorf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			var keyErr *ConfigKeyError
+			if !errors.As(err, &keyErr) || keyErr.Key != tt.key {
+				t.Errorf("Validate() = %v, want error for key %q", err, tt.key)
+			}
+		})
+	}
+}
+
+func TestValidateIncludesOptionErrors(t *testing.T) {
+	t.Setenv("GSLOG_LEVEL", "loud")
+	if err := NewSlogConfig(WithEnv("")).Validate(); err == nil {
+		t.Error("Validate() = nil, want recorded env error")
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	cfg := NewSlogConfig(
+		WithHandlerType("text"),
+		WithOutput(os.Stdout),
+		WithLevel(slog.LevelWarn),
+		WithAddSource(true),
+		WithAttrRules(AttrRule{Key: "password", Action: RuleRedact}),
+	)
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	want := `{"handler":"text","output":"stdout","level":"WARN","add_source":true,"rules":[{"key":"password","action":"redact"}]}`
+	if string(data) != want {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+	if cfg.String() != want {
+		t.Errorf("String() = %s, want %s", cfg.String(), want)
+	}
+}
+
+func TestMarshalJSONOutputNames(t *testing.T) {
+	f, err := os.Create(filepath.Join(t.TempDir(), "app.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	tests := []struct {
+		cfg  SlogConfig
+		want string
+	}{
+		{SlogConfig{}, "stderr"},
+		{NewSlogConfig(WithOutput(f)), f.Name()},
+		{NewSlogConfig(WithOutput(&bytes.Buffer{})), "<*bytes.Buffer>"},
+	}
+	for _, tt := range tests {
+		var doc configJSON
+		data, _ := json.Marshal(tt.cfg)
+		if err := json.Unmarshal(data, &doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.Output != tt.want {
+			t.Errorf("output = %q, want %q", doc.Output, tt.want)
+		}
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	orig := NewSlogConfig(
+		WithHandlerType("text"),
+		WithOutput(f),
+		WithLevel(slog.LevelDebug+2),
+		WithAddSource(true),
+		WithAttrRules(AttrRule{Key: "msg", Action: RuleRename, To: "message"}),
+	)
+	data, err := json.Marshal(orig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded SlogConfig
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if out, ok := decoded.Output.(*os.File); ok {
+		defer out.Close()
+	}
+	again, err := json.Marshal(decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(again) != string(data) {
+		t.Errorf("round trip = %s, want %s", again, data)
+	}
+}
+
+func TestUnmarshalJSONKeepsUnnamedWriter(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithOutput(&buf))
+	if err := json.Unmarshal([]byte(`{"output":"<*bytes.Buffer>","level":"DEBUG"}`), &cfg); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if cfg.Output != &buf {
+		t.Errorf("Output = %v, want unchanged buffer", cfg.Output)
+	}
+	if cfg.Level != slog.LevelDebug {
+		t.Errorf("Level = %v, want DEBUG", cfg.Level)
+	}
+}
+
+func TestUnmarshalJSONInvalid(t *testing.T) {
+	var cfg SlogConfig
+	err := json.Unmarshal([]byte(`{"level":"loud"}`), &cfg)
+	if err == nil || !strings.Contains(err.Error(), `"level"`) {
+		t.Errorf("Unmarshal error = %v, want level error", err)
+	}
+}
@@ -0,0 +1,139 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+)
+
+// Sink is one destination of a fan-out configuration (see WithSink).
+type Sink struct {
+	// HandlerType names a registered handler type. If empty, defaults to "json".
+	HandlerType string
+
+	// Output destination of the sink. If nil, defaults to os.Stderr.
+	Output io.Writer
+
+	// Minimum level of the sink. If nil, the config level is used, including
+	// any NameLevels override. Use a *slog.LevelVar to adjust it at runtime.
+	Level slog.Leveler
+}
+
+// WithSink returns a ConfigOption that adds a sink: records at or above level
+// are written to w using the registered handler type. Once a config has sinks,
+// HandlerType, Output and Level only serve as defaults for them; AddSource,
+// ReplaceAttr and Rules apply to every sink.
+//
+// For example, readable text on stderr at Info and JSON to a file at Debug:
+//
+//	cfg := NewSlogConfig(
+//		WithSink("text", os.Stderr, slog.LevelInfo),
+//		WithSink("json", file, slog.LevelDebug),
+//	)
+func WithSink(handlerType string, w io.Writer, level slog.Leveler) ConfigOption {
+	return func(cfg *SlogConfig) {
+		sinks := make([]Sink, 0, len(cfg.Sinks)+1)
+		sinks = append(sinks, cfg.Sinks...)
+		cfg.Sinks = append(sinks, Sink{HandlerType: handlerType, Output: w, Level: level})
+		cfg.markSet(SettingSinks)
+	}
+}
+
+// buildSinks builds one handler per sink of a resolved config and combines
+// them into a MultiHandler. The sinks without a level of their own share the
+// level variable of the config, which the MultiHandler exposes to LevelVarOf.
+func (c SlogConfig) buildSinks() (slog.Handler, error) {
+	c, lv := c.withLevelVar()
+	handlers := make([]slog.Handler, 0, len(c.Sinks))
+	var shared *slog.LevelVar
+	for _, sink := range c.Sinks {
+		sc := c
+		sc.Sinks = nil
+		if sink.HandlerType != "" {
+			sc.HandlerType = sink.HandlerType
+		}
+		sc.Output = sink.Output
+		if sc.Output == nil {
+			sc.Output = os.Stderr
+		}
+		if sink.Level != nil {
+			opts := *sc.HandlerOptions
+			opts.Level = sink.Level
+			sc.HandlerOptions = &opts
+			sc.Level = sink.Level
+		} else {
+			shared = lv
+		}
+		h, err := sc.buildResolved()
+		if err != nil {
+			return nil, err
+		}
+		handlers = append(handlers, h)
+	}
+	m := NewMultiHandler(handlers...)
+	m.level = shared
+	return m, nil
+}
+
+// MultiHandler fans records out to several handlers. A record is passed to
+// every handler that is enabled for its level; WithAttrs and WithGroup are
+// applied to all of them.
+type MultiHandler struct {
+	handlers []slog.Handler
+	level    *slog.LevelVar // of the sinks of a config that have no level
+}
+
+// NewMultiHandler returns a handler that fans records out to handlers.
+func NewMultiHandler(handlers ...slog.Handler) *MultiHandler {
+	return &MultiHandler{handlers: append([]slog.Handler(nil), handlers...)}
+}
+
+// Enabled reports whether any of the handlers is enabled for level.
+func (h *MultiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, next := range h.handlers {
+		if next.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes a copy of r to every handler enabled for its level and
+// returns the joined errors of all of them.
+func (h *MultiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, next := range h.handlers {
+		if !next.Enabled(ctx, r.Level) {
+			continue
+		}
+		if err := next.Handle(ctx, r.Clone()); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a MultiHandler whose handlers all carry attrs.
+func (h *MultiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, next := range h.handlers {
+		handlers[i] = next.WithAttrs(attrs)
+	}
+	return &MultiHandler{handlers: handlers, level: h.level}
+}
+
+// WithGroup returns a MultiHandler whose handlers all start the group name.
+func (h *MultiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, next := range h.handlers {
+		handlers[i] = next.WithGroup(name)
+	}
+	return &MultiHandler{handlers: handlers, level: h.level}
+}
+
+// Handlers returns the handlers records are fanned out to.
+func (h *MultiHandler) Handlers() []slog.Handler {
+	return append([]slog.Handler(nil), h.handlers...)
+}
@@ -0,0 +1,167 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMultiHandler(t *testing.T) {
+	a, b := newTestHandler(), newTestHandler()
+	logger := slog.New(NewMultiHandler(a, b))
+	logger.Info("hello", "k", "v")
+	for i, th := range []*testHandler{a, b} {
+		rec := th.lastRecord()
+		if rec == nil || rec.Message != "hello" || flattenRecord(rec)["k"] != "v" {
+			t.Errorf("handler %d got %v", i, rec)
+		}
+	}
+}
+
+func TestMultiHandlerPerHandlerLevels(t *testing.T) {
+	var info, debug bytes.Buffer
+	h := NewMultiHandler(
+		slog.NewTextHandler(&info, &slog.HandlerOptions{Level: slog.LevelInfo}),
+		slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+	)
+	if !h.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("Enabled(DEBUG) = false, want true")
+	}
+	slog.New(h).Debug("details")
+	if info.Len() != 0 {
+		t.Errorf("info handler got debug record: %q", info.String())
+	}
+	if !strings.Contains(debug.String(), "details") {
+		t.Errorf("debug handler missing record: %q", debug.String())
+	}
+}
+
+type failingHandler struct{ slog.Handler }
+
+func (failingHandler) Handle(context.Context, slog.Record) error { return errors.New("sink down") }
+
+func TestMultiHandlerErrors(t *testing.T) {
+	th := newTestHandler()
+	h := NewMultiHandler(failingHandler{newTestHandler()}, th)
+	err := h.Handle(context.Background(), slog.NewRecord(testTime, slog.LevelInfo, "msg", 0))
+	if err == nil || !strings.Contains(err.Error(), "sink down") {
+		t.Errorf("Handle error = %v", err)
+	}
+	if th.lastRecord() == nil {
+		t.Error("healthy handler skipped after failure")
+	}
+}
+
+func TestWithSink(t *testing.T) {
+	var text, js bytes.Buffer
+	cfg := NewSlogConfig(
+		WithSink("text", &text, slog.LevelInfo),
+		WithSink("json", &js, slog.LevelDebug),
+	)
+	logger := cfg.NewLogger().With("svc", "api").WithGroup("req")
+	logger.Debug("parsed", "id", 7)
+	logger.Info("served", "id", 7)
+
+	if out := text.String(); strings.Contains(out, "parsed") || !strings.Contains(out, "svc=api req.id=7") {
+		t.Errorf("text sink = %q", out)
+	}
+	lines := strings.Split(strings.TrimSpace(js.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("json sink has %d lines, want 2: %q", len(lines), js.String())
+	}
+	var record map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatal(err)
+	}
+	req, _ := record["req"].(map[string]any)
+	if record["svc"] != "api" || req["id"] != float64(7) {
+		t.Errorf("json record = %v", record)
+	}
+}
+
+func TestWithSinkLevelVar(t *testing.T) {
+	var buf bytes.Buffer
+	lv := new(slog.LevelVar)
+	lv.Set(slog.LevelError)
+	logger := NewSlogConfig(WithSink("text", &buf, lv)).NewLogger()
+	logger.Info("hidden")
+	lv.Set(slog.LevelInfo)
+	logger.Info("shown")
+	if out := buf.String(); strings.Contains(out, "hidden") || !strings.Contains(out, "shown") {
+		t.Errorf("output = %q", out)
+	}
+}
+
+func TestLevelVarOfSinks(t *testing.T) {
+	var text, js bytes.Buffer
+	logger := NewSlogConfig(
+		WithLevel(slog.LevelInfo),
+		WithSink("text", &text, nil),
+		WithSink("json", &js, slog.LevelDebug),
+	).NewLogger()
+	lv := LevelVarOf(logger)
+	if lv == nil {
+		t.Fatal("LevelVarOf = nil for a config with sinks")
+	}
+	lv.Set(slog.LevelWarn)
+	logger.Info("served")
+	if text.Len() != 0 || !strings.Contains(js.String(), "served") {
+		t.Errorf("text sink = %q, json sink = %q", text.String(), js.String())
+	}
+	lv.Set(slog.LevelDebug)
+	logger.With("svc", "api").Debug("parsed")
+	if !strings.Contains(text.String(), "parsed") {
+		t.Errorf("text sink = %q after lowering the level", text.String())
+	}
+
+	own := NewSlogConfig(WithSink("text", &text, slog.LevelInfo)).NewLogger()
+	if lv := LevelVarOf(own); lv != nil {
+		t.Errorf("LevelVarOf = %v, want nil when every sink has a level", lv)
+	}
+}
+
+func TestWithSinkUnknownType(t *testing.T) {
+	_, err := NewSlogConfig(WithSink("jsn", &bytes.Buffer{}, slog.LevelInfo)).BuildHandler()
+	if !errors.Is(err, ErrUnknownHandlerType) {
+		t.Errorf("BuildHandler error = %v, want ErrUnknownHandlerType", err)
+	}
+	var keyErr *ConfigKeyError
+	if err := NewSlogConfig(WithSink("jsn", nil, nil)).Validate(); !errors.As(err, &keyErr) || keyErr.Key != "sinks[0].handler" {
+		t.Errorf("Validate() = %v, want sinks[0].handler error", err)
+	}
+}
+
+func TestSinksConfigFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "debug.log")
+	doc := "sinks:\n  - handler: text\n    output: stderr\n    level: info\n  - handler: json\n    output: " + path + "\n    level: debug\n"
+	cfg, err := LoadSlogConfig(strings.NewReader(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Sinks) != 2 {
+		t.Fatalf("Sinks = %v", cfg.Sinks)
+	}
+	f, ok := cfg.Sinks[1].Output.(*os.File)
+	if !ok {
+		t.Fatalf("sink output = %T, want *os.File", cfg.Sinks[1].Output)
+	}
+	defer f.Close()
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"sinks":[{"handler":"text","output":"stderr","level":"INFO"},{"handler":"json","output":"` + path + `","level":"DEBUG"}]`
+	if !strings.Contains(string(data), want) {
+		t.Errorf("Marshal = %s, want %s", data, want)
+	}
+	if _, err := LoadSlogConfig(strings.NewReader("sinks:\n  - handler: text\n    level: loud\n")); err == nil {
+		t.Error("invalid sink level accepted")
+	}
+}
@@ -0,0 +1,204 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LoggerKey is the attribute key under which named loggers record their name.
+const LoggerKey = "logger"
+
+// NameLevelDefault is the NameLevels prefix that matches every logger name.
+const NameLevelDefault = "*"
+
+// Named returns a copy of the config for a child logger called name. Names
+// are joined with dots, so cfg.Named("db").Named("pool") builds loggers named
+// "db.pool". Loggers built from the returned config, with NewLogger or
+// NewStateful, record their name under LoggerKey and take their level from
+// NameLevels if a prefix matches.
+//
+// The returned config shares the handler chain of c (see BuildHandler).
+func (c SlogConfig) Named(name string) SlogConfig {
+	switch {
+	case name == "":
+	case c.Name == "":
+		c.Name = name
+	default:
+		c.Name = c.Name + "." + name
+	}
+	return c
+}
+
+// withNameAttr adds the logger name attribute to h if the config is named.
+func (c SlogConfig) withNameAttr(h slog.Handler) slog.Handler {
+	if c.Name == "" {
+		return h
+	}
+	return h.WithAttrs([]slog.Attr{slog.String(LoggerKey, c.Name)})
+}
+
+// loggerName follows the logger name through the WithAttrs and WithGroup
+// calls of a wrapper handler, so that the records the wrapper writes itself,
+// such as summaries, can carry it (see withNameAttr). Only a name added
+// outside any group counts.
+type loggerName struct {
+	name    string
+	grouped bool
+}
+
+func (n loggerName) withAttrs(attrs []slog.Attr) loggerName {
+	if n.grouped {
+		return n
+	}
+	for _, a := range attrs {
+		if a.Key == LoggerKey {
+			n.name = a.Value.String()
+		}
+	}
+	return n
+}
+
+func (n loggerName) withGroup(name string) loggerName {
+	if name != "" {
+		n.grouped = true
+	}
+	return n
+}
+
+// handler returns h with the name attribute added, if there is a name.
+func (n loggerName) handler(h slog.Handler) slog.Handler {
+	if n.name == "" {
+		return h
+	}
+	return h.WithAttrs([]slog.Attr{slog.String(LoggerKey, n.name)})
+}
+
+// NameLevels holds per-name level overrides for named loggers. Each prefix
+// owns a *slog.LevelVar shared by every logger it matches, so changing it
+// adjusts the whole subtree at runtime. Loggers resolve their prefix when
+// they are built; prefixes added later only affect loggers built afterwards.
+//
+// A NameLevels is safe for concurrent use.
+type NameLevels struct {
+	mu     sync.RWMutex
+	levels map[string]*slog.LevelVar
+}
+
+// ParseNameLevels parses a comma-separated list of prefix=level pairs, such as
+// "db=debug,http=warn,*=info". A prefix matches a name equal to it or starting
+// with it followed by a dot; the longest matching prefix wins and "*" matches
+// every name, including the unnamed root logger.
+func ParseNameLevels(spec string) (*NameLevels, error) {
+	n := &NameLevels{levels: make(map[string]*slog.LevelVar)}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		prefix, text, ok := strings.Cut(part, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || prefix == "" {
+			return nil, fmt.Errorf("invalid name level %q: want prefix=level", part)
+		}
+		level, err := parseLevel(text)
+		if err != nil {
+			return nil, fmt.Errorf("name level %q: %w", prefix, err)
+		}
+		n.Set(prefix, level)
+	}
+	return n, nil
+}
+
+// WithNameLevels returns a ConfigOption that sets per-name level overrides
+// parsed with ParseNameLevels. A malformed spec is recorded as an error on the
+// config (see SlogConfig.Err) and leaves NameLevels unchanged.
+func WithNameLevels(spec string) ConfigOption {
+	return func(cfg *SlogConfig) {
+		n, err := ParseNameLevels(spec)
+		if err != nil {
+			cfg.addErr(&ConfigKeyError{Key: SettingNameLevels, Err: err})
+			return
+		}
+		cfg.NameLevels = n
+		cfg.markSet(SettingNameLevels)
+	}
+}
+
+// Set sets the level of prefix, adding the prefix if needed.
+func (n *NameLevels) Set(prefix string, level slog.Level) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.levels == nil {
+		n.levels = make(map[string]*slog.LevelVar)
+	}
+	lv, ok := n.levels[prefix]
+	if !ok {
+		lv = new(slog.LevelVar)
+		n.levels[prefix] = lv
+	}
+	lv.Set(level)
+}
+
+// LevelVar returns the variable of prefix, or nil if prefix is not configured.
+func (n *NameLevels) LevelVar(prefix string) *slog.LevelVar {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return n.levels[prefix]
+}
+
+// Prefixes returns the configured prefixes in sorted order.
+func (n *NameLevels) Prefixes() []string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	prefixes := make([]string, 0, len(n.levels))
+	for prefix := range n.levels {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// String returns the overrides in the form accepted by ParseNameLevels.
+func (n *NameLevels) String() string {
+	if n == nil {
+		return ""
+	}
+	prefixes := n.Prefixes()
+	parts := make([]string, len(prefixes))
+	for i, prefix := range prefixes {
+		parts[i] = prefix + "=" + n.LevelVar(prefix).Level().String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// match returns the variable of the longest prefix matching name, falling
+// back to "*". It returns nil if n is nil or nothing matches.
+func (n *NameLevels) match(name string) *slog.LevelVar {
+	if n == nil {
+		return nil
+	}
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	for candidate := name; candidate != ""; {
+		if lv, ok := n.levels[candidate]; ok {
+			return lv
+		}
+		i := strings.LastIndexByte(candidate, '.')
+		if i < 0 {
+			break
+		}
+		candidate = candidate[:i]
+	}
+	return n.levels[NameLevelDefault]
+}
+
+// RegisterNameLevels registers the variable of every prefix of n under the
+// prefix itself, so the admin endpoint can adjust whole subtrees of named loggers.
+func (a *LevelAdmin) RegisterNameLevels(n *NameLevels) {
+	for _, prefix := range n.Prefixes() {
+		a.Register(prefix, n.LevelVar(prefix))
+	}
+}
@@ -0,0 +1,166 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	cfg := NewSlogConfig()
+	if got := cfg.Named("db").Named("pool").Name; got != "db.pool" {
+		t.Errorf("Name = %q, want db.pool", got)
+	}
+	if got := cfg.Named("").Name; got != "" {
+		t.Errorf("Name = %q, want empty", got)
+	}
+	if cfg.Name != "" {
+		t.Error("Named modified the receiver")
+	}
+}
+
+func TestNamedLoggerAttribute(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithOutput(&buf))
+	cfg.Named("db").Named("pool").NewLogger().Info("connected")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+	if record[LoggerKey] != "db.pool" {
+		t.Errorf("%s = %v, want db.pool", LoggerKey, record[LoggerKey])
+	}
+}
+
+func TestNamedCustomHandler(t *testing.T) {
+	th := newTestHandler()
+	cfg := NewSlogConfig(WithCustomHandler(th)).Named("http")
+	cfg.NewLogger().Info("msg")
+	if attrs := flattenRecord(th.lastRecord()); attrs[LoggerKey] != "http" {
+		t.Errorf("attrs = %v, want %s=http", attrs, LoggerKey)
+	}
+}
+
+func TestParseNameLevels(t *testing.T) {
+	n, err := ParseNameLevels("db=debug, http=warn ,*=info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]slog.Level{
+		"db":          slog.LevelDebug,
+		"db.pool":     slog.LevelDebug,
+		"http.server": slog.LevelWarn,
+		"dbx":         slog.LevelInfo,
+		"":            slog.LevelInfo,
+	}
+	for name, want := range tests {
+		if lv := n.match(name); lv == nil || lv.Level() != want {
+			t.Errorf("match(%q) = %v, want %v", name, lv, want)
+		}
+	}
+	if got := n.String(); got != "*=INFO,db=DEBUG,http=WARN" {
+		t.Errorf("String() = %q", got)
+	}
+	for _, bad := range []string{"db", "=debug", "db=loud"} {
+		if _, err := ParseNameLevels(bad); err == nil {
+			t.Errorf("ParseNameLevels(%q) succeeded, want error", bad)
+		}
+	}
+}
+
+func TestNameLevelsLongestPrefix(t *testing.T) {
+	n, _ := ParseNameLevels("db=warn,db.pool=debug")
+	if lv := n.match("db.pool.conn"); lv.Level() != slog.LevelDebug {
+		t.Errorf("db.pool.conn level = %v, want DEBUG", lv.Level())
+	}
+	if lv := n.match("db.query"); lv.Level() != slog.LevelWarn {
+		t.Errorf("db.query level = %v, want WARN", lv.Level())
+	}
+	if lv := n.match("http"); lv != nil {
+		t.Errorf("http matched %v without a default", lv)
+	}
+}
+
+func TestNamedLevels(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithHandlerType("text"), WithOutput(&buf), WithNameLevels("db=debug,http=warn"))
+	db := cfg.Named("db").Named("pool").NewLogger()
+	web := cfg.Named("http").NewLogger()
+	other := cfg.Named("cache").NewLogger()
+	ctx := context.Background()
+
+	if !db.Enabled(ctx, slog.LevelDebug) {
+		t.Error("db.pool not enabled at DEBUG")
+	}
+	if web.Enabled(ctx, slog.LevelInfo) {
+		t.Error("http enabled at INFO")
+	}
+	if !other.Enabled(ctx, slog.LevelInfo) || other.Enabled(ctx, slog.LevelDebug) {
+		t.Error("cache does not use the config level")
+	}
+
+	cfg.NameLevels.LevelVar("http").Set(slog.LevelDebug)
+	if !web.Enabled(ctx, slog.LevelDebug) {
+		t.Error("http did not follow runtime change of its prefix")
+	}
+	if LevelVarOf(web) != cfg.NameLevels.LevelVar("http") {
+		t.Error("LevelVarOf does not return the prefix variable")
+	}
+}
+
+func TestNamedStateful(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithOutput(&buf), WithNameLevels("billing=debug"))
+	logger := NewStateful(cfg.Named("billing").Named("invoices"), &testPerson{Name: "Alice"})
+	logger.Debug("created")
+
+	var record map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("no debug record: %q", buf.String())
+	}
+	if record[LoggerKey] != "billing.invoices" {
+		t.Errorf("%s = %v", LoggerKey, record[LoggerKey])
+	}
+	if _, ok := record["testPerson"]; !ok {
+		t.Error("state group missing")
+	}
+}
+
+func TestWithNameLevelsInvalid(t *testing.T) {
+	cfg := NewSlogConfig(WithNameLevels("db"))
+	var keyErr *ConfigKeyError
+	if !errors.As(cfg.Err(), &keyErr) || keyErr.Key != SettingNameLevels {
+		t.Errorf("Err() = %v, want %s error", cfg.Err(), SettingNameLevels)
+	}
+}
+
+func TestNameLevelsConfigFile(t *testing.T) {
+	cfg, err := LoadSlogConfig(strings.NewReader(`name_levels: "db=debug,*=warn"`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.NameLevels.String(); got != "*=WARN,db=DEBUG" {
+		t.Errorf("NameLevels = %q", got)
+	}
+	data, _ := json.Marshal(cfg)
+	if !strings.Contains(string(data), `"name_levels":"*=WARN,db=DEBUG"`) {
+		t.Errorf("Marshal = %s", data)
+	}
+}
+
+func TestLevelAdminRegisterNameLevels(t *testing.T) {
+	n, _ := ParseNameLevels("db=debug,http=warn")
+	admin := NewLevelAdmin()
+	admin.RegisterNameLevels(n)
+	if err := admin.SetLevel("http", slog.LevelError, 0); err != nil {
+		t.Fatal(err)
+	}
+	if n.LevelVar("http").Level() != slog.LevelError {
+		t.Error("admin change did not reach the name prefix")
+	}
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultDialTimeout bounds connection attempts of network handlers.
+const DefaultDialTimeout = 5 * time.Second
+
+// parseDestination splits a destination such as "udp://localhost:514",
+// "tcp://logs.internal:6514" or "unixgram:///dev/log" into the network and
+// address passed to net.Dial. Only the given networks are accepted.
+func parseDestination(dest string, networks ...string) (network, address string, err error) {
+	network, address, ok := strings.Cut(dest, "://")
+	if !ok {
+		return "", "", fmt.Errorf("invalid destination %q: missing scheme, e.g. %s://", dest, networks[0])
+	}
+	if !slices.Contains(networks, network) {
+		return "", "", fmt.Errorf("invalid destination %q: unsupported scheme %q (want one of %s)",
+			dest, network, strings.Join(networks, ", "))
+	}
+	switch network {
+	case "unix", "unixgram":
+		if address == "" {
+			return "", "", fmt.Errorf("invalid destination %q: missing socket path", dest)
+		}
+	default:
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return "", "", fmt.Errorf("invalid destination %q: %w", dest, err)
+		}
+	}
+	return network, address, nil
+}
+
+// parseHTTPDestination checks that dest is an http or https URL with a host.
+// If its path is empty or "/", defaultPath is used.
+func parseHTTPDestination(dest, defaultPath string) (string, error) {
+	u, err := url.Parse(dest)
+	if err != nil {
+		return "", fmt.Errorf("invalid destination %q: %w", dest, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("invalid destination %q: unsupported scheme %q (want http or https)", dest, u.Scheme)
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("invalid destination %q: missing host", dest)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = defaultPath
+	}
+	return u.String(), nil
+}
+
+// reconnConn is a connection that is dialed on first use and dialed again
+// after a write fails, so that a restarted server is picked up without
+// restarting the logger. It is safe for concurrent use.
+type reconnConn struct {
+	network string
+	address string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	conn   net.Conn
+	closed bool
+}
+
+// newReconnConn returns an unconnected reconnConn.
+func newReconnConn(network, address string) *reconnConn {
+	return &reconnConn{network: network, address: address, timeout: DefaultDialTimeout}
+}
+
+// write sends each packet over the connection, in order. If a write fails,
+// the connection is dialed again and the remaining packets are retried once.
+func (c *reconnConn) write(packets ...[]byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return net.ErrClosed
+	}
+	for retried := false; ; retried = true {
+		err := c.writeLocked(&packets)
+		if err == nil || retried {
+			return err
+		}
+	}
+}
+
+// writeLocked dials if needed and writes packets, removing the ones written.
+func (c *reconnConn) writeLocked(packets *[][]byte) error {
+	if c.conn == nil {
+		conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+		if err != nil {
+			return err
+		}
+		c.conn = conn
+	}
+	for len(*packets) > 0 {
+		if _, err := c.conn.Write((*packets)[0]); err != nil {
+			c.conn.Close()
+			c.conn = nil
+			return err
+		}
+		*packets = (*packets)[1:]
+	}
+	return nil
+}
+
+// Close closes the connection. Later writes fail with net.ErrClosed.
+func (c *reconnConn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	if errors.Is(err, net.ErrClosed) {
+		err = nil
+	}
+	return err
+}
@@ -0,0 +1,459 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultOTLPDestination is the destination of the "otlp" handler type when
+// SlogConfig.Destination is empty: the OTLP/HTTP logs endpoint of a collector
+// on this host.
+const DefaultOTLPDestination = "http://localhost:4318" + otlpLogsPath
+
+// DefaultOTLPScopeName is the instrumentation scope name used when
+// OTLPOptions.ScopeName is empty.
+const DefaultOTLPScopeName = "github.com/Galdoba/logger"
+
+// otlpLogsPath is used when a destination has no path.
+const otlpLogsPath = "/v1/logs"
+
+// OTLPOptions configure an OTLPHandler.
+type OTLPOptions struct {
+	slog.HandlerOptions
+
+	// Resource holds the resource attributes sent with every batch, such as
+	// service.name and deployment.environment. If it has no service.name,
+	// "unknown_service:" followed by the executable name is used, as the
+	// OpenTelemetry SDKs do.
+	Resource map[string]string
+
+	// ScopeName is the name of the instrumentation scope of the records.
+	// If empty, DefaultOTLPScopeName is used.
+	ScopeName string
+
+	// TraceID and SpanID extract the ids of the current span from the context
+	// passed to Handle. An id may be a hex string, a byte slice or array, or a
+	// fmt.Stringer that returns hex, such as the ids of the OpenTelemetry
+	// trace API. Zero fields extract nothing; invalid ids are ignored.
+	TraceID, SpanID ContextField
+
+	// Batch configures batching and retries.
+	Batch BatchOptions
+
+	// Gzip compresses request bodies.
+	Gzip bool
+
+	// Header is added to every request, e.g. Authorization.
+	Header http.Header
+
+	// Client sends the requests. If nil, a client with DefaultHTTPTimeout is used.
+	Client *http.Client
+}
+
+// OTLPHandler batches records and exports them as OpenTelemetry log records
+// over OTLP/HTTP with JSON encoding:
+//
+//	{"resourceLogs":[{"resource":{"attributes":[{"key":"service.name","value":{"stringValue":"shop"}}]},
+//	 "scopeLogs":[{"scope":{"name":"github.com/Galdoba/logger"},"logRecords":[
+//	 {"timeUnixNano":"1792153845123456789","observedTimeUnixNano":"1792153845123480000",
+//	  "severityNumber":9,"severityText":"INFO","body":{"stringValue":"paid"},
+//	  "attributes":[{"key":"amount","value":{"intValue":"42"}}],
+//	  "traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174"}]}]}]}
+//
+// The level maps to the severity number as in the OpenTelemetry slog bridge:
+// DEBUG is 5, INFO 9, WARN 13 and ERROR 17, with offsets kept. The message
+// becomes the body, the source the code.file.path, code.line.number and
+// code.function.name attributes. Groups become kvlist values; slices, arrays
+// and maps become array and kvlist values. Built-in attributes renamed by
+// ReplaceAttr are sent as attributes under their new name.
+//
+// Records rejected by the collector in a partial success response are counted
+// as failed and reported through BatchOptions.OnError.
+//
+// Handlers derived with WithAttrs and WithGroup share the batches of their
+// parent. Call Flush to wait for buffered records and Close to send the rest
+// and stop the sender.
+//
+// It is registered as the "otlp" handler type, which reads the endpoint from
+// SlogConfig.Destination and the resource attributes from SlogConfig.Resource
+// (see WithDestination and WithResource).
+type OTLPHandler struct {
+	batch           *batcher[[]byte]
+	traceID, spanID ContextField
+	level           slog.Leveler
+	addSource       bool
+	scope           attrScope
+}
+
+// NewOTLPHandler returns an OTLPHandler that sends records to url. If url has
+// no path, "/v1/logs" is used. A nil opts uses the defaults. It fails only if
+// url is malformed; request errors are reported through BatchOptions.OnError
+// and Stats.
+func NewOTLPHandler(url string, opts *OTLPOptions) (*OTLPHandler, error) {
+	url, err := parseHTTPDestination(url, otlpLogsPath)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &OTLPOptions{}
+	}
+	prefix, err := otlpPayloadPrefix(opts.Resource, opts.ScopeName)
+	if err != nil {
+		return nil, err
+	}
+	sender := newHTTPSender(opts.Client, url, opts.Header, opts.Gzip)
+	h := &OTLPHandler{
+		traceID:   opts.TraceID,
+		spanID:    opts.SpanID,
+		level:     opts.Level,
+		addSource: opts.AddSource,
+		scope:     attrScope{replace: opts.ReplaceAttr},
+	}
+	if h.level == nil {
+		h.level = slog.LevelInfo
+	}
+	h.batch = newBatcher(opts.Batch, func(ctx context.Context, items [][]byte) error {
+		var body bytes.Buffer
+		body.Write(prefix)
+		body.Write(bytes.Join(items, []byte{','}))
+		body.WriteString("]}]}]}")
+		resp, err := sender.post(ctx, "application/json", body.Bytes())
+		if err != nil {
+			return err
+		}
+		return checkOTLPResponse(resp, len(items))
+	})
+	return h, nil
+}
+
+func (h *OTLPHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *OTLPHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope.withAttrs(attrs)
+	return &h2
+}
+
+func (h *OTLPHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.scope = h.scope.withGroup(name)
+	return &h2
+}
+
+// Handle encodes r as a log record and buffers it for the next batch. It
+// returns ErrBatchClosed after Close.
+func (h *OTLPHandler) Handle(ctx context.Context, r slog.Record) error {
+	rec := otlpLogRecord{
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       otlpSeverity(r.Level),
+	}
+	attrs := &attrNode{children: []*attrNode{}}
+	if !r.Time.IsZero() {
+		if a, ok := h.scope.builtin(slog.Time(slog.TimeKey, r.Time)); ok {
+			if a.Key == slog.TimeKey && a.Value.Kind() == slog.KindTime {
+				rec.TimeUnixNano = strconv.FormatInt(a.Value.Time().UnixNano(), 10)
+			} else {
+				attrs.insert(nil, a.Key, a.Value)
+			}
+		}
+	}
+	if a, ok := h.scope.builtin(slog.Any(slog.LevelKey, r.Level)); ok {
+		if a.Key == slog.LevelKey {
+			rec.SeverityText = a.Value.String()
+		} else {
+			attrs.insert(nil, a.Key, a.Value)
+		}
+	}
+	if a, ok := h.scope.builtin(slog.String(slog.MessageKey, r.Message)); ok {
+		if a.Key == slog.MessageKey {
+			body := otlpAnyValue(a.Value)
+			rec.Body = &body
+		} else {
+			attrs.insert(nil, a.Key, a.Value)
+		}
+	}
+	if h.addSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		src := &slog.Source{Function: frame.Function, File: frame.File, Line: frame.Line}
+		if a, ok := h.scope.builtin(slog.Any(slog.SourceKey, src)); ok {
+			if src, isSource := a.Value.Any().(*slog.Source); a.Key == slog.SourceKey && isSource {
+				attrs.insert(nil, "code.file.path", slog.StringValue(src.File))
+				attrs.insert(nil, "code.line.number", slog.IntValue(src.Line))
+				attrs.insert(nil, "code.function.name", slog.StringValue(src.Function))
+			} else {
+				attrs.insert(nil, a.Key, a.Value)
+			}
+		}
+	}
+	h.scope.each(r, func(groups []string, a slog.Attr) {
+		attrs.insert(groups, a.Key, a.Value)
+	})
+	rec.Attributes = attrs.otlpKeyValues()
+	if ctx != nil {
+		rec.TraceID = otlpID(ctx, h.traceID, 16)
+		rec.SpanID = otlpID(ctx, h.spanID, 8)
+	}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return h.batch.add(data, len(data))
+}
+
+// Flush sends the buffered records and waits until they have been exported
+// or given up, or until ctx is done.
+func (h *OTLPHandler) Flush(ctx context.Context) error {
+	return h.batch.flush(ctx)
+}
+
+// Close stops accepting records, sends the buffered ones and stops the
+// sender goroutine. It is safe to call more than once.
+func (h *OTLPHandler) Close() error {
+	h.batch.close()
+	return nil
+}
+
+// Stats returns a snapshot of the handler's counters.
+func (h *OTLPHandler) Stats() BatchStats {
+	return h.batch.snapshot()
+}
+
+// otlpLogRecord is the JSON form of an OTLP LogRecord. 64-bit integers are
+// strings, as in the protobuf JSON mapping.
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano,omitempty"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText,omitempty"`
+	Body                 *otlpValue     `json:"body,omitempty"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string    `json:"key"`
+	Value otlpValue `json:"value"`
+}
+
+// otlpValue is the JSON form of an OTLP AnyValue; at most one field is set.
+type otlpValue struct {
+	StringValue *string     `json:"stringValue,omitempty"`
+	BoolValue   *bool       `json:"boolValue,omitempty"`
+	IntValue    *string     `json:"intValue,omitempty"`
+	DoubleValue *float64    `json:"doubleValue,omitempty"`
+	BytesValue  []byte      `json:"bytesValue,omitempty"`
+	ArrayValue  *otlpArray  `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKvlist `json:"kvlistValue,omitempty"`
+}
+
+type otlpArray struct {
+	Values []otlpValue `json:"values"`
+}
+
+type otlpKvlist struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+// otlpKeyValues converts the children of a group node, with groups as
+// kvlist values.
+func (n *attrNode) otlpKeyValues() []otlpKeyValue {
+	var kvs []otlpKeyValue
+	for _, child := range n.children {
+		kv := otlpKeyValue{Key: child.key}
+		if child.children != nil {
+			kv.Value.KvlistValue = &otlpKvlist{Values: child.otlpKeyValues()}
+		} else {
+			kv.Value = otlpAnyValue(child.value)
+		}
+		kvs = append(kvs, kv)
+	}
+	return kvs
+}
+
+// otlpAnyValue converts a value. Durations are sent as nanoseconds, times as
+// RFC 3339 strings, and values without an OTLP counterpart as their text.
+func otlpAnyValue(v slog.Value) otlpValue {
+	v = v.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		s := v.String()
+		return otlpValue{StringValue: &s}
+	case slog.KindInt64:
+		s := strconv.FormatInt(v.Int64(), 10)
+		return otlpValue{IntValue: &s}
+	case slog.KindUint64:
+		if u := v.Uint64(); u <= math.MaxInt64 {
+			s := strconv.FormatUint(u, 10)
+			return otlpValue{IntValue: &s}
+		}
+	case slog.KindFloat64:
+		if f := v.Float64(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return otlpValue{DoubleValue: &f}
+		}
+	case slog.KindBool:
+		b := v.Bool()
+		return otlpValue{BoolValue: &b}
+	case slog.KindDuration:
+		s := strconv.FormatInt(v.Duration().Nanoseconds(), 10)
+		return otlpValue{IntValue: &s}
+	case slog.KindGroup:
+		var kvs []otlpKeyValue
+		for _, a := range v.Group() {
+			kvs = append(kvs, otlpKeyValue{Key: a.Key, Value: otlpAnyValue(a.Value)})
+		}
+		return otlpValue{KvlistValue: &otlpKvlist{Values: kvs}}
+	case slog.KindAny:
+		switch x := v.Any().(type) {
+		case error, fmt.Stringer:
+		case []byte:
+			return otlpValue{BytesValue: x}
+		default:
+			if rv := reflect.ValueOf(x); rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+				values := make([]otlpValue, rv.Len())
+				for i := range values {
+					values[i] = otlpAnyValue(slog.AnyValue(rv.Index(i).Interface()))
+				}
+				return otlpValue{ArrayValue: &otlpArray{Values: values}}
+			} else if rv.Kind() == reflect.Map {
+				var kvs []otlpKeyValue
+				for iter := rv.MapRange(); iter.Next(); {
+					kvs = append(kvs, otlpKeyValue{Key: fmt.Sprint(iter.Key().Interface()), Value: otlpAnyValue(slog.AnyValue(iter.Value().Interface()))})
+				}
+				slices.SortFunc(kvs, func(a, b otlpKeyValue) int { return strings.Compare(a.Key, b.Key) })
+				return otlpValue{KvlistValue: &otlpKvlist{Values: kvs}}
+			}
+		}
+	}
+	s := syslogValue(v)
+	return otlpValue{StringValue: &s}
+}
+
+// otlpSeverity maps a level to an OTLP severity number between 1 and 24.
+func otlpSeverity(level slog.Level) int {
+	return min(max(int(level)+9, 1), 24)
+}
+
+// otlpID extracts a trace or span id of size bytes with f and returns it as
+// lowercase hex, or "" if there is none or it is invalid.
+func otlpID(ctx context.Context, f ContextField, size int) string {
+	if f.Key == "" && f.Extractor == nil {
+		return ""
+	}
+	var id []byte
+	switch v := f.value(ctx).(type) {
+	case nil:
+		return ""
+	case []byte:
+		id = v
+	case string:
+		id, _ = hex.DecodeString(v)
+	case fmt.Stringer:
+		id, _ = hex.DecodeString(v.String())
+	default:
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Array && rv.Type().Elem().Kind() == reflect.Uint8 {
+			id = make([]byte, rv.Len())
+			reflect.Copy(reflect.ValueOf(id), rv)
+		}
+	}
+	if len(id) != size || !slices.ContainsFunc(id, func(b byte) bool { return b != 0 }) {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// otlpPayloadPrefix encodes the start of an export request, up to the
+// opening bracket of the log records.
+func otlpPayloadPrefix(resource map[string]string, scopeName string) ([]byte, error) {
+	if scopeName == "" {
+		scopeName = DefaultOTLPScopeName
+	}
+	var attrs []otlpKeyValue
+	for _, k := range slices.Sorted(maps.Keys(resource)) {
+		v := resource[k]
+		attrs = append(attrs, otlpKeyValue{Key: k, Value: otlpValue{StringValue: &v}})
+	}
+	if _, ok := resource["service.name"]; !ok {
+		service := "unknown_service:" + filepath.Base(os.Args[0])
+		attrs = append(attrs, otlpKeyValue{Key: "service.name", Value: otlpValue{StringValue: &service}})
+	}
+	res, err := json.Marshal(map[string]any{"attributes": attrs})
+	if err != nil {
+		return nil, err
+	}
+	scope, err := json.Marshal(map[string]string{"name": scopeName})
+	if err != nil {
+		return nil, err
+	}
+	return fmt.Appendf(nil, `{"resourceLogs":[{"resource":%s,"scopeLogs":[{"scope":%s,"logRecords":[`, res, scope), nil
+}
+
+// checkOTLPResponse reports the records a collector rejected in a partial
+// success response as failed.
+func checkOTLPResponse(body []byte, records int) error {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil
+	}
+	var resp struct {
+		PartialSuccess struct {
+			RejectedLogRecords json.Number `json:"rejectedLogRecords"`
+			ErrorMessage       string      `json:"errorMessage"`
+		} `json:"partialSuccess"`
+	}
+	// Collectors may answer in protobuf despite a JSON request; anything
+	// that is not a partial success is taken as full success.
+	if json.Unmarshal(body, &resp) != nil {
+		return nil
+	}
+	rejected, _ := strconv.Atoi(strings.Trim(string(resp.PartialSuccess.RejectedLogRecords), `"`))
+	if rejected <= 0 {
+		return nil
+	}
+	rejected = min(rejected, records)
+	return &partialError[[]byte]{failed: rejected, err: fmt.Errorf("logger: OTLP export: %d of %d log records rejected: %s", rejected, records, resp.PartialSuccess.ErrorMessage)}
+}
+
+// parseResource parses resource attributes in the format of the
+// OTEL_RESOURCE_ATTRIBUTES variable: comma-separated key=value pairs with
+// percent-encoded values, such as "service.name=shop,deployment.environment=prod".
+func parseResource(spec string) (map[string]string, error) {
+	resource := make(map[string]string)
+	for pair := range strings.SplitSeq(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		k = strings.TrimSpace(k)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid resource attribute %q: want key=value", pair)
+		}
+		v, err := url.PathUnescape(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("invalid resource attribute %q: %w", pair, err)
+		}
+		resource[k] = v
+	}
+	return resource, nil
+}
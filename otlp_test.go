@@ -0,0 +1,248 @@
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type otlpExportRequest struct {
+	ResourceLogs []struct {
+		Resource struct {
+			Attributes []otlpKeyValue `json:"attributes"`
+		} `json:"resource"`
+		ScopeLogs []struct {
+			Scope struct {
+				Name string `json:"name"`
+			} `json:"scope"`
+			LogRecords []otlpLogRecord `json:"logRecords"`
+		} `json:"scopeLogs"`
+	} `json:"resourceLogs"`
+}
+
+// otlpCollector is a stand-in for the logs endpoint of a collector. It
+// answers with response if it is set.
+type otlpCollector struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []otlpExportRequest
+	response string
+}
+
+func newOTLPCollector(t *testing.T) *otlpCollector {
+	c := &otlpCollector{}
+	c.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/logs" || r.Header.Get("Content-Type") != "application/json" {
+			http.Error(w, "unexpected request", http.StatusNotFound)
+			return
+		}
+		var req otlpExportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.requests = append(c.requests, req)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(c.response))
+	}))
+	t.Cleanup(c.Close)
+	return c
+}
+
+// records returns the log records of all requests.
+func (c *otlpCollector) records() []otlpLogRecord {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var records []otlpLogRecord
+	for _, req := range c.requests {
+		for _, rl := range req.ResourceLogs {
+			for _, sl := range rl.ScopeLogs {
+				records = append(records, sl.LogRecords...)
+			}
+		}
+	}
+	return records
+}
+
+// otlpAttrs indexes key-value pairs by key, with values as JSON.
+func otlpAttrs(kvs []otlpKeyValue) map[string]string {
+	m := make(map[string]string)
+	for _, kv := range kvs {
+		data, _ := json.Marshal(kv.Value)
+		m[kv.Key] = string(data)
+	}
+	return m
+}
+
+type otlpSpanKey struct{}
+
+func TestOTLPHandlerExport(t *testing.T) {
+	c := newOTLPCollector(t)
+	h, err := NewOTLPHandler(c.URL, &OTLPOptions{
+		HandlerOptions: slog.HandlerOptions{AddSource: true},
+		Resource:       map[string]string{"service.name": "shop", "deployment.environment": "prod"},
+		TraceID: ExtractorContextField("trace_id", func(ctx context.Context) any {
+			return ctx.Value(otlpSpanKey{}).([2]string)[0]
+		}),
+		SpanID: ExtractorContextField("span_id", func(ctx context.Context) any {
+			return []byte(ctx.Value(otlpSpanKey{}).([2]string)[1])
+		}),
+		Batch: BatchOptions{Interval: time.Hour},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+	ctx := context.WithValue(t.Context(), otlpSpanKey{}, [2]string{"5b8efff798038103d269b633813fc60c", "spanid01"})
+	logger := slog.New(h).With("order", 7).WithGroup("req")
+	logger.InfoContext(ctx, "paid", "amount", 4.5, "ok", true, "took", time.Millisecond, "tags", []string{"a", "b"})
+	slog.New(h).Log(ctx, slog.LevelWarn+2, "retrying", "user", slog.GroupValue(slog.String("name", "Alice")))
+	if err := h.Flush(t.Context()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(c.requests) != 1 {
+		t.Fatalf("got %d requests, want 1", len(c.requests))
+	}
+	rl := c.requests[0].ResourceLogs[0]
+	if got := otlpAttrs(rl.Resource.Attributes); got["service.name"] != `{"stringValue":"shop"}` || got["deployment.environment"] != `{"stringValue":"prod"}` {
+		t.Errorf("resource = %v", got)
+	}
+	if rl.ScopeLogs[0].Scope.Name != DefaultOTLPScopeName {
+		t.Errorf("scope = %q", rl.ScopeLogs[0].Scope.Name)
+	}
+	records := c.records()
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2", len(records))
+	}
+	rec := records[0]
+	if rec.SeverityNumber != 9 || rec.SeverityText != "INFO" || rec.Body == nil || *rec.Body.StringValue != "paid" {
+		t.Errorf("record = %+v", rec)
+	}
+	if rec.TraceID != "5b8efff798038103d269b633813fc60c" || rec.SpanID != "7370616e69643031" {
+		t.Errorf("traceId = %q, spanId = %q", rec.TraceID, rec.SpanID)
+	}
+	if len(rec.TimeUnixNano) != 19 || len(rec.ObservedTimeUnixNano) != 19 {
+		t.Errorf("timeUnixNano = %q, observedTimeUnixNano = %q", rec.TimeUnixNano, rec.ObservedTimeUnixNano)
+	}
+	attrs := otlpAttrs(rec.Attributes)
+	for k, want := range map[string]string{
+		"order": `{"intValue":"7"}`,
+		"req":   `{"kvlistValue":{"values":[{"key":"amount","value":{"doubleValue":4.5}},{"key":"ok","value":{"boolValue":true}},{"key":"took","value":{"intValue":"1000000"}},{"key":"tags","value":{"arrayValue":{"values":[{"stringValue":"a"},{"stringValue":"b"}]}}}]}}`,
+	} {
+		if attrs[k] != want {
+			t.Errorf("attribute %s = %s, want %s", k, attrs[k], want)
+		}
+	}
+	if !strings.HasSuffix(attrs["code.file.path"], `otlp_test.go"}`) || !strings.Contains(attrs["code.function.name"], "TestOTLPHandlerExport") {
+		t.Errorf("source attributes = %v", attrs)
+	}
+
+	rec = records[1]
+	if rec.SeverityNumber != 15 || rec.SeverityText != "WARN+2" {
+		t.Errorf("severity = %d %q", rec.SeverityNumber, rec.SeverityText)
+	}
+	if got := otlpAttrs(rec.Attributes)["user"]; got != `{"kvlistValue":{"values":[{"key":"name","value":{"stringValue":"Alice"}}]}}` {
+		t.Errorf("user = %s", got)
+	}
+}
+
+func TestOTLPHandlerPartialSuccess(t *testing.T) {
+	c := newOTLPCollector(t)
+	c.response = `{"partialSuccess":{"rejectedLogRecords":"1","errorMessage":"attribute too long"}}`
+	var reported []error
+	h, err := NewOTLPHandler(c.URL, &OTLPOptions{
+		Batch: BatchOptions{Interval: time.Hour, OnError: func(err error) { reported = append(reported, err) }},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	slog.New(h).Info("one")
+	slog.New(h).Info("two")
+	h.Close()
+	if st := h.Stats(); st.Sent != 1 || st.Failed != 1 || st.Retries != 0 {
+		t.Errorf("stats = %+v", st)
+	}
+	if len(reported) != 1 || !strings.Contains(reported[0].Error(), "attribute too long") {
+		t.Errorf("reported = %v", reported)
+	}
+}
+
+func TestOTLPID(t *testing.T) {
+	for _, tt := range []struct {
+		value any
+		want  string
+	}{
+		{"00f067aa0ba902b7", "00f067aa0ba902b7"},
+		{[8]byte{0, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}, "00f067aa0ba902b7"},
+		{time.Second, ""}, // a Stringer, but not hex
+		{"00f067aa", ""},
+		{"0000000000000000", ""},
+		{nil, ""},
+	} {
+		field := ExtractorContextField("id", func(context.Context) any { return tt.value })
+		if got := otlpID(context.Background(), field, 8); got != tt.want {
+			t.Errorf("otlpID(%v) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+	if got := otlpID(context.Background(), ContextField{}, 8); got != "" {
+		t.Errorf("otlpID with a zero field = %q", got)
+	}
+}
+
+func TestParseResource(t *testing.T) {
+	got, err := parseResource(" service.name=shop , team=a%2Cb,empty=,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 || got["service.name"] != "shop" || got["team"] != "a,b" || got["empty"] != "" {
+		t.Errorf("parseResource = %v", got)
+	}
+	for _, spec := range []string{"service.name", "=x", "a=%zz"} {
+		if _, err := parseResource(spec); err == nil {
+			t.Errorf("parseResource(%q) succeeded", spec)
+		}
+	}
+}
+
+func TestOTLPHandlerType(t *testing.T) {
+	c := newOTLPCollector(t)
+	cfg, err := LoadSlogConfig(strings.NewReader("handler: otlp\ndestination: " + c.URL + "\nresource: service.name=shop,service.version=1.2\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger, err := cfg.BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	logger.Info("hello")
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.records()) != 1 {
+		t.Fatalf("got %d records, want 1", len(c.records()))
+	}
+	if got := otlpAttrs(c.requests[0].ResourceLogs[0].Resource.Attributes); len(got) != 2 || got["service.version"] != `{"stringValue":"1.2"}` {
+		t.Errorf("resource = %v", got)
+	}
+
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"resource":{"service.name":"shop","service.version":"1.2"}`) {
+		t.Errorf("MarshalJSON = %s", data)
+	}
+	var decoded SlogConfig
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Resource["service.name"] != "shop" {
+		t.Errorf("UnmarshalJSON: resource = %v, err = %v", decoded.Resource, err)
+	}
+}
@@ -0,0 +1,336 @@
+package logger
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DefaultRateLimit is the rate used when RateLimitOptions.Rate is not positive.
+const DefaultRateLimit = 100
+
+// rateLimitSweep is the number of keyed buckets above which idle ones are
+// discarded.
+const rateLimitSweep = 1024
+
+// RateLimitOptions configure a RateLimitHandler. Records are let through as
+// long as a token bucket that holds Burst tokens and gains Rate tokens per
+// second is not empty; each record takes one token.
+type RateLimitOptions struct {
+	// Rate is the number of records per second let through in the long run.
+	// If not positive, DefaultRateLimit is used.
+	Rate float64
+
+	// Burst is the number of records that may be let through at once.
+	// If not positive, Rate rounded up is used.
+	Burst int
+
+	// Key, if non-nil, gives every key its own bucket, e.g. the record
+	// message. If nil, all records of a logger share one bucket. Loggers with
+	// different names (see SlogConfig.Named) never share buckets.
+	Key func(r slog.Record) string
+}
+
+// RateLimitStats are the counters of a RateLimitHandler.
+type RateLimitStats struct {
+	Passed        uint64 // records passed to the wrapped handler
+	Suppressed    uint64 // records dropped
+	SummaryErrors uint64 // summaries the wrapped handler failed to write
+}
+
+// RateLimitHandler caps the rate of records of a logger, or of each key, so
+// that incident storms do not flood disks and downstream sinks.
+//
+// Once a bucket that dropped records has a token again, exactly one summary
+// record is written, at the level of the most severe dropped record, with
+// the count under "suppressed" and the key under "key" if
+// RateLimitOptions.Key is set:
+//
+//	level=ERROR msg="250 records suppressed" suppressed=250
+//
+// Flush writes the summaries of all buckets that are still dropping records
+// right away; Closer calls it, so that no count is lost when the logger is
+// closed. Summaries go to the wrapped handler without the attributes and
+// groups added with WithAttrs and WithGroup, except the logger name of the
+// bucket.
+//
+// Handlers derived with WithAttrs and WithGroup share the buckets of their
+// parent.
+type RateLimitHandler struct {
+	next    slog.Handler
+	name    loggerName
+	limiter *rateLimiter
+}
+
+// rateLimiter is the state shared by a RateLimitHandler and the handlers
+// derived from it.
+type rateLimiter struct {
+	opts  RateLimitOptions
+	root  slog.Handler // receives summaries
+	now   func() time.Time
+	after func(time.Duration, func()) stopper // starts timers on the clock of now
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*tokenBucket
+	sweepAt int
+	stats   RateLimitStats
+}
+
+// bucketKey identifies the bucket of a logger name and key.
+type bucketKey struct {
+	name, key string
+}
+
+// tokenBucket is the bucket of a key, with the records it dropped since it
+// last had a token.
+type tokenBucket struct {
+	tokens     float64
+	last       time.Time
+	suppressed int
+	maxLevel   slog.Level
+	timer      stopper // fires when the bucket has a token again
+	gen        uint64  // identifies the armed timer
+}
+
+// NewRateLimitHandler returns a handler that limits the rate of records
+// passed to next.
+func NewRateLimitHandler(next slog.Handler, opts RateLimitOptions) *RateLimitHandler {
+	if opts.Rate <= 0 {
+		opts.Rate = DefaultRateLimit
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = max(1, int(math.Ceil(opts.Rate)))
+	}
+	return &RateLimitHandler{next: next, limiter: &rateLimiter{
+		opts:    opts,
+		root:    next,
+		now:     time.Now,
+		after:   afterFunc,
+		buckets: make(map[bucketKey]*tokenBucket),
+		sweepAt: rateLimitSweep,
+	}}
+}
+
+// WithRateLimit returns a ConfigOption that makes handlers built from the
+// config limit the rate of records through a RateLimitHandler (see
+// RateLimitHandlerOf). It has no effect on a CustomHandler.
+func WithRateLimit(opts RateLimitOptions) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.RateLimit = &opts
+		cfg.touch()
+	}
+}
+
+// RateLimitHandlerOf returns the RateLimitHandler of a logger built from a
+// config with WithRateLimit, or nil if the logger is not rate limited.
+func RateLimitHandlerOf(l *slog.Logger) *RateLimitHandler {
+	if l == nil {
+		return nil
+	}
+	h, _ := findHandler[*RateLimitHandler](l.Handler())
+	return h
+}
+
+func (h *RateLimitHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes r to the wrapped handler if its bucket has a token left.
+func (h *RateLimitHandler) Handle(ctx context.Context, r slog.Record) error {
+	l := h.limiter
+	k := bucketKey{name: h.name.name}
+	if l.opts.Key != nil {
+		k.key = l.opts.Key(r)
+	}
+	now := l.now()
+	l.mu.Lock()
+	b := l.bucket(k, now)
+	var (
+		summary    slog.Record
+		hasSummary bool
+	)
+	pass := b.take(now, l.opts)
+	if pass {
+		l.stats.Passed++
+		summary, hasSummary = l.summary(k, b, now)
+	} else {
+		l.stats.Suppressed++
+		l.drop(k, b, r.Level)
+	}
+	l.mu.Unlock()
+
+	if !pass {
+		return nil
+	}
+	var err error
+	if hasSummary {
+		err = l.write(k, summary)
+	}
+	if err2 := h.next.Handle(ctx, r); err == nil {
+		err = err2
+	}
+	return err
+}
+
+func (h *RateLimitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &RateLimitHandler{next: h.next.WithAttrs(attrs), name: h.name.withAttrs(attrs), limiter: h.limiter}
+}
+
+func (h *RateLimitHandler) WithGroup(name string) slog.Handler {
+	return &RateLimitHandler{next: h.next.WithGroup(name), name: h.name.withGroup(name), limiter: h.limiter}
+}
+
+func (h *RateLimitHandler) Unwrap() slog.Handler {
+	return h.next
+}
+
+// Flush writes the summaries of the buckets that dropped records since they
+// last had a token.
+func (h *RateLimitHandler) Flush(context.Context) error {
+	l := h.limiter
+	now := l.now()
+	var (
+		summaries []slog.Record
+		keys      []bucketKey
+	)
+	l.mu.Lock()
+	for _, k := range slices.SortedFunc(maps.Keys(l.buckets), compareBucketKeys) {
+		if r, ok := l.summary(k, l.buckets[k], now); ok {
+			summaries = append(summaries, r)
+			keys = append(keys, k)
+		}
+	}
+	l.mu.Unlock()
+	var errs []error
+	for i, r := range summaries {
+		if err := l.write(keys[i], r); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Stats returns a snapshot of the handler's counters.
+func (h *RateLimitHandler) Stats() RateLimitStats {
+	h.limiter.mu.Lock()
+	defer h.limiter.mu.Unlock()
+	return h.limiter.stats
+}
+
+// bucket returns the bucket of k, adding a full one if needed. l.mu must be
+// held.
+func (l *rateLimiter) bucket(k bucketKey, now time.Time) *tokenBucket {
+	if b, ok := l.buckets[k]; ok {
+		return b
+	}
+	if len(l.buckets) >= l.sweepAt {
+		l.sweep(now)
+		l.sweepAt = max(rateLimitSweep, 2*len(l.buckets))
+	}
+	b := &tokenBucket{tokens: float64(l.opts.Burst), last: now}
+	l.buckets[k] = b
+	return b
+}
+
+// drop counts a record b dropped and, if it is the first since b last had a
+// token, arms the timer that writes the summary once b has one again. b was
+// refilled just now. l.mu must be held.
+func (l *rateLimiter) drop(k bucketKey, b *tokenBucket, level slog.Level) {
+	if b.suppressed == 0 {
+		gen := b.gen
+		wait := time.Duration((1 - b.tokens) / l.opts.Rate * float64(time.Second))
+		b.timer = l.after(wait, func() { l.expire(k, b, gen) })
+		b.maxLevel = level
+	}
+	b.maxLevel = max(b.maxLevel, level)
+	b.suppressed++
+}
+
+// expire writes the summary of b when the timer armed for generation gen
+// fires.
+func (l *rateLimiter) expire(k bucketKey, b *tokenBucket, gen uint64) {
+	l.mu.Lock()
+	if b.gen != gen {
+		l.mu.Unlock()
+		return
+	}
+	now := l.now()
+	b.refill(now, l.opts)
+	r, ok := l.summary(k, b, now)
+	l.mu.Unlock()
+	if ok {
+		l.write(k, r)
+	}
+}
+
+// write writes the summary r of the bucket k to the wrapped handler,
+// counting failures.
+func (l *rateLimiter) write(k bucketKey, r slog.Record) error {
+	err := loggerName{name: k.name}.handler(l.root).Handle(context.Background(), r)
+	if err != nil {
+		l.mu.Lock()
+		l.stats.SummaryErrors++
+		l.mu.Unlock()
+	}
+	return err
+}
+
+// compareBucketKeys orders bucket keys by logger name, then key.
+func compareBucketKeys(a, b bucketKey) int {
+	return cmp.Or(cmp.Compare(a.name, b.name), cmp.Compare(a.key, b.key))
+}
+
+// sweep discards the buckets that are full again and owe no summary, which
+// behave like new ones. l.mu must be held.
+func (l *rateLimiter) sweep(now time.Time) {
+	for k, b := range l.buckets {
+		b.refill(now, l.opts)
+		if b.suppressed == 0 && b.tokens >= float64(l.opts.Burst) {
+			delete(l.buckets, k)
+		}
+	}
+}
+
+// summary returns the summary record of the records b dropped, resets the
+// count and stops the timer. It reports false if none were dropped. l.mu
+// must be held.
+func (l *rateLimiter) summary(k bucketKey, b *tokenBucket, now time.Time) (slog.Record, bool) {
+	if b.suppressed == 0 {
+		return slog.Record{}, false
+	}
+	b.timer.Stop()
+	b.timer = nil
+	b.gen++
+	r := slog.NewRecord(now, b.maxLevel, fmt.Sprintf("%d records suppressed", b.suppressed), 0)
+	r.AddAttrs(slog.Int("suppressed", b.suppressed))
+	if l.opts.Key != nil {
+		r.AddAttrs(slog.String("key", k.key))
+	}
+	b.suppressed = 0
+	return r, true
+}
+
+// refill adds the tokens gained since the last refill.
+func (b *tokenBucket) refill(now time.Time, opts RateLimitOptions) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens = min(float64(opts.Burst), b.tokens+elapsed.Seconds()*opts.Rate)
+	}
+	b.last = now
+}
+
+// take refills the bucket and takes a token if there is one.
+func (b *tokenBucket) take(now time.Time, opts RateLimitOptions) bool {
+	b.refill(now, opts)
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestRateLimiter(opts RateLimitOptions) (*RateLimitHandler, *testHandler, *fakeClock) {
+	th := newTestHandler()
+	h := NewRateLimitHandler(th, opts)
+	clock := &fakeClock{t: testTime}
+	h.limiter.now, h.limiter.after = clock.now, clock.afterFunc
+	return h, th, clock
+}
+
+func TestRateLimitHandlerBucket(t *testing.T) {
+	h, th, clock := newTestRateLimiter(RateLimitOptions{Rate: 2, Burst: 2})
+	logger := slog.New(h).With("worker", 1)
+	logger.Info("a")
+	logger.Info("b")
+	logger.Info("c")
+	logger.Warn("d")
+	clock.advance(400 * time.Millisecond) // 0.8 tokens
+	logger.Info("e")
+	if got := strings.Join(messages(th), ","); got != "a,b" {
+		t.Fatalf("while limited: messages = %s", got)
+	}
+
+	clock.advance(100 * time.Millisecond)
+	logger.Info("f")
+	clock.advance(500 * time.Millisecond)
+	logger.Info("g")
+	if got := strings.Join(messages(th), ","); got != "a,b,3 records suppressed,f,g" {
+		t.Fatalf("after the window reopened: messages = %s", got)
+	}
+	summary := (*th.records)[2]
+	attrs := flattenRecord(summary)
+	if summary.Level != slog.LevelWarn || attrs["suppressed"] != int64(3) || !summary.Time.Equal(clock.t.Add(-500*time.Millisecond)) {
+		t.Errorf("summary = %v at %v, %v", attrs, summary.Level, summary.Time)
+	}
+	if _, ok := attrs["worker"]; ok {
+		t.Error("summary carries the attributes of a derived handler")
+	}
+	if st := h.Stats(); st.Passed != 4 || st.Suppressed != 3 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestRateLimitHandlerSummaryWhenReopened(t *testing.T) {
+	h, th, clock := newTestRateLimiter(RateLimitOptions{Rate: 4, Burst: 1})
+	logger := slog.New(h)
+	logger.Info("a")
+	clock.advance(100 * time.Millisecond)
+	logger.Info("b")
+	logger.Error("c")
+	clock.advance(149 * time.Millisecond)
+	if got := strings.Join(messages(th), ","); got != "a" {
+		t.Fatalf("while limited: messages = %s", got)
+	}
+	clock.advance(time.Millisecond)
+	summary := th.lastRecord()
+	if attrs := flattenRecord(summary); summary.Message != "2 records suppressed" || summary.Level != slog.LevelError || attrs["suppressed"] != int64(2) {
+		t.Fatalf("summary = %s %v at %v", summary.Message, attrs, summary.Level)
+	}
+	if !summary.Time.Equal(testTime.Add(250 * time.Millisecond)) {
+		t.Errorf("summary time = %v", summary.Time)
+	}
+	logger.Info("d") // takes the token that reopened the window
+	clock.advance(time.Hour)
+	if got := strings.Join(messages(th), ","); got != "a,2 records suppressed,d" {
+		t.Errorf("messages = %s", got)
+	}
+}
+
+func TestRateLimitHandlerNamedLoggers(t *testing.T) {
+	h, th, _ := newTestRateLimiter(RateLimitOptions{Rate: 2})
+	db, api := slog.New(h).With(LoggerKey, "db"), slog.New(h).With(LoggerKey, "api")
+	for range 10 {
+		db.Info("query")
+	}
+	api.Info("request")
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(messages(th), ","); got != "query,query,request,8 records suppressed" {
+		t.Fatalf("messages = %s", got)
+	}
+	if attrs := flattenRecord(th.lastRecord()); attrs[LoggerKey] != "db" {
+		t.Errorf("summary = %v, want logger db", attrs)
+	}
+}
+
+func TestRateLimitHandlerKeys(t *testing.T) {
+	h, th, _ := newTestRateLimiter(RateLimitOptions{Rate: 1, Key: func(r slog.Record) string { return r.Message }})
+	logger := slog.New(h)
+	for range 3 {
+		logger.Info("x")
+		logger.Error("y")
+	}
+	logger.Info("z")
+	if got := strings.Join(messages(th), ","); got != "x,y,z" {
+		t.Fatalf("messages = %s", got)
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	records := *th.records
+	if len(records) != 5 {
+		t.Fatalf("got %d records, want 5", len(records))
+	}
+	for i, want := range []struct {
+		key   string
+		level slog.Level
+	}{{"x", slog.LevelInfo}, {"y", slog.LevelError}} {
+		r := records[3+i]
+		if attrs := flattenRecord(r); r.Message != "2 records suppressed" || attrs["key"] != want.key || r.Level != want.level {
+			t.Errorf("summary %d = %s %v at %v", i, r.Message, attrs, r.Level)
+		}
+	}
+}
+
+func TestRateLimitHandlerSweep(t *testing.T) {
+	h, _, clock := newTestRateLimiter(RateLimitOptions{Rate: 1, Key: func(r slog.Record) string { return r.Message }})
+	logger := slog.New(h)
+	for i := range rateLimitSweep - 1 {
+		logger.Info(fmt.Sprint(i))
+	}
+	clock.advance(500 * time.Millisecond)
+	logger.Info("late")
+	logger.Info("late") // suppressed: its bucket owes a summary
+	clock.advance(500 * time.Millisecond)
+	logger.Info("new")
+	if n := len(h.limiter.buckets); n != 2 {
+		t.Errorf("%d buckets after the sweep, want 2", n)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogConfig(
+		WithHandlerType("text"),
+		WithOutput(&buf),
+		WithRateLimit(RateLimitOptions{Rate: 1, Burst: 1}),
+	).Named("svc").NewLogger()
+	for range 5 {
+		logger.Info("storm")
+	}
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "msg=storm logger=svc"); n != 1 {
+		t.Errorf("got %d storm records, want 1:\n%s", n, out)
+	}
+	if !strings.Contains(out, `msg="4 records suppressed" logger=svc suppressed=4`) {
+		t.Errorf("no summary in output:\n%s", out)
+	}
+	if RateLimitHandlerOf(logger) == nil {
+		t.Error("RateLimitHandlerOf = nil")
+	}
+}
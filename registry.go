@@ -0,0 +1,183 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+)
+
+// ErrUnknownHandlerType is returned when SlogConfig.HandlerType names a handler
+// type that has not been registered with RegisterHandlerType.
+var ErrUnknownHandlerType = errors.New("unknown handler type")
+
+// HandlerFactory builds a slog.Handler for a registered handler type.
+// The config passed to the factory is already resolved: Output, Level and
+// HandlerOptions (including HandlerOptions.Level) are never nil. Factories
+// should honour HandlerOptions.Level, which is usually a *slog.LevelVar that
+// may change at runtime. The handler is shared by the loggers built from the
+// same config (see SlogConfig.BuildHandler), so a factory that opens a
+// connection or starts a goroutine does so once per config, not per logger.
+type HandlerFactory func(c SlogConfig) (slog.Handler, error)
+
+var handlerTypes = struct {
+	sync.RWMutex
+	factories map[string]HandlerFactory
+}{
+	factories: map[string]HandlerFactory{
+		"json": func(c SlogConfig) (slog.Handler, error) {
+			return slog.NewJSONHandler(c.Output, c.HandlerOptions), nil
+		},
+		"logfmt": func(c SlogConfig) (slog.Handler, error) {
+			return NewLogfmtHandler(c.Output, &LogfmtOptions{HandlerOptions: *c.HandlerOptions, KeyOrder: c.LogfmtKeyOrder}), nil
+		},
+		"syslog": func(c SlogConfig) (slog.Handler, error) {
+			dest := c.Destination
+			if dest == "" {
+				dest = DefaultSyslogDestination
+			}
+			h, err := NewSyslogHandler(dest, &SyslogOptions{HandlerOptions: *c.HandlerOptions})
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		"elasticsearch": func(c SlogConfig) (slog.Handler, error) {
+			dest := c.Destination
+			if dest == "" {
+				dest = DefaultElasticsearchDestination
+			}
+			var opts ElasticsearchOptions
+			if c.Elasticsearch != nil {
+				opts = *c.Elasticsearch
+			}
+			opts.HandlerOptions = *c.HandlerOptions
+			h, err := NewElasticsearchHandler(dest, &opts)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		"gelf": func(c SlogConfig) (slog.Handler, error) {
+			dest := c.Destination
+			if dest == "" {
+				dest = DefaultGELFDestination
+			}
+			var opts GELFOptions
+			if c.GELF != nil {
+				opts = *c.GELF
+			}
+			opts.HandlerOptions = *c.HandlerOptions
+			h, err := NewGELFHandler(dest, &opts)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		"http": func(c SlogConfig) (slog.Handler, error) {
+			if c.Destination == "" {
+				return nil, errors.New("the http handler type needs a destination")
+			}
+			var opts HTTPOptions
+			if c.HTTP != nil {
+				opts = *c.HTTP
+			}
+			opts.HandlerOptions = *c.HandlerOptions
+			h, err := NewHTTPHandler(c.Destination, &opts)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		"journald": func(c SlogConfig) (slog.Handler, error) {
+			dest := c.Destination
+			if dest == "" {
+				dest = DefaultJournaldDestination
+			}
+			h, err := NewJournaldHandler(dest, &JournaldOptions{HandlerOptions: *c.HandlerOptions})
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		"loki": func(c SlogConfig) (slog.Handler, error) {
+			dest := c.Destination
+			if dest == "" {
+				dest = DefaultLokiDestination
+			}
+			var opts LokiOptions
+			if c.Loki != nil {
+				opts = *c.Loki
+			}
+			opts.HandlerOptions = *c.HandlerOptions
+			h, err := NewLokiHandler(dest, &opts)
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		"otlp": func(c SlogConfig) (slog.Handler, error) {
+			dest := c.Destination
+			if dest == "" {
+				dest = DefaultOTLPDestination
+			}
+			h, err := NewOTLPHandler(dest, &OTLPOptions{HandlerOptions: *c.HandlerOptions, Resource: c.Resource})
+			if err != nil {
+				return nil, err
+			}
+			return h, nil
+		},
+		"text": func(c SlogConfig) (slog.Handler, error) {
+			return slog.NewTextHandler(c.Output, c.HandlerOptions), nil
+		},
+		"cbor": func(c SlogConfig) (slog.Handler, error) {
+			return NewCBORHandler(c.Output, c.HandlerOptions), nil
+		},
+		"console": func(c SlogConfig) (slog.Handler, error) {
+			return NewConsoleHandler(c.Output, &ConsoleOptions{HandlerOptions: *c.HandlerOptions}), nil
+		},
+		"discard": func(c SlogConfig) (slog.Handler, error) {
+			return slog.DiscardHandler, nil
+		},
+	},
+}
+
+// RegisterHandlerType makes a handler factory available under the given name,
+// so that it can be selected with WithHandlerType. Registering a name that
+// already exists replaces the previous factory.
+// It panics if name is empty or factory is nil.
+func RegisterHandlerType(name string, factory HandlerFactory) {
+	if name == "" {
+		panic("logger: RegisterHandlerType called with empty name")
+	}
+	if factory == nil {
+		panic("logger: RegisterHandlerType called with nil factory for " + name)
+	}
+	handlerTypes.Lock()
+	defer handlerTypes.Unlock()
+	handlerTypes.factories[name] = factory
+}
+
+// HandlerTypes returns the sorted names of all registered handler types.
+func HandlerTypes() []string {
+	handlerTypes.RLock()
+	defer handlerTypes.RUnlock()
+	names := make([]string, 0, len(handlerTypes.factories))
+	for name := range handlerTypes.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookupHandlerType returns the factory registered under name.
+func lookupHandlerType(name string) (HandlerFactory, error) {
+	handlerTypes.RLock()
+	factory, ok := handlerTypes.factories[name]
+	handlerTypes.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownHandlerType, name)
+	}
+	return factory, nil
+}
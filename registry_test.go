@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestHandlerTypesBuiltin(t *testing.T) {
+	types := HandlerTypes()
+	for _, want := range []string{"cbor", "console", "discard", "elasticsearch", "gelf", "http", "journald", "json", "logfmt", "loki", "otlp", "syslog", "text"} {
+		found := false
+		for _, name := range types {
+			if name == want {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("HandlerTypes() = %v, missing %q", types, want)
+		}
+	}
+}
+
+func TestRegisterHandlerType(t *testing.T) {
+	th := newTestHandler()
+	var got SlogConfig
+	RegisterHandlerType("test-house", func(c SlogConfig) (slog.Handler, error) {
+		got = c
+		return th, nil
+	})
+
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithHandlerType("test-house"), WithOutput(&buf), WithLevel(slog.LevelWarn))
+	logger, err := cfg.BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	logger.Warn("hello")
+	if rec := th.lastRecord(); rec == nil || rec.Message != "hello" {
+		t.Fatalf("record not delivered to registered handler: %v", rec)
+	}
+	if got.Output != &buf {
+		t.Errorf("factory Output = %v, want %v", got.Output, &buf)
+	}
+	if got.HandlerOptions == nil || got.HandlerOptions.Level.Level() != slog.LevelWarn {
+		t.Errorf("factory HandlerOptions = %+v, want Level WARN", got.HandlerOptions)
+	}
+}
+
+func TestRegisterHandlerTypeFactoryError(t *testing.T) {
+	RegisterHandlerType("test-broken", func(c SlogConfig) (slog.Handler, error) {
+		return nil, errors.New("boom")
+	})
+	_, err := NewSlogConfig(WithHandlerType("test-broken")).BuildHandler()
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("BuildHandler error = %v, want factory error", err)
+	}
+}
+
+func TestRegisterHandlerTypePanics(t *testing.T) {
+	for name, fn := range map[string]func(){
+		"empty name":  func() { RegisterHandlerType("", func(SlogConfig) (slog.Handler, error) { return nil, nil }) },
+		"nil factory": func() { RegisterHandlerType("x", nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Error("expected panic")
+				}
+			}()
+			fn()
+		})
+	}
+}
+
+func TestBuildLoggerUnknownType(t *testing.T) {
+	_, err := NewSlogConfig(WithHandlerType("jsn")).BuildLogger()
+	if !errors.Is(err, ErrUnknownHandlerType) {
+		t.Errorf("BuildLogger error = %v, want ErrUnknownHandlerType", err)
+	}
+}
+
+func TestNewLoggerUnknownTypeFallsBackToJSON(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(WithHandlerType("jsn"), WithOutput(&buf))
+	cfg.NewLogger().Info("hello")
+	if !strings.HasPrefix(buf.String(), "{") {
+		t.Errorf("expected JSON fallback output, got %q", buf.String())
+	}
+}
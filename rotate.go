@@ -0,0 +1,489 @@
+package logger
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotationInterval selects time-based rotation of a RotatingFile.
+type RotationInterval int
+
+const (
+	RotateNever  RotationInterval = iota // rotate by size only
+	RotateHourly                         // rotate at the start of every hour
+	RotateDaily                          // rotate at local midnight
+)
+
+// rotatedTimeLayout is the timestamp embedded in rotated file names. It sorts
+// lexically in chronological order.
+const rotatedTimeLayout = "20060102T150405.000"
+
+// RotationPolicy controls when a RotatingFile rotates and which rotated files
+// it keeps.
+type RotationPolicy struct {
+	// MaxSize rotates the file before a write would grow it beyond MaxSize
+	// bytes. Zero disables size-based rotation.
+	MaxSize int64
+
+	// Interval rotates the file when a write happens in a new hour or day.
+	Interval RotationInterval
+
+	// Compress gzips rotated files in the background.
+	Compress bool
+
+	// MaxFiles is the number of rotated files to keep. Zero keeps all.
+	MaxFiles int
+
+	// MaxAge removes rotated files older than MaxAge, for example
+	// 7*24*time.Hour to keep a week. Zero keeps all.
+	MaxAge time.Duration
+
+	// ReopenOnSIGHUP reopens the file when the process receives SIGHUP,
+	// so that external tools such as logrotate can move it away. It has no
+	// effect on platforms without SIGHUP.
+	ReopenOnSIGHUP bool
+}
+
+// RotatingFile is an io.Writer that writes to a file and rotates it according
+// to a RotationPolicy. Rotated files are renamed by inserting a UTC timestamp
+// before the extension: "app.log" becomes "app-20261016T123045.000.log", or
+// "app-20261016T123045.000.log.gz" when compressed. Use RotatedFiles or
+// OrderLogFiles to discover them in chronological order.
+//
+// A RotatingFile is safe for concurrent use.
+type RotatingFile struct {
+	mu          sync.Mutex
+	path        string
+	policy      RotationPolicy
+	file        *os.File
+	size        int64
+	periodStart time.Time
+	now         func() time.Time
+
+	mill     sync.WaitGroup // background compression and cleanup
+	millMu   sync.Mutex     // serializes background runs
+	sighup   chan os.Signal
+	done     chan struct{}
+	closeErr error
+	closed   bool
+}
+
+// OpenRotatingFile opens (or creates) the file at path for appending and
+// returns a writer that rotates it according to policy.
+func OpenRotatingFile(path string, policy RotationPolicy) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, policy: policy, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	if policy.ReopenOnSIGHUP {
+		sighup := make(chan os.Signal, 1)
+		if notifySIGHUP(sighup) {
+			f.sighup = sighup
+			f.done = make(chan struct{})
+			go f.watchSIGHUP()
+		}
+	}
+	return f, nil
+}
+
+// WithFileOutput returns a ConfigOption that writes to the file at path,
+// rotating it according to policy (see RotatingFile). If the file cannot be
+// opened, the error is recorded on the config (see SlogConfig.Err). The file
+// is closed with the last logger built from the config (see Closer).
+func WithFileOutput(path string, policy RotationPolicy) ConfigOption {
+	return func(cfg *SlogConfig) {
+		f, err := OpenRotatingFile(path, policy)
+		if err != nil {
+			cfg.addErr(&ConfigKeyError{Key: SettingOutput, Err: err})
+			return
+		}
+		cfg.Output = f
+		cfg.markSet(SettingOutput)
+		cfg.own(f)
+	}
+}
+
+// Name returns the path of the active file.
+func (f *RotatingFile) Name() string {
+	return f.path
+}
+
+// Write writes p to the active file, rotating it first if the policy requires.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return 0, os.ErrClosed
+	}
+	if f.needsRotation(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file immediately.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.rotate()
+}
+
+// Reopen closes and reopens the active file by path. It is used after an
+// external tool has moved the file away.
+func (f *RotatingFile) Reopen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	if err := f.file.Close(); err != nil {
+		return errors.Join(err, f.open())
+	}
+	return f.open()
+}
+
+// Sync commits the active file to stable storage.
+func (f *RotatingFile) Sync() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return os.ErrClosed
+	}
+	return f.file.Sync()
+}
+
+// Close closes the active file and waits for background compression and
+// cleanup to finish.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return f.closeErr
+	}
+	f.closed = true
+	if f.sighup != nil {
+		signal.Stop(f.sighup)
+		close(f.done)
+	}
+	f.closeErr = f.file.Close()
+	f.mu.Unlock()
+	f.mill.Wait()
+	return f.closeErr
+}
+
+// open opens the active file and initializes size and period from it.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.periodStart = f.period(f.now())
+	if f.size > 0 {
+		f.periodStart = f.period(info.ModTime())
+	}
+	return nil
+}
+
+// period returns the start of the rotation interval containing t.
+func (f *RotatingFile) period(t time.Time) time.Time {
+	switch f.policy.Interval {
+	case RotateHourly:
+		return t.Truncate(time.Hour)
+	case RotateDaily:
+		y, m, d := t.Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	}
+	return time.Time{}
+}
+
+// needsRotation reports whether writing n more bytes requires a rotation.
+func (f *RotatingFile) needsRotation(n int64) bool {
+	if f.size == 0 {
+		return false
+	}
+	if f.policy.MaxSize > 0 && f.size+n > f.policy.MaxSize {
+		return true
+	}
+	return f.policy.Interval != RotateNever && !f.period(f.now()).Equal(f.periodStart)
+}
+
+// rotate moves the active file to its rotated name, opens a new one and
+// starts background compression and cleanup. If the file cannot be closed or
+// moved, it is reopened so that writes go on to it. f.mu must be held.
+func (f *RotatingFile) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return errors.Join(fmt.Errorf("rotate %s: %w", f.path, err), f.open())
+	}
+	rotated := f.rotatedName(f.now())
+	if err := os.Rename(f.path, rotated); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Join(fmt.Errorf("rotate %s: %w", f.path, err), f.open())
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.periodStart = f.period(f.now())
+	f.mill.Add(1)
+	go f.millRun(rotated, f.now())
+	return nil
+}
+
+// rotatedName returns an unused rotated file name for time t.
+func (f *RotatingFile) rotatedName(t time.Time) string {
+	dir, base := filepath.Split(f.path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	t = t.UTC()
+	for {
+		name := filepath.Join(dir, stem+"-"+t.Format(rotatedTimeLayout)+ext)
+		_, errPlain := os.Lstat(name)
+		_, errGzip := os.Lstat(name + ".gz")
+		if errors.Is(errPlain, os.ErrNotExist) && errors.Is(errGzip, os.ErrNotExist) {
+			return name
+		}
+		t = t.Add(time.Millisecond) // keep names unique and ordered
+	}
+}
+
+// millRun compresses a freshly rotated file and applies retention as of now.
+func (f *RotatingFile) millRun(rotated string, now time.Time) {
+	defer f.mill.Done()
+	f.millMu.Lock()
+	defer f.millMu.Unlock()
+	if f.policy.Compress {
+		// A failed compression leaves the plain rotated file in place.
+		gzipFile(rotated)
+	}
+	f.prune(now)
+}
+
+// prune removes rotated files beyond MaxFiles or older than MaxAge at now.
+func (f *RotatingFile) prune(now time.Time) {
+	if f.policy.MaxFiles <= 0 && f.policy.MaxAge <= 0 {
+		return
+	}
+	files, err := RotatedFiles(f.path)
+	if err != nil {
+		return
+	}
+	cutoff := now.Add(-f.policy.MaxAge)
+	for i, name := range files {
+		tooMany := f.policy.MaxFiles > 0 && len(files)-i > f.policy.MaxFiles
+		tooOld := false
+		if f.policy.MaxAge > 0 {
+			if t, ok := rotatedTime(f.path, name); ok && t.Before(cutoff) {
+				tooOld = true
+			}
+		}
+		if tooMany || tooOld {
+			os.Remove(name)
+		}
+	}
+}
+
+// watchSIGHUP reopens the file on every SIGHUP until the file is closed.
+func (f *RotatingFile) watchSIGHUP() {
+	for {
+		select {
+		case <-f.sighup:
+			f.Reopen()
+		case <-f.done:
+			return
+		}
+	}
+}
+
+// gzipFile compresses name into name+".gz" and removes name.
+func gzipFile(name string) error {
+	src, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(name + ".gz")
+		return err
+	}
+	src.Close()
+	return os.Remove(name)
+}
+
+// RotatedFiles returns the rotated files of the log file at path, oldest
+// first. The active file itself is not included.
+func RotatedFiles(path string) ([]string, error) {
+	dir, base := filepath.Split(path)
+	ext := filepath.Ext(base)
+	stem := strings.TrimSuffix(base, ext)
+	matches, err := filepath.Glob(filepath.Join(dir, globEscape(stem)+"-*"+globEscape(ext)+"*"))
+	if err != nil {
+		return nil, err
+	}
+	type rotated struct {
+		name string
+		t    time.Time
+	}
+	var files []rotated
+	for _, name := range matches {
+		if t, ok := rotatedTime(path, name); ok {
+			files = append(files, rotated{name, t})
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].t.Before(files[j].t) })
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = file.name
+	}
+	return names, nil
+}
+
+// rotatedTime parses the rotation time from the name of a rotated file of
+// the log file at path. ok is false if name is not such a file.
+func rotatedTime(path, name string) (time.Time, bool) {
+	active, t, ok := activeFileOf(name)
+	if !ok || active != filepath.Clean(path) {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// globEscape escapes glob metacharacters in s.
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// OrderLogFiles orders paths so that the rotated files of each log file come
+// oldest first, immediately followed by the active file. Files that are not
+// part of a rotation set keep their relative order.
+func OrderLogFiles(paths []string) []string {
+	type entry struct {
+		path  string
+		group string    // active file path the entry belongs to
+		t     time.Time // rotation time; zero for the active file
+		index int       // original position of the group
+	}
+	entries := make([]entry, len(paths))
+	first := make(map[string]int)
+	for i, p := range paths {
+		e := entry{path: p, group: p}
+		if active, t, ok := activeFileOf(p); ok {
+			e.group, e.t = active, t
+		}
+		if _, seen := first[e.group]; !seen {
+			first[e.group] = i
+		}
+		entries[i] = e
+	}
+	for i := range entries {
+		entries[i].index = first[entries[i].group]
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.index != b.index {
+			return a.index < b.index
+		}
+		if a.t.IsZero() != b.t.IsZero() {
+			return !a.t.IsZero() // rotated files before the active one
+		}
+		return a.t.Before(b.t)
+	})
+	ordered := make([]string, len(entries))
+	for i, e := range entries {
+		ordered[i] = e.path
+	}
+	return ordered
+}
+
+// activeFileOf returns the active file path and rotation time for a rotated
+// file name such as "app-20261016T123045.000.log.gz".
+func activeFileOf(name string) (string, time.Time, bool) {
+	dir, base := filepath.Split(strings.TrimSuffix(name, ".gz"))
+	ext := filepath.Ext(base)
+	stemStamp := strings.TrimSuffix(base, ext)
+	if len(stemStamp) < len(rotatedTimeLayout)+2 {
+		return "", time.Time{}, false
+	}
+	cut := len(stemStamp) - len(rotatedTimeLayout)
+	if stemStamp[cut-1] != '-' {
+		return "", time.Time{}, false
+	}
+	t, err := time.Parse(rotatedTimeLayout, stemStamp[cut:])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return filepath.Join(dir, stemStamp[:cut-1]+ext), t, true
+}
+
+// OpenLogFile opens a log file for reading, transparently decompressing
+// rotated files that end in ".gz" and converting CBOR logs (see CBORHandler)
+// to JSON lines.
+func OpenLogFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	var rc io.ReadCloser = f
+	if strings.HasSuffix(path, ".gz") {
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		rc = &gzipFileReader{Reader: zr, file: f}
+	}
+	return cborJSONReader(rc), nil
+}
+
+// gzipFileReader closes both the gzip stream and the underlying file.
+type gzipFileReader struct {
+	*gzip.Reader
+	file *os.File
+}
+
+func (r *gzipFileReader) Close() error {
+	err := r.Reader.Close()
+	if ferr := r.file.Close(); err == nil {
+		err = ferr
+	}
+	return err
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package logger
+
+import "os"
+
+// notifySIGHUP reports that SIGHUP does not exist on this platform.
+func notifySIGHUP(c chan<- os.Signal) bool {
+	return false
+}
@@ -0,0 +1,219 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestRotatingFile(t *testing.T, policy RotationPolicy) (*RotatingFile, *fakeClock, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := OpenRotatingFile(path, policy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clock := &fakeClock{t: testTime}
+	f.now = clock.now
+	f.periodStart = f.period(clock.t)
+	t.Cleanup(func() { f.Close() })
+	return f, clock, path
+}
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	r, err := OpenLogFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	f, clock, path := openTestRotatingFile(t, RotationPolicy{MaxSize: 10})
+	f.Write([]byte("12345\n"))
+	clock.advance(time.Second)
+	f.Write([]byte("67890\n")) // would exceed 10 bytes: rotates first
+	clock.advance(time.Second)
+	f.Write([]byte("abcde\n"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	rotated, err := RotatedFiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rotated) != 2 {
+		t.Fatalf("RotatedFiles = %v, want 2 files", rotated)
+	}
+	want := filepath.Join(filepath.Dir(path), "app-20261016T123046.123.log")
+	if rotated[0] != want {
+		t.Errorf("rotated[0] = %q, want %q", rotated[0], want)
+	}
+	got := []string{readFile(t, rotated[0]), readFile(t, rotated[1]), readFile(t, path)}
+	if !reflect.DeepEqual(got, []string{"12345\n", "67890\n", "abcde\n"}) {
+		t.Errorf("contents = %q", got)
+	}
+}
+
+func TestRotatingFileInterval(t *testing.T) {
+	f, clock, path := openTestRotatingFile(t, RotationPolicy{Interval: RotateHourly})
+	f.Write([]byte("first hour\n"))
+	clock.advance(10 * time.Minute)
+	f.Write([]byte("same hour\n"))
+	clock.advance(time.Hour)
+	f.Write([]byte("next hour\n"))
+	f.Close()
+
+	rotated, _ := RotatedFiles(path)
+	if len(rotated) != 1 {
+		t.Fatalf("RotatedFiles = %v, want 1 file", rotated)
+	}
+	if got := readFile(t, rotated[0]); got != "first hour\nsame hour\n" {
+		t.Errorf("rotated content = %q", got)
+	}
+	if got := readFile(t, path); got != "next hour\n" {
+		t.Errorf("active content = %q", got)
+	}
+}
+
+func TestRotatingFileCompressAndRetention(t *testing.T) {
+	f, clock, path := openTestRotatingFile(t, RotationPolicy{Compress: true, MaxFiles: 2})
+	for i := 0; i < 4; i++ {
+		f.Write([]byte{byte('a' + i), '\n'})
+		clock.advance(time.Second)
+		if err := f.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	f.Close()
+
+	rotated, _ := RotatedFiles(path)
+	if len(rotated) != 2 {
+		t.Fatalf("RotatedFiles = %v, want 2 files", rotated)
+	}
+	for _, name := range rotated {
+		if !strings.HasSuffix(name, ".log.gz") {
+			t.Errorf("%s is not compressed", name)
+		}
+	}
+	if got := readFile(t, rotated[0]) + readFile(t, rotated[1]); got != "c\nd\n" {
+		t.Errorf("kept contents = %q, want the two newest", got)
+	}
+}
+
+func TestRotatingFileMaxAge(t *testing.T) {
+	f, clock, path := openTestRotatingFile(t, RotationPolicy{MaxAge: 48 * time.Hour})
+	f.Write([]byte("old\n"))
+	f.Rotate()
+	clock.advance(72 * time.Hour)
+	f.Write([]byte("new\n"))
+	f.Rotate()
+	f.Close()
+
+	rotated, _ := RotatedFiles(path)
+	if len(rotated) != 1 || readFile(t, rotated[0]) != "new\n" {
+		t.Errorf("RotatedFiles = %v, want only the recent file", rotated)
+	}
+}
+
+func TestRotatingFileReopen(t *testing.T) {
+	f, _, path := openTestRotatingFile(t, RotationPolicy{})
+	f.Write([]byte("before\n"))
+	moved := path + ".1"
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("after\n"))
+	f.Close()
+	if got := readFile(t, moved); got != "before\n" {
+		t.Errorf("moved content = %q", got)
+	}
+	if got := readFile(t, path); got != "after\n" {
+		t.Errorf("reopened content = %q", got)
+	}
+	if _, err := f.Write([]byte("x")); err == nil {
+		t.Error("Write after Close succeeded")
+	}
+}
+
+func TestRotatingFileRotateCloseError(t *testing.T) {
+	f, _, path := openTestRotatingFile(t, RotationPolicy{})
+	io.WriteString(f, "before\n")
+	f.file.Close() // the next Close fails
+	if err := f.Rotate(); !errors.Is(err, os.ErrClosed) {
+		t.Fatalf("Rotate error = %v, want os.ErrClosed", err)
+	}
+	if _, err := io.WriteString(f, "after\n"); err != nil {
+		t.Fatalf("Write after a failed rotation: %v", err)
+	}
+	if got := readFile(t, path); got != "before\nafter\n" {
+		t.Errorf("active file = %q", got)
+	}
+}
+
+func TestOrderLogFiles(t *testing.T) {
+	paths := []string{
+		"/var/log/app.log",
+		"/var/log/other.txt",
+		"/var/log/app-20261016T120000.000.log.gz",
+		"/var/log/db.log",
+		"/var/log/app-20261015T120000.000.log.gz",
+		"/var/log/app-20261016T130000.000.log",
+	}
+	want := []string{
+		"/var/log/app-20261015T120000.000.log.gz",
+		"/var/log/app-20261016T120000.000.log.gz",
+		"/var/log/app-20261016T130000.000.log",
+		"/var/log/app.log",
+		"/var/log/other.txt",
+		"/var/log/db.log",
+	}
+	if got := OrderLogFiles(paths); !reflect.DeepEqual(got, want) {
+		t.Errorf("OrderLogFiles =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestWithFileOutput(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "svc.log")
+	cfg := NewSlogConfig(WithFileOutput(path, RotationPolicy{MaxSize: 1 << 20}))
+	if err := cfg.Err(); err != nil {
+		t.Fatal(err)
+	}
+	f, ok := cfg.Output.(*RotatingFile)
+	if !ok {
+		t.Fatalf("Output = %T, want *RotatingFile", cfg.Output)
+	}
+	defer f.Close()
+	cfg.NewLogger().Info("hello")
+
+	var doc configJSON
+	data, _ := json.Marshal(cfg)
+	json.Unmarshal(data, &doc)
+	if doc.Output != path {
+		t.Errorf("marshalled output = %q, want %q", doc.Output, path)
+	}
+	if !strings.Contains(readFile(t, path), "hello") {
+		t.Error("record not written to file")
+	}
+
+	bad := NewSlogConfig(WithFileOutput(filepath.Join(path, "not-a-dir", "x.log"), RotationPolicy{}))
+	if bad.Err() == nil {
+		t.Error("Err() = nil for unopenable path")
+	}
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifySIGHUP relays SIGHUP to c.
+func notifySIGHUP(c chan<- os.Signal) bool {
+	signal.Notify(c, syscall.SIGHUP)
+	return true
+}
@@ -0,0 +1,99 @@
+package logger
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+)
+
+// Attribute rule actions understood by AttrRule.
+const (
+	RuleRedact = "redact" // replace the attribute value
+	RuleRename = "rename" // change the attribute key
+)
+
+// DefaultRedaction is the value written in place of redacted attributes
+// when AttrRule.To is empty.
+const DefaultRedaction = "[REDACTED]"
+
+// AttrRule describes a rewrite applied to attributes before they are written.
+//
+// Key selects the attribute. A key without dots matches that attribute at any
+// group depth, while a dotted key such as "User.Password" matches only the
+// attribute with that full group path. Built-in keys ("time", "level", "msg",
+// "source") can be renamed as well.
+type AttrRule struct {
+	Key    string `json:"key"`          // attribute key or dotted path
+	Action string `json:"action"`       // RuleRedact or RuleRename
+	To     string `json:"to,omitempty"` // new key for RuleRename, replacement value for RuleRedact
+}
+
+// Validate reports whether the rule is well formed.
+func (r AttrRule) Validate() error {
+	if r.Key == "" {
+		return fmt.Errorf("rule key is empty")
+	}
+	switch r.Action {
+	case RuleRedact:
+	case RuleRename:
+		if r.To == "" {
+			return fmt.Errorf("rename rule for %q has no target key", r.Key)
+		}
+	default:
+		return fmt.Errorf("unknown rule action %q", r.Action)
+	}
+	return nil
+}
+
+// matches reports whether the rule selects attribute a nested under groups.
+func (r AttrRule) matches(groups []string, a slog.Attr) bool {
+	if !strings.Contains(r.Key, ".") {
+		return r.Key == a.Key
+	}
+	if len(groups) == 0 {
+		return r.Key == a.Key
+	}
+	return r.Key == strings.Join(groups, ".")+"."+a.Key
+}
+
+// apply rewrites a according to the rule.
+func (r AttrRule) apply(a slog.Attr) slog.Attr {
+	switch r.Action {
+	case RuleRedact:
+		replacement := r.To
+		if replacement == "" {
+			replacement = DefaultRedaction
+		}
+		return slog.String(a.Key, replacement)
+	case RuleRename:
+		a.Key = r.To
+	}
+	return a
+}
+
+// WithAttrRules returns a ConfigOption that appends attribute rewrite rules.
+// Rules run after any HandlerOptions.ReplaceAttr function.
+func WithAttrRules(rules ...AttrRule) ConfigOption {
+	return func(cfg *SlogConfig) {
+		merged := make([]AttrRule, 0, len(cfg.Rules)+len(rules))
+		merged = append(merged, cfg.Rules...)
+		cfg.Rules = append(merged, rules...)
+		cfg.markSet(SettingRules)
+	}
+}
+
+// replaceAttrWithRules returns a ReplaceAttr function that calls next (if any)
+// and then applies the rules in order.
+func replaceAttrWithRules(rules []AttrRule, next func([]string, slog.Attr) slog.Attr) func([]string, slog.Attr) slog.Attr {
+	return func(groups []string, a slog.Attr) slog.Attr {
+		if next != nil {
+			a = next(groups, a)
+		}
+		for _, rule := range rules {
+			if rule.matches(groups, a) {
+				a = rule.apply(a)
+			}
+		}
+		return a
+	}
+}
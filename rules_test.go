@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestAttrRuleValidate(t *testing.T) {
+	tests := []struct {
+		rule AttrRule
+		ok   bool
+	}{
+		{AttrRule{Key: "password", Action: RuleRedact}, true},
+		{AttrRule{Key: "msg", Action: RuleRename, To: "message"}, true},
+		{AttrRule{Action: RuleRedact}, false},
+		{AttrRule{Key: "msg", Action: RuleRename}, false},
+		{AttrRule{Key: "msg", Action: "drop"}, false},
+	}
+	for _, tt := range tests {
+		if err := tt.rule.Validate(); (err == nil) != tt.ok {
+			t.Errorf("Validate(%+v) = %v, want ok=%v", tt.rule, err, tt.ok)
+		}
+	}
+}
+
+func TestWithAttrRules(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(
+		WithHandlerType("text"),
+		WithOutput(&buf),
+		WithAttrRules(
+			AttrRule{Key: "token", Action: RuleRedact, To: "xxx"},
+			AttrRule{Key: "User.Email", Action: RuleRename, To: "mail"},
+		),
+	)
+	cfg.NewLogger().Info("msg",
+		"token", "secret",
+		slog.Group("User", "Email", "a@b.c"),
+		slog.Group("Other", "Email", "d@e.f"),
+	)
+	out := buf.String()
+	for _, want := range []string{"token=xxx", "User.mail=a@b.c", "Other.Email=d@e.f"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output %q missing %q", out, want)
+		}
+	}
+}
+
+func TestWithAttrRulesAfterReplaceAttr(t *testing.T) {
+	var buf bytes.Buffer
+	cfg := NewSlogConfig(
+		WithHandlerType("text"),
+		WithOutput(&buf),
+		WithHandlerOptions(&slog.HandlerOptions{
+			ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+				if a.Key == "pw" {
+					a.Key = "password"
+				}
+				return a
+			},
+		}),
+		WithAttrRules(AttrRule{Key: "password", Action: RuleRedact}),
+	)
+	cfg.NewLogger().Info("msg", "pw", "secret")
+	if !strings.Contains(buf.String(), "password="+DefaultRedaction) {
+		t.Errorf("output %q, want redacted password", buf.String())
+	}
+}
+
+func TestCloneCopiesRules(t *testing.T) {
+	orig := NewSlogConfig(WithAttrRules(AttrRule{Key: "a", Action: RuleRedact}))
+	clone := orig.Clone()
+	clone.Rules[0].Key = "b"
+	if orig.Rules[0].Key != "a" {
+		t.Error("Clone shares Rules with the original")
+	}
+}
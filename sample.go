@@ -0,0 +1,321 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Defaults of SamplingOptions.
+const (
+	DefaultSamplingInterval   = time.Second
+	DefaultSamplingFirst      = 100
+	DefaultSamplingThereafter = 100
+)
+
+// SamplingSummaryMessage is the message of the records that report how many
+// records a SamplingHandler dropped.
+const SamplingSummaryMessage = "log records sampled"
+
+// SamplingOptions configure a SamplingHandler. In each Interval, the first
+// First records with the same key are passed on, then every Thereafter-th.
+type SamplingOptions struct {
+	// Interval is the length of a sampling window; counts start over in each.
+	// If not positive, DefaultSamplingInterval is used.
+	Interval time.Duration
+
+	// First is the number of records per key passed in each interval.
+	// If not positive, DefaultSamplingFirst is used.
+	First int
+
+	// Thereafter is the rate at which records beyond First are passed: 1
+	// passes all, 100 every hundredth. If not positive,
+	// DefaultSamplingThereafter is used.
+	Thereafter int
+
+	// Key returns the sampling key of a record. If nil, records are keyed by
+	// their level and message.
+	Key func(r slog.Record) string
+}
+
+// SamplingStats are the counters of a SamplingHandler.
+type SamplingStats struct {
+	Passed        uint64 // records passed to the wrapped handler
+	Sampled       uint64 // records dropped
+	SummaryErrors uint64 // summaries the wrapped handler failed to write
+}
+
+// SamplingHandler thins out records that repeat at a high rate, such as the
+// same message logged in a hot loop, as configured by SamplingOptions.
+//
+// At the end of each interval in which records were dropped, a summary
+// record is written with SamplingSummaryMessage, the level of the most
+// severe dropped record, the total under "sampled" and the count per key in
+// the group "keys". Characters that would need quoting in a key, such as
+// spaces, are replaced with '_':
+//
+//	level=INFO msg="log records sampled" sampled=9800 keys.INFO_cache_miss=9800
+//
+// Flush writes the summary of the current interval right away; Closer calls
+// it, so that no count is lost when the logger is closed. Summaries go to the
+// wrapped handler without the attributes and groups added with WithAttrs and
+// WithGroup, except the logger name (see SlogConfig.Named): each named
+// logger that dropped records gets a summary of its own.
+//
+// Handlers derived with WithAttrs and WithGroup share the counts of their
+// parent.
+type SamplingHandler struct {
+	next    slog.Handler
+	name    loggerName
+	sampler *sampler
+}
+
+// sampler is the state shared by a SamplingHandler and the handlers derived
+// from it.
+type sampler struct {
+	opts  SamplingOptions
+	root  slog.Handler // receives summaries
+	now   func() time.Time
+	after func(time.Duration, func()) stopper // starts timers on the clock of now
+
+	mu          sync.Mutex
+	windowStart time.Time
+	counts      map[string]int
+	dropped     map[string]*sampledCounts // by logger name
+	timer       stopper                   // ends an interval with drops
+	gen         uint64                    // identifies the armed timer
+	stats       SamplingStats
+}
+
+// sampledCounts are the records of one logger dropped in the current interval.
+type sampledCounts struct {
+	keys     map[string]int
+	maxLevel slog.Level
+}
+
+// NewSamplingHandler returns a handler that samples records for next.
+func NewSamplingHandler(next slog.Handler, opts SamplingOptions) *SamplingHandler {
+	if opts.Interval <= 0 {
+		opts.Interval = DefaultSamplingInterval
+	}
+	if opts.First <= 0 {
+		opts.First = DefaultSamplingFirst
+	}
+	if opts.Thereafter <= 0 {
+		opts.Thereafter = DefaultSamplingThereafter
+	}
+	if opts.Key == nil {
+		opts.Key = levelMessageKey
+	}
+	return &SamplingHandler{next: next, sampler: &sampler{
+		opts:    opts,
+		root:    next,
+		now:     time.Now,
+		after:   afterFunc,
+		counts:  make(map[string]int),
+		dropped: make(map[string]*sampledCounts),
+	}}
+}
+
+// WithSampling returns a ConfigOption that makes handlers built from the
+// config sample records through a SamplingHandler (see SamplingHandlerOf).
+// It has no effect on a CustomHandler.
+func WithSampling(opts SamplingOptions) ConfigOption {
+	return func(cfg *SlogConfig) {
+		cfg.Sampling = &opts
+		cfg.touch()
+	}
+}
+
+// SamplingHandlerOf returns the SamplingHandler of a logger built from a
+// config with WithSampling, or nil if the logger does not sample.
+func SamplingHandlerOf(l *slog.Logger) *SamplingHandler {
+	if l == nil {
+		return nil
+	}
+	h, _ := findHandler[*SamplingHandler](l.Handler())
+	return h
+}
+
+// stopper is a timer that can be stopped, such as a *time.Timer.
+type stopper interface {
+	Stop() bool
+}
+
+// afterFunc is time.AfterFunc, the default timer of handlers whose clock
+// tests replace.
+func afterFunc(d time.Duration, f func()) stopper {
+	return time.AfterFunc(d, f)
+}
+
+// levelMessageKey is the default sampling key.
+func levelMessageKey(r slog.Record) string {
+	return r.Level.String() + " " + r.Message
+}
+
+func (h *SamplingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle passes r to the wrapped handler unless it is sampled away.
+func (h *SamplingHandler) Handle(ctx context.Context, r slog.Record) error {
+	s := h.sampler
+	key := s.opts.Key(r)
+	now := s.now()
+	s.mu.Lock()
+	summaries := s.rollover(now)
+	s.counts[key]++
+	n := s.counts[key]
+	pass := n <= s.opts.First || (n-s.opts.First)%s.opts.Thereafter == 0
+	if pass {
+		s.stats.Passed++
+	} else {
+		s.stats.Sampled++
+		s.drop(h.name.name, key, r.Level, now)
+	}
+	s.mu.Unlock()
+
+	err := s.write(summaries)
+	if pass {
+		if err2 := h.next.Handle(ctx, r); err == nil {
+			err = err2
+		}
+	}
+	return err
+}
+
+func (h *SamplingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &SamplingHandler{next: h.next.WithAttrs(attrs), name: h.name.withAttrs(attrs), sampler: h.sampler}
+}
+
+func (h *SamplingHandler) WithGroup(name string) slog.Handler {
+	return &SamplingHandler{next: h.next.WithGroup(name), name: h.name.withGroup(name), sampler: h.sampler}
+}
+
+func (h *SamplingHandler) Unwrap() slog.Handler {
+	return h.next
+}
+
+// Flush writes the summary of the records dropped in the current interval,
+// if any, and starts counting dropped records afresh.
+func (h *SamplingHandler) Flush(context.Context) error {
+	s := h.sampler
+	s.mu.Lock()
+	summaries := s.summaries(s.now())
+	s.mu.Unlock()
+	return s.write(summaries)
+}
+
+// Stats returns a snapshot of the handler's counters.
+func (h *SamplingHandler) Stats() SamplingStats {
+	h.sampler.mu.Lock()
+	defer h.sampler.mu.Unlock()
+	return h.sampler.stats
+}
+
+// drop counts a record of the named logger that was sampled away, and arms
+// the timer that ends the interval if it is the first. s.mu must be held.
+func (s *sampler) drop(name, key string, level slog.Level, now time.Time) {
+	if len(s.dropped) == 0 {
+		gen := s.gen
+		s.timer = s.after(s.windowStart.Add(s.opts.Interval).Sub(now), func() { s.expire(gen) })
+	}
+	c := s.dropped[name]
+	if c == nil {
+		c = &sampledCounts{keys: make(map[string]int), maxLevel: level}
+		s.dropped[name] = c
+	}
+	c.maxLevel = max(c.maxLevel, level)
+	c.keys[key]++
+}
+
+// expire ends the interval when the timer armed for generation gen fires
+// and writes its summaries.
+func (s *sampler) expire(gen uint64) {
+	s.mu.Lock()
+	if s.gen != gen {
+		s.mu.Unlock()
+		return
+	}
+	s.windowStart = time.Time{} // the next record starts a new interval
+	clear(s.counts)
+	summaries := s.summaries(s.now())
+	s.mu.Unlock()
+	s.write(summaries)
+}
+
+// rollover starts a new interval if the current one is over and returns the
+// summaries of the one that ended. s.mu must be held.
+func (s *sampler) rollover(now time.Time) []samplingSummary {
+	if !s.windowStart.IsZero() && now.Sub(s.windowStart) < s.opts.Interval {
+		return nil
+	}
+	s.windowStart = now
+	clear(s.counts)
+	return s.summaries(now)
+}
+
+// samplingSummary is a summary record and the name of the logger it is for.
+type samplingSummary struct {
+	name   loggerName
+	record slog.Record
+}
+
+// summaries returns the summary records of the dropped records, one per
+// logger name, resets their counts and stops the timer. s.mu must be held.
+func (s *sampler) summaries(now time.Time) []samplingSummary {
+	if len(s.dropped) == 0 {
+		return nil
+	}
+	s.timer.Stop()
+	s.timer = nil
+	s.gen++
+	summaries := make([]samplingSummary, 0, len(s.dropped))
+	for _, name := range slices.Sorted(maps.Keys(s.dropped)) {
+		c := s.dropped[name]
+		r := slog.NewRecord(now, c.maxLevel, SamplingSummaryMessage, 0)
+		total := 0
+		keys := make([]any, 0, len(c.keys))
+		for _, key := range slices.Sorted(maps.Keys(c.keys)) {
+			total += c.keys[key]
+			keys = append(keys, slog.Int(summaryKey(key), c.keys[key]))
+		}
+		r.AddAttrs(slog.Int("sampled", total), slog.Group("keys", keys...))
+		summaries = append(summaries, samplingSummary{name: loggerName{name: name}, record: r})
+	}
+	clear(s.dropped)
+	return summaries
+}
+
+// write writes summaries to the wrapped handler, counting failures.
+func (s *sampler) write(summaries []samplingSummary) error {
+	var errs []error
+	for _, sum := range summaries {
+		if err := sum.name.handler(s.root).Handle(context.Background(), sum.record); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		s.mu.Lock()
+		s.stats.SummaryErrors += uint64(len(errs))
+		s.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// summaryKey returns key with the characters that would need quoting as an
+// attribute key, such as spaces, '=' and '"', replaced with '_'.
+func summaryKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || !unicode.IsPrint(r) || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+}
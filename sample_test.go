@@ -0,0 +1,164 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestSampler(opts SamplingOptions) (*SamplingHandler, *testHandler, *fakeClock) {
+	th := newTestHandler()
+	h := NewSamplingHandler(th, opts)
+	clock := &fakeClock{t: testTime}
+	h.sampler.now, h.sampler.after = clock.now, clock.afterFunc
+	return h, th, clock
+}
+
+func TestSamplingHandlerFirstThereafter(t *testing.T) {
+	h, th, clock := newTestSampler(SamplingOptions{Interval: time.Second, First: 2, Thereafter: 3})
+	logger := slog.New(h)
+	for range 9 {
+		logger.Info("hot")
+	}
+	logger.Info("cold")
+	logger.Warn("hot")
+	clock.advance(999 * time.Millisecond)
+	logger.Info("hot") // 10th in the interval: sampled away
+	if got := strings.Join(messages(th), ","); got != "hot,hot,hot,hot,cold,hot" {
+		t.Errorf("messages = %s", got)
+	}
+
+	clock.advance(time.Millisecond)
+	logger.Info("hot")
+	msgs := messages(th)
+	if got := strings.Join(msgs[6:], ","); got != SamplingSummaryMessage+",hot" {
+		t.Fatalf("after the interval: messages = %s", got)
+	}
+	summary := (*th.records)[6]
+	if attrs := flattenRecord(summary); attrs["sampled"] != int64(6) || attrs["keys.INFO_hot"] != int64(6) || summary.Level != slog.LevelInfo {
+		t.Errorf("summary = %v at %v", attrs, summary.Level)
+	}
+	if !summary.Time.Equal(clock.t) {
+		t.Errorf("summary time = %v, want %v", summary.Time, clock.t)
+	}
+	if st := h.Stats(); st.Passed != 7 || st.Sampled != 6 {
+		t.Errorf("stats = %+v", st)
+	}
+
+	// No summary follows an interval without drops.
+	clock.advance(time.Second)
+	logger.Info("hot")
+	if n := len(messages(th)); n != 9 {
+		t.Errorf("got %d records, want 9", n)
+	}
+}
+
+func TestSamplingHandlerKeyAndFlush(t *testing.T) {
+	h, th, _ := newTestSampler(SamplingOptions{First: 1, Thereafter: 1000, Key: func(r slog.Record) string {
+		return r.Message
+	}})
+	logger := slog.New(h).With("request", 1)
+	logger.Info("retrying")
+	logger.Warn("retrying")
+	logger.Error("retrying")
+	logger.Info("retrying")
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(messages(th), ","); got != "retrying,"+SamplingSummaryMessage {
+		t.Fatalf("messages = %s", got)
+	}
+	summary := th.lastRecord()
+	attrs := flattenRecord(summary)
+	if summary.Level != slog.LevelError || attrs["sampled"] != int64(3) || attrs["keys.retrying"] != int64(3) {
+		t.Errorf("summary = %v at %v", attrs, summary.Level)
+	}
+	if _, ok := attrs["request"]; ok {
+		t.Error("summary carries the attributes of a derived handler")
+	}
+}
+
+func TestSamplingHandlerPeriodicSummary(t *testing.T) {
+	h, th, clock := newTestSampler(SamplingOptions{Interval: time.Second, First: 1, Thereafter: 100})
+	logger := slog.New(h)
+	logger.Info("hot")
+	clock.advance(300 * time.Millisecond)
+	logger.Info("hot")
+	clock.advance(699 * time.Millisecond)
+	if got := strings.Join(messages(th), ","); got != "hot" {
+		t.Fatalf("before the end of the interval: messages = %s", got)
+	}
+	clock.advance(time.Millisecond)
+	summary := th.lastRecord()
+	if attrs := flattenRecord(summary); summary.Message != SamplingSummaryMessage || attrs["sampled"] != int64(1) {
+		t.Fatalf("summary = %s %v", summary.Message, attrs)
+	}
+	if !summary.Time.Equal(testTime.Add(time.Second)) {
+		t.Errorf("summary time = %v", summary.Time)
+	}
+	clock.advance(10 * time.Millisecond)
+	logger.Info("hot") // the timer started a new interval
+	clock.advance(time.Hour)
+	if got := strings.Join(messages(th), ","); got != "hot,"+SamplingSummaryMessage+",hot" {
+		t.Errorf("messages = %s", got)
+	}
+}
+
+func TestSamplingHandlerSummaryPerName(t *testing.T) {
+	h, th, _ := newTestSampler(SamplingOptions{First: 1, Thereafter: 100})
+	db, api := slog.New(h).With(LoggerKey, "db"), slog.New(h).With(LoggerKey, "api").With("request", 7)
+	for range 3 {
+		db.Info("hot")
+		api.WithGroup("g").With(LoggerKey, "not a name").Info("hot")
+	}
+	if err := h.Flush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	recs := *th.records
+	if len(recs) != 3 {
+		t.Fatalf("got %d records, want 3", len(recs))
+	}
+	for i, want := range map[int]struct {
+		name    string
+		sampled int64
+	}{1: {"api", 3}, 2: {"db", 2}} {
+		attrs := flattenRecord(recs[i])
+		if recs[i].Message != SamplingSummaryMessage || attrs[LoggerKey] != want.name || attrs["sampled"] != want.sampled {
+			t.Errorf("summary %d = %s %v, want logger %s", i, recs[i].Message, attrs, want.name)
+		}
+		if _, ok := attrs["request"]; ok {
+			t.Errorf("summary %d carries the attributes of a derived handler", i)
+		}
+	}
+}
+
+func TestWithSampling(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewSlogConfig(
+		WithHandlerType("text"),
+		WithOutput(&buf),
+		WithSampling(SamplingOptions{First: 1, Thereafter: 2}),
+	).Named("svc").NewLogger()
+	for range 4 {
+		logger.Info("tick")
+	}
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if n := strings.Count(out, "msg=tick"); n != 2 {
+		t.Errorf("got %d tick records, want 2:\n%s", n, out)
+	}
+	if !strings.Contains(out, `msg="log records sampled" logger=svc sampled=2 keys.INFO_tick=2`) {
+		t.Errorf("no summary in output:\n%s", out)
+	}
+	if SamplingHandlerOf(logger) == nil {
+		t.Error("SamplingHandlerOf = nil")
+	}
+}
@@ -138,9 +138,32 @@ func (l *Stateful[T]) logWithState(ctx context.Context, level slog.Level, msg st
 	groupName := l.stateTypeName()
 	group := slog.Group(groupName, anyAttrs...)
 	finalArgs := append(args, group)
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx = context.WithValue(ctx, stateGroupKey{}, groupName)
 	l.logger.Log(ctx, level, msg, finalArgs...)
 }
 
+// stateGroupKey is the context key under which a Stateful logger passes the
+// name of its state group to handlers.
+type stateGroupKey struct{}
+
+// stateGroup returns the name of the state group that r carries as a
+// top-level attribute, if it was logged by a Stateful logger with ctx.
+func stateGroup(ctx context.Context, r slog.Record) (string, bool) {
+	name, _ := ctx.Value(stateGroupKey{}).(string)
+	if name == "" {
+		return "", false
+	}
+	found := false
+	r.Attrs(func(a slog.Attr) bool {
+		found = a.Key == name && a.Value.Kind() == slog.KindGroup
+		return !found
+	})
+	return name, found
+}
+
 // appendStateFields returns a slice of slog.Attr for fields of the state.
 // If includeZeroFields is false, only non-zero fields are included.
 func (l *Stateful[T]) appendStateFields() []slog.Attr {
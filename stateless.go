@@ -9,6 +9,17 @@ func (c SlogConfig) NewLogger() *slog.Logger {
 	return slog.New(c.newHandler())
 }
 
+// BuildLogger creates a *slog.Logger with the configuration applied.
+// It returns an error if the handler cannot be built, for example because
+// HandlerType names a type that was never registered.
+func (c SlogConfig) BuildLogger() (*slog.Logger, error) {
+	h, err := c.BuildHandler()
+	if err != nil {
+		return nil, err
+	}
+	return slog.New(h), nil
+}
+
 // Stateless creates a plain slog.Logger from the configuration.
 // It is an alias for NewLogger for clarity.
 func (c SlogConfig) Stateless() *slog.Logger {
@@ -0,0 +1,286 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSyslogDestination is the destination of the "syslog" handler type
+// when SlogConfig.Destination is empty: the local syslog socket.
+const DefaultSyslogDestination = "unixgram:///dev/log"
+
+// DefaultSyslogSDID is the SD-ID of the STRUCTURED-DATA element holding the
+// attributes of a record. 32473 is the private enterprise number reserved
+// for documentation (RFC 5612).
+const DefaultSyslogSDID = "slog@32473"
+
+// Syslog facilities (RFC 5424, section 6.2.1) commonly used by applications.
+const (
+	FacilityUser   = 1
+	FacilityDaemon = 3
+	FacilityLocal0 = 16
+	FacilityLocal1 = 17
+	FacilityLocal2 = 18
+	FacilityLocal3 = 19
+	FacilityLocal4 = 20
+	FacilityLocal5 = 21
+	FacilityLocal6 = 22
+	FacilityLocal7 = 23
+)
+
+// SyslogOptions configure a SyslogHandler.
+type SyslogOptions struct {
+	slog.HandlerOptions
+
+	// Facility is the syslog facility, 1 to 23. Zero selects FacilityUser,
+	// since facility 0 is reserved for the kernel.
+	Facility int
+
+	// AppName, Hostname and MsgID fill the header fields of the same names.
+	// AppName defaults to the program name and Hostname to os.Hostname;
+	// MsgID is left out if empty.
+	AppName  string
+	Hostname string
+	MsgID    string
+
+	// SDID is the SD-ID of the element holding the attributes.
+	// If empty, DefaultSyslogSDID is used.
+	SDID string
+}
+
+// SyslogHandler sends records as RFC 5424 syslog messages:
+//
+//	<14>1 2026-10-16T12:30:45.123456Z host app 4242 - [slog@32473 user="bob" Session.User.ID="7"] user logged in
+//
+// Levels map to severities: Error and above to err (3), Warn to warning (4),
+// Info to info (6) and lower levels to debug (7). Attributes go into a
+// single STRUCTURED-DATA element, with groups as dotted parameter names.
+//
+// The destination selects the transport: "udp://host:port" and
+// "unixgram:///path" send one datagram per message, "tcp://host:port" uses
+// octet-counting framing (RFC 6587). The connection is opened on the first
+// record and reopened after a failed write.
+//
+// It is registered as the "syslog" handler type, which reads the destination
+// from SlogConfig.Destination (see WithDestination).
+type SyslogHandler struct {
+	conn      *reconnConn
+	framed    bool // octet-counting framing for stream transports
+	header    string
+	facility  int
+	sdid      string
+	level     slog.Leveler
+	addSource bool
+	scope     attrScope
+}
+
+// NewSyslogHandler returns a SyslogHandler that sends records to dest.
+// A nil opts uses the defaults. It fails only if dest is malformed;
+// connection errors are reported by Handle.
+func NewSyslogHandler(dest string, opts *SyslogOptions) (*SyslogHandler, error) {
+	network, address, err := parseDestination(dest, "udp", "tcp", "unixgram")
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &SyslogOptions{}
+	}
+	h := &SyslogHandler{
+		conn:      newReconnConn(network, address),
+		framed:    network == "tcp",
+		facility:  opts.Facility,
+		sdid:      opts.SDID,
+		level:     opts.Level,
+		addSource: opts.AddSource,
+		scope:     attrScope{replace: opts.ReplaceAttr},
+	}
+	if h.facility <= 0 || h.facility > 23 {
+		h.facility = FacilityUser
+	}
+	if h.sdid == "" {
+		h.sdid = DefaultSyslogSDID
+	}
+	if h.level == nil {
+		h.level = slog.LevelInfo
+	}
+	hostname := opts.Hostname
+	if hostname == "" {
+		hostname, _ = os.Hostname()
+	}
+	appName := opts.AppName
+	if appName == "" {
+		appName = filepath.Base(os.Args[0])
+	}
+	h.header = strings.Join([]string{
+		syslogHeaderField(hostname, 255),
+		syslogHeaderField(appName, 48),
+		strconv.Itoa(os.Getpid()),
+		syslogHeaderField(opts.MsgID, 32),
+	}, " ")
+	return h, nil
+}
+
+func (h *SyslogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *SyslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+	h2 := *h
+	h2.scope = h.scope.withAttrs(attrs)
+	return &h2
+}
+
+func (h *SyslogHandler) WithGroup(name string) slog.Handler {
+	h2 := *h
+	h2.scope = h.scope.withGroup(name)
+	return &h2
+}
+
+func (h *SyslogHandler) Handle(_ context.Context, r slog.Record) error {
+	msg := h.format(r)
+	if h.framed {
+		msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+	}
+	return h.conn.write(msg)
+}
+
+// Close closes the connection to the syslog server. Handlers derived with
+// WithAttrs and WithGroup share the connection.
+func (h *SyslogHandler) Close() error {
+	return h.conn.Close()
+}
+
+// format returns the RFC 5424 message for r, without transport framing.
+func (h *SyslogHandler) format(r slog.Record) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 ", h.facility*8+syslogSeverity(r.Level))
+	if r.Time.IsZero() {
+		buf.WriteByte('-')
+	} else {
+		buf.WriteString(r.Time.Format("2006-01-02T15:04:05.999999Z07:00"))
+	}
+	buf.WriteByte(' ')
+	buf.WriteString(h.header)
+	buf.WriteByte(' ')
+
+	var params bytes.Buffer
+	addParam := func(key string, v slog.Value) {
+		params.WriteByte(' ')
+		params.WriteString(syslogParamName(key))
+		params.WriteString(`="`)
+		syslogEscape(&params, syslogValue(v))
+		params.WriteByte('"')
+	}
+	if h.addSource && r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		src := slog.String(slog.SourceKey, fmt.Sprintf("%s:%d", frame.File, frame.Line))
+		if a, ok := h.scope.builtin(src); ok {
+			addParam(a.Key, a.Value)
+		}
+	}
+	h.scope.each(r, func(groups []string, a slog.Attr) {
+		key := a.Key
+		if len(groups) > 0 {
+			key = strings.Join(groups, ".") + "." + key
+		}
+		addParam(key, a.Value)
+	})
+	if params.Len() == 0 {
+		buf.WriteByte('-')
+	} else {
+		buf.WriteByte('[')
+		buf.WriteString(h.sdid)
+		buf.Write(params.Bytes())
+		buf.WriteByte(']')
+	}
+
+	message := ""
+	if a, ok := h.scope.builtin(slog.String(slog.MessageKey, r.Message)); ok {
+		message = syslogValue(a.Value)
+	}
+	if message != "" {
+		buf.WriteByte(' ')
+		buf.WriteString(message)
+	}
+	return buf.Bytes()
+}
+
+// syslogSeverity maps a slog level to a syslog severity.
+func syslogSeverity(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return 3
+	case level >= slog.LevelWarn:
+		return 4
+	case level >= slog.LevelInfo:
+		return 6
+	}
+	return 7
+}
+
+// syslogHeaderField returns s restricted to printable US-ASCII and at most
+// max bytes, or the NILVALUE "-" if s is empty.
+func syslogHeaderField(s string, max int) string {
+	field := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return '_'
+		}
+		return r
+	}, s)
+	if len(field) > max {
+		field = field[:max]
+	}
+	if field == "" {
+		return "-"
+	}
+	return field
+}
+
+// syslogParamName returns a valid PARAM-NAME for key: printable US-ASCII
+// other than '=', ']' and '"', at most 32 bytes.
+func syslogParamName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+		return r
+	}, key)
+	if len(name) > 32 {
+		name = name[:32]
+	}
+	return name
+}
+
+// syslogEscape writes a PARAM-VALUE, escaping '"', '\' and ']'.
+func syslogEscape(buf *bytes.Buffer, s string) {
+	for _, r := range s {
+		if r == '"' || r == '\\' || r == ']' {
+			buf.WriteByte('\\')
+		}
+		buf.WriteRune(r)
+	}
+}
+
+// syslogValue returns the text of an attribute value.
+func syslogValue(v slog.Value) string {
+	switch v.Kind() {
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+	}
+	return v.String()
+}
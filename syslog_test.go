@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// listenPacket starts a datagram listener and returns its address and a
+// function that waits for the next datagram.
+func listenPacket(t *testing.T, network, address string) (string, func() []byte) {
+	t.Helper()
+	pc, err := net.ListenPacket(network, address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+	return pc.LocalAddr().String(), func() []byte {
+		t.Helper()
+		buf := make([]byte, 1<<16)
+		pc.SetReadDeadline(time.Now().Add(5 * time.Second))
+		n, _, err := pc.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read datagram: %v", err)
+		}
+		return buf[:n]
+	}
+}
+
+// shortSocketPath returns a unix socket path short enough for sun_path.
+func shortSocketPath(t *testing.T, name string) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "gslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, name)
+}
+
+var syslogPattern = regexp.MustCompile(`^<(\d+)>1 (\S+) host app (\d+) (\S+) (-|\[.*\])(?: (.*))?$`)
+
+func testSyslogHandler(t *testing.T, dest string) *SyslogHandler {
+	t.Helper()
+	h, err := NewSyslogHandler(dest, &SyslogOptions{
+		HandlerOptions: slog.HandlerOptions{Level: slog.LevelDebug},
+		Facility:       FacilityLocal0,
+		AppName:        "app",
+		Hostname:       "host",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+func TestSyslogHandlerUDP(t *testing.T) {
+	addr, recv := listenPacket(t, "udp", "127.0.0.1:0")
+	h := testSyslogHandler(t, "udp://"+addr)
+	r := slog.NewRecord(testTime, slog.LevelWarn, "disk almost full", 0)
+	r.AddAttrs(slog.String("path", `C:\data "x" [1]`), slog.Group("Session", slog.Int("User.ID", 7)))
+	if err := h.Handle(context.Background(), r); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	m := syslogPattern.FindStringSubmatch(string(recv()))
+	if m == nil {
+		t.Fatal("message does not match RFC 5424 layout")
+	}
+	if m[1] != strconv.Itoa(FacilityLocal0*8+4) {
+		t.Errorf("PRI = %s, want local0.warning", m[1])
+	}
+	if m[2] != "2026-10-16T12:30:45.123456Z" {
+		t.Errorf("TIMESTAMP = %s", m[2])
+	}
+	if m[3] != strconv.Itoa(os.Getpid()) || m[4] != "-" {
+		t.Errorf("PROCID, MSGID = %s, %s", m[3], m[4])
+	}
+	if want := `[slog@32473 path="C:\\data \"x\" [1\]" Session.User.ID="7"]`; m[5] != want {
+		t.Errorf("STRUCTURED-DATA = %s, want %s", m[5], want)
+	}
+	if m[6] != "disk almost full" {
+		t.Errorf("MSG = %q", m[6])
+	}
+}
+
+func TestSyslogHandlerSeverities(t *testing.T) {
+	addr, recv := listenPacket(t, "udp", "127.0.0.1:0")
+	logger := slog.New(testSyslogHandler(t, "udp://"+addr))
+	for level, severity := range map[slog.Level]int{
+		slog.LevelDebug: 7, slog.LevelInfo: 6, slog.LevelWarn: 4, slog.LevelError: 3, slog.LevelError + 4: 3,
+	} {
+		logger.Log(context.Background(), level, "m")
+		want := fmt.Sprintf("<%d>", FacilityLocal0*8+severity)
+		if got := string(recv()); !strings.HasPrefix(got, want) {
+			t.Errorf("%v: got %q, want prefix %q", level, got, want)
+		}
+	}
+}
+
+func TestSyslogHandlerUnixgram(t *testing.T) {
+	path := shortSocketPath(t, "log.sock")
+	_, recv := listenPacket(t, "unixgram", path)
+	h := testSyslogHandler(t, "unixgram://"+path)
+	slog.New(h).Info("local")
+	if got := string(recv()); !strings.HasSuffix(got, " - local") {
+		t.Errorf("got %q", got)
+	}
+}
+
+// readOctetFrame reads one octet-counted frame (RFC 6587).
+func readOctetFrame(r *bufio.Reader) (string, error) {
+	size, err := r.ReadString(' ')
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(strings.TrimSuffix(size, " "))
+	if err != nil {
+		return "", err
+	}
+	buf := make([]byte, n)
+	_, err = io.ReadFull(r, buf)
+	return string(buf), err
+}
+
+func TestSyslogHandlerTCPReconnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	frames := make(chan string, 100)
+	conns := make(chan net.Conn, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conns <- conn
+			go func() {
+				r := bufio.NewReader(conn)
+				for {
+					frame, err := readOctetFrame(r)
+					if err != nil {
+						return
+					}
+					frames <- frame
+				}
+			}()
+		}
+	}()
+
+	logger := slog.New(testSyslogHandler(t, "tcp://"+ln.Addr().String()))
+	logger.Info("first", "multi", "line\nvalue")
+	select {
+	case frame := <-frames:
+		if !strings.HasSuffix(frame, ` [slog@32473 multi="line`+"\n"+`value"] first`) {
+			t.Errorf("frame = %q", frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no frame received")
+	}
+
+	// Drop the connection; the handler must dial again. Writes to a
+	// connection closed by the peer may still succeed once, so keep logging
+	// until a record arrives over a new connection.
+	(<-conns).Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		logger.Info("again")
+		select {
+		case <-conns:
+			return
+		case <-time.After(20 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("handler did not reconnect")
+		}
+	}
+}
+
+func TestSyslogHandlerType(t *testing.T) {
+	addr, recv := listenPacket(t, "udp", "127.0.0.1:0")
+	cfg := NewSlogConfig(WithHandlerType("syslog"), WithDestination("udp://"+addr))
+	logger, err := cfg.BuildLogger()
+	if err != nil {
+		t.Fatalf("BuildLogger: %v", err)
+	}
+	logger.Info("via config")
+	if got := string(recv()); !strings.HasSuffix(got, " via config") {
+		t.Errorf("got %q", got)
+	}
+	if err := CloserOf(logger).Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	if err := logger.Handler().Handle(context.Background(), slog.NewRecord(testTime, slog.LevelInfo, "late", 0)); err == nil {
+		t.Error("Handle after Close succeeded")
+	}
+}
+
+func TestParseDestination(t *testing.T) {
+	for _, dest := range []string{"localhost:514", "http://localhost:514", "udp://localhost", "unixgram://"} {
+		if _, err := NewSyslogHandler(dest, nil); err == nil {
+			t.Errorf("NewSyslogHandler(%q) succeeded", dest)
+		}
+	}
+	_, err := NewSlogConfig(WithHandlerType("syslog"), WithDestination("syslog.local")).BuildHandler()
+	if err == nil || !strings.Contains(err.Error(), "missing scheme") {
+		t.Errorf("BuildHandler error = %v", err)
+	}
+}
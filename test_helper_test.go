@@ -3,9 +3,61 @@ package logger
 import (
 	"context"
 	"log/slog"
+	"slices"
 	"sync"
+	"time"
 )
 
+// testTime is a fixed record time for handler output tests.
+var testTime = time.Date(2026, 10, 16, 12, 30, 45, 123456789, time.UTC)
+
+// fakeClock is a manually advanced time source. Timers started with
+// afterFunc fire, in order, while advance moves the clock past them.
+type fakeClock struct {
+	t      time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a timer of a fakeClock.
+type fakeTimer struct {
+	at   time.Time
+	f    func()
+	done bool // fired or stopped
+}
+
+func (t *fakeTimer) Stop() bool {
+	stopped := !t.done
+	t.done = true
+	return stopped
+}
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func (c *fakeClock) afterFunc(d time.Duration, f func()) stopper {
+	t := &fakeTimer{at: c.t.Add(d), f: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+func (c *fakeClock) advance(d time.Duration) {
+	end := c.t.Add(d)
+	for {
+		var next *fakeTimer
+		for _, t := range c.timers {
+			if !t.done && !t.at.After(end) && (next == nil || t.at.Before(next.at)) {
+				next = t
+			}
+		}
+		if next == nil {
+			break
+		}
+		c.t, next.done = next.at, true
+		next.f()
+	}
+	c.timers = slices.DeleteFunc(c.timers, func(t *fakeTimer) bool { return t.done })
+	c.t = end
+}
+
 // testHandler is a slog.Handler that stores records for later inspection,
 // correctly handling WithAttrs and WithGroup.
 type testHandler struct {
@@ -2,18 +2,26 @@ package main
 
 import (
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/Galdoba/gslog"
 )
 
 func main() {
-	f, _ := os.Create(`./testLog.txt`)
-	gslog.SetWriter(f)
+	cfg := gslog.NewSlogConfig(gslog.WithFileOutput(`./testLog.txt`, gslog.RotationPolicy{
+		MaxSize:  16 * 1024,
+		Compress: true,
+		MaxFiles: 5,
+	}))
+	if err := cfg.Err(); err != nil {
+		fmt.Println("config error:", err)
+		return
+	}
+	log := cfg.NewLogger()
+	defer gslog.CloserOf(log).Close()
 	for i := range 999 {
 		time.Sleep(time.Millisecond * 70)
-		gslog.Info(fmt.Sprintf("message %v", i))
+		log.Info(fmt.Sprintf("message %v", i))
 		fmt.Printf("%v\r", i)
 	}
 